@@ -6,29 +6,43 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata" // la imagen de producción no trae la base de zonas horarias
 
 	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/application/adjunto"
 	"github.com/tunek/centro-caribel/internal/application/auth"
 	"github.com/tunek/centro-caribel/internal/application/cita"
 	"github.com/tunek/centro-caribel/internal/application/consentimiento"
+	"github.com/tunek/centro-caribel/internal/application/foto"
 	"github.com/tunek/centro-caribel/internal/application/historia"
 	"github.com/tunek/centro-caribel/internal/application/paciente"
 	"github.com/tunek/centro-caribel/internal/application/paquete"
+	"github.com/tunek/centro-caribel/internal/application/plantilla"
+	"github.com/tunek/centro-caribel/internal/application/reporte"
+	"github.com/tunek/centro-caribel/internal/application/timeline"
 	"github.com/tunek/centro-caribel/internal/application/usuario"
 	"github.com/tunek/centro-caribel/internal/domain"
 	"github.com/tunek/centro-caribel/internal/infrastructure/config"
 	"github.com/tunek/centro-caribel/internal/infrastructure/database"
 	jwtinfra "github.com/tunek/centro-caribel/internal/infrastructure/jwt"
+	"github.com/tunek/centro-caribel/internal/infrastructure/notificacion"
 	"github.com/tunek/centro-caribel/internal/infrastructure/repository"
+	"github.com/tunek/centro-caribel/internal/infrastructure/storage"
 	"github.com/tunek/centro-caribel/internal/interfaces/http/handler"
 	"github.com/tunek/centro-caribel/internal/interfaces/http/router"
+	"github.com/tunek/centro-caribel/pkg/password"
+	"github.com/tunek/centro-caribel/pkg/totp"
 	"golang.org/x/crypto/bcrypt"
 )
 
 func main() {
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Configuración inválida: %v", err)
+	}
 
 	db, err := database.NewConnection(cfg.DB)
 	if err != nil {
@@ -36,6 +50,8 @@ func main() {
 	}
 	defer db.Close()
 
+	zonaClinica, _ := time.LoadLocation(cfg.Server.Timezone) // validada en cfg.Validate
+
 	migrationsDir := "migrations"
 	if _, err := os.Stat(migrationsDir); os.IsNotExist(err) {
 		migrationsDir = "/migrations"
@@ -47,24 +63,102 @@ func main() {
 	// Repositorios
 	rolRepo := repository.NewRolRepository(db)
 	usuarioRepo := repository.NewUsuarioRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	intentoLoginRepo := repository.NewIntentoLoginRepository(db)
+	passwordResetRepo := repository.NewPasswordResetTokenRepository(db)
+	totpRepo := repository.NewTOTPRepository(db)
 	pacienteRepo := repository.NewPacienteRepository(db)
 	consentimientoRepo := repository.NewConsentimientoRepository(db)
+	plantillaConsentimientoRepo := repository.NewPlantillaConsentimientoRepository(db)
 	citaRepo := repository.NewCitaRepository(db)
 	historiaRepo := repository.NewHistoriaClinicaRepository(db)
 	notaRepo := repository.NewNotaEvolucionRepository(db)
 	paqueteRepo := repository.NewPaqueteRepository(db)
+	timelineRepo := repository.NewTimelineRepository(db)
+	tutorRepo := repository.NewTutorRepository(db)
+	alergiaRepo := repository.NewAlergiaRepository(db)
+	medicamentoRepo := repository.NewMedicamentoRepository(db)
+	condicionRepo := repository.NewCondicionRepository(db)
+	plantillaRepo := repository.NewPlantillaNotaRepository(db)
+	medicionRepo := repository.NewMedicionRepository(db)
+	fotoRepo := repository.NewFotoRepository(db)
+	adjuntoRepo := repository.NewAdjuntoRepository(db)
+	transactor := repository.NewTransactor(db)
+
+	// Almacenamiento de archivos
+	fileStorage, err := storage.New(cfg.Storage)
+	if err != nil {
+		log.Fatalf("Error configurando el almacenamiento: %v", err)
+	}
+
+	// Notificaciones a usuarios
+	notificador, err := notificacion.New(cfg.Notifier)
+	if err != nil {
+		log.Fatalf("Error configurando las notificaciones: %v", err)
+	}
 
 	// JWT
-	jwtSvc := jwtinfra.NewService(cfg.JWT.Secret, cfg.JWT.ExpirationHours, cfg.JWT.RefreshExpirationHrs)
+	jwtSvc, err := jwtinfra.New(cfg.JWT)
+	if err != nil {
+		log.Fatalf("Error configurando las claves JWT: %v", err)
+	}
+
+	// Config.Validate exige ambas claves en producción; el respaldo con
+	// JWT_SECRET es solo para desarrollo.
+	claveSello := cfg.Consent.SealSecret
+	if claveSello == "" {
+		log.Printf("CONSENT_SEAL_SECRET vacío: se usa JWT_SECRET (solo para desarrollo)")
+		claveSello = cfg.JWT.Secret
+	}
+
+	claveTOTP := cfg.TOTP.EncryptionKey
+	if claveTOTP == "" {
+		log.Printf("TOTP_ENCRYPTION_KEY vacío: se usa JWT_SECRET (solo para desarrollo)")
+		claveTOTP = cfg.JWT.Secret
+	}
+	cifradorTOTP, err := totp.NewCipher([]byte(claveTOTP))
+	if err != nil {
+		log.Fatalf("Error configurando el cifrado TOTP: %v", err)
+	}
+	var rolesTOTP []string
+	for _, r := range strings.Split(cfg.TOTP.RequiredRoles, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			rolesTOTP = append(rolesTOTP, r)
+		}
+	}
 
 	// Servicios
-	authSvc := auth.NewService(usuarioRepo, rolRepo, jwtSvc)
-	usuarioSvc := usuario.NewService(usuarioRepo, rolRepo)
-	pacienteSvc := paciente.NewService(pacienteRepo, historiaRepo)
-	consentimientoSvc := consentimiento.NewService(consentimientoRepo, pacienteRepo)
-	citaSvc := cita.NewService(citaRepo, pacienteRepo, paqueteRepo)
+	sesiones := auth.NewSesiones(usuarioRepo, time.Duration(cfg.JWT.SessionCacheSeconds)*time.Second)
+	politicaPassword := password.Policy{
+		MinLength:     cfg.Password.MinLength,
+		RequireUpper:  cfg.Password.RequireUpper,
+		RequireLower:  cfg.Password.RequireLower,
+		RequireDigit:  cfg.Password.RequireDigit,
+		RequireSymbol: cfg.Password.RequireSymbol,
+	}
+	politicaLogin := auth.PoliticaLogin{
+		MaxIntentos:   cfg.Login.MaxAttempts,
+		Bloqueo:       time.Duration(cfg.Login.LockoutMinutes) * time.Minute,
+		DemoraBase:    time.Duration(cfg.Login.DelayBaseSeconds) * time.Second,
+		DemoraMax:     time.Duration(cfg.Login.DelayMaxSeconds) * time.Second,
+		MaxFallidosIP: cfg.Login.MaxFailuresPerIP,
+		VentanaIP:     time.Duration(cfg.Login.IPWindowMinutes) * time.Minute,
+	}
+	authSvc := auth.NewService(usuarioRepo, rolRepo, refreshTokenRepo, intentoLoginRepo, passwordResetRepo, notificador,
+		jwtSvc, sesiones, politicaLogin, politicaPassword, time.Duration(cfg.Password.ResetTTLMinutes)*time.Minute,
+		totpRepo, auth.ConfigTOTP{Emisor: cfg.TOTP.Issuer, RolesRequeridos: rolesTOTP, Cifrador: cifradorTOTP})
+	usuarioSvc := usuario.NewService(usuarioRepo, rolRepo, sesiones, politicaPassword)
+	pacienteSvc := paciente.NewService(pacienteRepo, historiaRepo, tutorRepo)
+	consentimientoSvc := consentimiento.NewService(consentimientoRepo, pacienteRepo, tutorRepo, plantillaConsentimientoRepo, []byte(claveSello), zonaClinica)
+	historiaSvc := historia.NewService(historiaRepo, notaRepo, pacienteRepo, alergiaRepo, medicamentoRepo, condicionRepo,
+		citaRepo, paqueteRepo, plantillaRepo, medicionRepo)
+	citaSvc := cita.NewService(citaRepo, pacienteRepo, paqueteRepo, historiaSvc, transactor, consentimientoSvc, cfg.Consent.MissingPolicy == "block")
 	paqueteSvc := paquete.NewService(paqueteRepo, pacienteRepo)
-	historiaSvc := historia.NewService(historiaRepo, notaRepo, pacienteRepo)
+	timelineSvc := timeline.NewService(timelineRepo, pacienteRepo)
+	plantillaSvc := plantilla.NewService(plantillaRepo)
+	fotoSvc := foto.NewService(fotoRepo, pacienteRepo, consentimientoRepo, citaRepo, paqueteRepo, fileStorage)
+	adjuntoSvc := adjunto.NewService(adjuntoRepo, pacienteRepo, historiaRepo, notaRepo, fileStorage, transactor)
+	reporteSvc := reporte.NewService(pacienteRepo, usuarioRepo, consentimientoRepo, paqueteRepo, historiaSvc)
 
 	// Seed admin
 	seedAdmin(usuarioRepo, rolRepo, cfg.Admin)
@@ -79,9 +173,14 @@ func main() {
 		Historia:       handler.NewHistoriaHandler(historiaSvc),
 		Rol:            handler.NewRolHandler(rolRepo),
 		Paquete:        handler.NewPaqueteHandler(paqueteSvc),
+		Timeline:       handler.NewTimelineHandler(timelineSvc),
+		Plantilla:      handler.NewPlantillaHandler(plantillaSvc),
+		Foto:           handler.NewFotoHandler(fotoSvc),
+		Adjunto:        handler.NewAdjuntoHandler(adjuntoSvc),
+		Reporte:        handler.NewReporteHandler(reporteSvc),
 	}
 
-	mux := router.New(handlers, jwtSvc)
+	mux := router.New(handlers, jwtSvc, sesiones)
 
 	srv := &http.Server{
 		Addr:         ":" + cfg.Server.Port,
@@ -138,6 +237,8 @@ func seedAdmin(userRepo *repository.UsuarioRepository, rolRepo *repository.RolRe
 		PasswordHash:   string(hash),
 		RolID:          rol.ID,
 		Activo:         true,
+		// ADMIN_PASSWORD suele quedar con el valor de ejemplo.
+		DebeCambiarPassword: true,
 	}
 
 	if err := userRepo.Create(ctx, user); err != nil {
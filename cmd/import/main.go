@@ -0,0 +1,96 @@
+// Command import carga pacientes desde una planilla CSV o XLSX.
+//
+//	go run ./cmd/import -archivo pacientes.xlsx -dry-run
+//	go run ./cmd/import -archivo pacientes.csv -mapeo "nombre_completo=Nombre,ci=Carnet"
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/tunek/centro-caribel/internal/application/paciente"
+	"github.com/tunek/centro-caribel/internal/infrastructure/config"
+	"github.com/tunek/centro-caribel/internal/infrastructure/database"
+	"github.com/tunek/centro-caribel/internal/infrastructure/repository"
+	"github.com/tunek/centro-caribel/pkg/spreadsheet"
+)
+
+func main() {
+	cfg := config.Load()
+
+	archivo := flag.String("archivo", "", "planilla .csv o .xlsx a importar")
+	mapeoFlag := flag.String("mapeo", "", "mapeo campo=Columna separado por comas (campos: "+strings.Join(paciente.CamposImportacion, ", ")+")")
+	dryRun := flag.Bool("dry-run", false, "solo validar y reportar errores, sin registrar pacientes")
+	email := flag.String("usuario", cfg.Admin.Email, "email del usuario que figura como creador de los pacientes")
+	flag.Parse()
+
+	if *archivo == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	mapeo := make(map[string]string)
+	if *mapeoFlag != "" {
+		for _, par := range strings.Split(*mapeoFlag, ",") {
+			campo, columna, ok := strings.Cut(par, "=")
+			if !ok {
+				log.Fatalf("Mapeo inválido %q: use campo=Columna", par)
+			}
+			mapeo[strings.TrimSpace(campo)] = strings.TrimSpace(columna)
+		}
+	}
+
+	f, err := os.Open(*archivo)
+	if err != nil {
+		log.Fatalf("Error abriendo archivo: %v", err)
+	}
+	registros, err := spreadsheet.Read(*archivo, f)
+	f.Close()
+	if err != nil {
+		log.Fatalf("Error leyendo planilla: %v", err)
+	}
+
+	filas, err := paciente.MapearFilas(registros, mapeo)
+	if err != nil {
+		log.Fatalf("Error mapeando columnas: %v", err)
+	}
+
+	db, err := database.NewConnection(cfg.DB)
+	if err != nil {
+		log.Fatalf("Error conectando a la base de datos: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	usuarioRepo := repository.NewUsuarioRepository(db)
+	user, err := usuarioRepo.GetByEmail(ctx, *email)
+	if err != nil {
+		log.Fatalf("Usuario %s no encontrado", *email)
+	}
+
+	pacienteSvc := paciente.NewService(
+		repository.NewPacienteRepository(db),
+		repository.NewHistoriaClinicaRepository(db),
+		repository.NewTutorRepository(db),
+	)
+
+	res, err := pacienteSvc.Importar(ctx, filas, *dryRun, user.ID)
+	if err != nil {
+		log.Fatalf("Error importando pacientes: %v", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(res)
+
+	fmt.Fprintf(os.Stderr, "Filas: %d, válidas: %d, importadas: %d, errores: %d\n",
+		res.TotalFilas, res.Validas, res.Importadas, len(res.Errores))
+	if len(res.Errores) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,277 @@
+package adjunto
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+)
+
+// TamanoMaximo limita el tamaño de cada adjunto.
+const TamanoMaximo = 20 << 20
+
+// tiposPermitidos son los formatos aceptados, detectados por contenido y no
+// por la extensión o el Content-Type declarado por el cliente.
+var tiposPermitidos = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/webp":      true,
+}
+
+type DatosAdjunto struct {
+	NotaID      *uuid.UUID
+	Descripcion string
+	Visibilidad domain.VisibilidadAdjunto
+}
+
+type Service struct {
+	repo         domain.AdjuntoRepository
+	pacienteRepo domain.PacienteRepository
+	historiaRepo domain.HistoriaClinicaRepository
+	notaRepo     domain.NotaEvolucionRepository
+	storage      domain.FileStorage
+	tx           domain.Transactor
+}
+
+func NewService(repo domain.AdjuntoRepository, pacienteRepo domain.PacienteRepository, historiaRepo domain.HistoriaClinicaRepository,
+	notaRepo domain.NotaEvolucionRepository, storage domain.FileStorage, tx domain.Transactor) *Service {
+	return &Service{
+		repo:         repo,
+		pacienteRepo: pacienteRepo,
+		historiaRepo: historiaRepo,
+		notaRepo:     notaRepo,
+		storage:      storage,
+		tx:           tx,
+	}
+}
+
+// Subir guarda un adjunto del paciente. El contenido se almacena una sola vez
+// por checksum; si el mismo archivo ya está adjuntado al paciente se rechaza.
+func (s *Service) Subir(ctx context.Context, pacienteID uuid.UUID, archivo io.ReadSeeker, tamano int64, nombreArchivo string, datos DatosAdjunto, subidoPor uuid.UUID, rolNombre string) (*domain.Adjunto, error) {
+	pac, err := s.pacienteRepo.GetByID(ctx, pacienteID)
+	if err != nil {
+		return nil, apperrors.NewNotFound("Paciente")
+	}
+	if pac.Archivado() {
+		return nil, apperrors.NewBadRequest("No se pueden adjuntar documentos a un paciente archivado")
+	}
+
+	if tamano <= 0 {
+		return nil, apperrors.NewBadRequest("El archivo está vacío")
+	}
+	if tamano > TamanoMaximo {
+		return nil, apperrors.NewBadRequest(fmt.Sprintf("El archivo excede el tamaño máximo de %d MB", TamanoMaximo>>20))
+	}
+
+	if datos.Visibilidad == "" {
+		datos.Visibilidad = domain.AdjuntoGeneral
+	}
+	if !datos.Visibilidad.IsValid() {
+		return nil, apperrors.NewBadRequest("Visibilidad inválida. Use: GENERAL, CLINICO o ADMIN")
+	}
+	if !datos.Visibilidad.VisiblePara(rolNombre) {
+		return nil, apperrors.NewForbidden("No puede asignar una visibilidad que su rol no puede consultar")
+	}
+
+	if datos.NotaID != nil {
+		if err := s.validarNota(ctx, pacienteID, *datos.NotaID); err != nil {
+			return nil, err
+		}
+	}
+
+	contentType, err := detectarTipo(archivo)
+	if err != nil {
+		return nil, err
+	}
+	checksum, err := calcularChecksum(archivo)
+	if err != nil {
+		return nil, err
+	}
+
+	existente, err := s.repo.GetByChecksum(ctx, pacienteID, checksum)
+	if err == nil {
+		return nil, apperrors.NewConflict(fmt.Sprintf("Este archivo ya fue adjuntado al paciente como %q", existente.NombreArchivo))
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, apperrors.NewInternal("Error al verificar el adjunto")
+	}
+
+	a := &domain.Adjunto{
+		ID:            uuid.New(),
+		PacienteID:    pacienteID,
+		NotaID:        datos.NotaID,
+		Descripcion:   strings.TrimSpace(datos.Descripcion),
+		Visibilidad:   datos.Visibilidad,
+		NombreArchivo: path.Base(nombreArchivo),
+		ContentType:   contentType,
+		TamanoBytes:   tamano,
+		SHA256:        checksum,
+		StorageKey:    fmt.Sprintf("adjuntos/%s/%s", checksum[:2], checksum),
+		SubidoPor:     subidoPor,
+	}
+
+	// Si otro paciente ya tiene el mismo contenido, se reutiliza el archivo. El
+	// bloqueo sobre la clave impide que un borrado simultáneo lo elimine entre
+	// el conteo y el alta del registro.
+	err = s.tx.EnTransaccion(ctx, func(ctx context.Context) error {
+		if err := s.repo.BloquearArchivo(ctx, a.StorageKey); err != nil {
+			return apperrors.NewInternal("Error al verificar el adjunto")
+		}
+		compartidos, err := s.repo.CountByStorageKey(ctx, a.StorageKey)
+		if err != nil {
+			return apperrors.NewInternal("Error al verificar el adjunto")
+		}
+		if compartidos == 0 {
+			if err := s.storage.Put(ctx, a.StorageKey, archivo, tamano, contentType); err != nil {
+				return apperrors.NewInternal("Error al guardar el adjunto")
+			}
+		}
+
+		if err := s.repo.Create(ctx, a); err != nil {
+			// Con el bloqueo tomado ningún otro registro pudo empezar a usar
+			// el archivo recién guardado, así que se puede quitar.
+			if compartidos == 0 {
+				_ = s.storage.Delete(ctx, a.StorageKey)
+			}
+			return apperrors.NewInternal("Error al registrar el adjunto")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errorInterno(err, "Error al registrar el adjunto")
+	}
+
+	return a, nil
+}
+
+// GetByPaciente lista los adjuntos que el rol puede ver, opcionalmente de una nota.
+func (s *Service) GetByPaciente(ctx context.Context, pacienteID uuid.UUID, notaID *uuid.UUID, rolNombre string) ([]domain.Adjunto, error) {
+	if _, err := s.pacienteRepo.GetByID(ctx, pacienteID); err != nil {
+		return nil, apperrors.NewNotFound("Paciente")
+	}
+
+	adjuntos, err := s.repo.GetByPacienteID(ctx, pacienteID, notaID, domain.VisibilidadesPara(rolNombre))
+	if err != nil {
+		return nil, apperrors.NewInternal("Error al obtener adjuntos")
+	}
+	if adjuntos == nil {
+		adjuntos = []domain.Adjunto{}
+	}
+	return adjuntos, nil
+}
+
+// Abrir devuelve el adjunto y su contenido. El llamador debe cerrar el lector.
+func (s *Service) Abrir(ctx context.Context, pacienteID, adjuntoID uuid.UUID, rolNombre string) (*domain.Adjunto, io.ReadSeekCloser, error) {
+	a, err := s.adjuntoVisible(ctx, pacienteID, adjuntoID, rolNombre)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contenido, err := s.storage.Get(ctx, a.StorageKey)
+	if err != nil {
+		return nil, nil, apperrors.NewInternal("Error al leer el adjunto")
+	}
+	return a, contenido, nil
+}
+
+// Delete elimina el registro y, si ningún otro adjunto comparte el archivo,
+// también el contenido almacenado.
+func (s *Service) Delete(ctx context.Context, pacienteID, adjuntoID uuid.UUID, rolNombre string) error {
+	a, err := s.adjuntoVisible(ctx, pacienteID, adjuntoID, rolNombre)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, a.ID); err != nil {
+		return apperrors.NewInternal("Error al eliminar el adjunto")
+	}
+
+	// El archivo se borra con el bloqueo de la clave tomado y solo si, ya
+	// confirmado el borrado del registro, nadie más lo usa; una subida
+	// simultánea del mismo contenido espera y vuelve a guardarlo. Si falla, el
+	// archivo queda huérfano pero ningún registro apunta a un archivo borrado.
+	_ = s.tx.EnTransaccion(ctx, func(ctx context.Context) error {
+		if err := s.repo.BloquearArchivo(ctx, a.StorageKey); err != nil {
+			return err
+		}
+		restantes, err := s.repo.CountByStorageKey(ctx, a.StorageKey)
+		if err != nil || restantes > 0 {
+			return err
+		}
+		return s.storage.Delete(ctx, a.StorageKey)
+	})
+	return nil
+}
+
+// errorInterno devuelve err si ya es un error de la aplicación o, si no (por
+// ejemplo, al confirmar la transacción), un error interno con detalle.
+func errorInterno(err error, detalle string) error {
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return apperrors.NewInternal(detalle)
+}
+
+// adjuntoVisible trata un adjunto de otro paciente o fuera del alcance del
+// rol como inexistente, para no revelar su existencia.
+func (s *Service) adjuntoVisible(ctx context.Context, pacienteID, adjuntoID uuid.UUID, rolNombre string) (*domain.Adjunto, error) {
+	a, err := s.repo.GetByID(ctx, adjuntoID)
+	if err != nil || a.PacienteID != pacienteID || !a.Visibilidad.VisiblePara(rolNombre) {
+		return nil, apperrors.NewNotFound("Adjunto")
+	}
+	return a, nil
+}
+
+func (s *Service) validarNota(ctx context.Context, pacienteID, notaID uuid.UUID) error {
+	historia, err := s.historiaRepo.GetByPacienteID(ctx, pacienteID)
+	if err != nil {
+		return apperrors.NewNotFound("Historia clínica")
+	}
+	nota, err := s.notaRepo.GetByID(ctx, notaID)
+	if err != nil || nota.HistoriaID != historia.ID {
+		return apperrors.NewNotFound("Nota de evolución")
+	}
+	return nil
+}
+
+// detectarTipo identifica el formato por los primeros bytes del archivo y
+// vuelve al inicio para la lectura posterior.
+func detectarTipo(archivo io.ReadSeeker) (string, error) {
+	cabecera := make([]byte, 512)
+	n, err := io.ReadFull(archivo, cabecera)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", apperrors.NewBadRequest("No se pudo leer el archivo")
+	}
+	if _, err := archivo.Seek(0, io.SeekStart); err != nil {
+		return "", apperrors.NewInternal("Error al procesar el archivo")
+	}
+
+	contentType := http.DetectContentType(cabecera[:n])
+	if !tiposPermitidos[contentType] {
+		return "", apperrors.NewBadRequest("Formato no permitido. Use PDF, JPEG, PNG o WebP")
+	}
+	return contentType, nil
+}
+
+func calcularChecksum(archivo io.ReadSeeker) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, archivo); err != nil {
+		return "", apperrors.NewBadRequest("No se pudo leer el archivo")
+	}
+	if _, err := archivo.Seek(0, io.SeekStart); err != nil {
+		return "", apperrors.NewInternal("Error al procesar el archivo")
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
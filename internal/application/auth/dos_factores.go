@@ -0,0 +1,350 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+	"github.com/tunek/centro-caribel/pkg/totp"
+)
+
+// cantidadCodigosRecuperacion es cuántos códigos de recuperación se emiten.
+const cantidadCodigosRecuperacion = 10
+
+// ConfigTOTP configura la autenticación en dos pasos. Emisor es el nombre que
+// muestran las apps de autenticación; los usuarios de RolesRequeridos deben
+// inscribirse antes de usar la API.
+type ConfigTOTP struct {
+	Emisor          string
+	RolesRequeridos []string
+	Cifrador        *totp.Cipher
+}
+
+// Inscripcion2FA son los datos para configurar la app de autenticación. URI
+// es el otpauth:// que se muestra como código QR.
+type Inscripcion2FA struct {
+	Secreto string `json:"secreto"`
+	URI     string `json:"uri"`
+}
+
+// Estado2FA resume la configuración del segundo factor de un usuario.
+type Estado2FA struct {
+	Habilitado       bool `json:"habilitado"`
+	Requerido        bool `json:"requerido"`
+	CodigosRestantes int  `json:"codigos_recuperacion_restantes"`
+}
+
+// Verificar2FA completa un login que quedó pendiente del código TOTP. codigo
+// puede ser un código de la app o uno de recuperación. Los códigos erróneos
+// cuentan como intentos fallidos del login.
+func (s *Service) Verificar2FA(ctx context.Context, preAuthToken, codigo string, disp Dispositivo) (*ResultadoLogin, error) {
+	invalido := apperrors.NewUnauthorized("El paso previo del login es inválido o venció; inicie sesión nuevamente")
+	claims, err := s.jwt.ValidatePreAuthToken(preAuthToken)
+	if err != nil {
+		return nil, invalido
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, invalido
+	}
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil || !user.Activo || user.TokenVersion != claims.TokenVersion {
+		return nil, invalido
+	}
+
+	if err := s.verificarLimites(ctx, user, user.Email, disp, time.Now()); err != nil {
+		return nil, err
+	}
+
+	t, err := s.totpRepo.GetByUsuarioID(ctx, user.ID)
+	if err != nil || !t.Habilitado {
+		return nil, invalido
+	}
+	ok, err := s.verificarCodigo(ctx, t, codigo, true)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, s.loginFallido(ctx, user, user.Email, disp, domain.LoginCodigo2FA)
+	}
+
+	return s.loginExitoso(ctx, user, user.Email, disp)
+}
+
+// Inscribir2FA genera un secreto nuevo para el usuario. Queda pendiente
+// hasta que Activar2FA confirma un código; una inscripción pendiente
+// anterior se reemplaza.
+func (s *Service) Inscribir2FA(ctx context.Context, userID uuid.UUID) (*Inscripcion2FA, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, apperrors.NewNotFound("Usuario")
+	}
+	habilitado, err := s.totpHabilitado(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if habilitado {
+		return nil, apperrors.NewConflict("La autenticación en dos pasos ya está activada")
+	}
+
+	secreto, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, apperrors.NewInternal("Error al generar el secreto")
+	}
+	cifrado, err := s.totpCfg.Cifrador.Seal(secreto)
+	if err != nil {
+		return nil, apperrors.NewInternal("Error al cifrar el secreto")
+	}
+	if err := s.totpRepo.GuardarPendiente(ctx, user.ID, cifrado); err != nil {
+		return nil, apperrors.NewInternal("Error al guardar la inscripción")
+	}
+
+	return &Inscripcion2FA{Secreto: secreto, URI: totp.URI(s.totpCfg.Emisor, user.Email, secreto)}, nil
+}
+
+// Activar2FA confirma la inscripción con un código de la app. Devuelve los
+// códigos de recuperación, que no se vuelven a mostrar, y una sesión nueva
+// sin la restricción de inscripción.
+func (s *Service) Activar2FA(ctx context.Context, userID uuid.UUID, codigo string, disp Dispositivo) ([]string, *ResultadoLogin, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, apperrors.NewNotFound("Usuario")
+	}
+	t, err := s.totpRepo.GetByUsuarioID(ctx, user.ID)
+	if err != nil {
+		return nil, nil, apperrors.NewBadRequest("No hay una inscripción pendiente; use POST /auth/2fa/inscribir")
+	}
+	if t.Habilitado {
+		return nil, nil, apperrors.NewConflict("La autenticación en dos pasos ya está activada")
+	}
+
+	secreto, err := s.totpCfg.Cifrador.Open(t.SecretoCifrado)
+	if err != nil {
+		return nil, nil, apperrors.NewInternal("Error al descifrar el secreto")
+	}
+	paso, ok := totp.Validate(secreto, codigo, time.Now(), 1)
+	if !ok {
+		return nil, nil, apperrors.NewBadRequest("Código incorrecto")
+	}
+
+	codigos, hashes, err := generarCodigosRecuperacion()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.totpRepo.Habilitar(ctx, user.ID, paso, hashes); err != nil {
+		return nil, nil, apperrors.NewInternal("Error al activar la autenticación en dos pasos")
+	}
+
+	res, err := s.nuevaSesion(ctx, user, disp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return codigos, res, nil
+}
+
+// RegenerarCodigos2FA reemplaza los códigos de recuperación, previa
+// verificación de un código de la app. Los códigos erróneos cuentan como
+// intentos fallidos del login.
+func (s *Service) RegenerarCodigos2FA(ctx context.Context, userID uuid.UUID, codigo string, disp Dispositivo) ([]string, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, apperrors.NewNotFound("Usuario")
+	}
+	t, err := s.totpRepo.GetByUsuarioID(ctx, userID)
+	if err != nil || !t.Habilitado {
+		return nil, apperrors.NewBadRequest("La autenticación en dos pasos no está activada")
+	}
+	if err := s.verificarLimites(ctx, user, user.Email, disp, time.Now()); err != nil {
+		return nil, err
+	}
+	ok, err := s.verificarCodigo(ctx, t, codigo, false)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, s.codigoIncorrecto(ctx, user, disp)
+	}
+	if err := s.reiniciarFallos(ctx, user); err != nil {
+		return nil, err
+	}
+
+	codigos, hashes, err := generarCodigosRecuperacion()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.totpRepo.ReemplazarCodigos(ctx, userID, hashes); err != nil {
+		return nil, apperrors.NewInternal("Error al guardar los códigos de recuperación")
+	}
+	return codigos, nil
+}
+
+// Desactivar2FA quita el segundo factor del usuario, previa verificación de
+// un código, y cierra sus sesiones como Reset2FA. Devuelve una sesión nueva
+// para el dispositivo actual. No se permite si su rol lo exige, y los
+// códigos erróneos cuentan como intentos fallidos del login.
+func (s *Service) Desactivar2FA(ctx context.Context, userID uuid.UUID, codigo string, disp Dispositivo) (*ResultadoLogin, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, apperrors.NewNotFound("Usuario")
+	}
+	if s.rolRequiere2FA(user.Rol.Nombre) {
+		return nil, apperrors.NewForbidden("Su rol exige autenticación en dos pasos")
+	}
+	t, err := s.totpRepo.GetByUsuarioID(ctx, userID)
+	if err != nil || !t.Habilitado {
+		return nil, apperrors.NewBadRequest("La autenticación en dos pasos no está activada")
+	}
+	if err := s.verificarLimites(ctx, user, user.Email, disp, time.Now()); err != nil {
+		return nil, err
+	}
+	ok, err := s.verificarCodigo(ctx, t, codigo, true)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, s.codigoIncorrecto(ctx, user, disp)
+	}
+	if err := s.reiniciarFallos(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if err := s.totpRepo.Delete(ctx, userID); err != nil {
+		return nil, apperrors.NewInternal("Error al desactivar la autenticación en dos pasos")
+	}
+	if err := s.refreshRepo.RevocarUsuario(ctx, userID, domain.TokenDesact2FA); err != nil {
+		return nil, apperrors.NewInternal("Error al cerrar las sesiones")
+	}
+	if err := s.userRepo.IncrementTokenVersion(ctx, userID); err != nil {
+		return nil, apperrors.NewInternal("Error al cerrar las sesiones")
+	}
+	user.TokenVersion++
+	s.sesiones.Invalidar(userID)
+	return s.nuevaSesion(ctx, user, disp)
+}
+
+// Reset2FA lo usa la Administradora cuando un usuario pierde su dispositivo:
+// borra la inscripción y los códigos de recuperación y cierra sus sesiones.
+// En el próximo login el usuario vuelve a inscribirse si su rol lo exige.
+func (s *Service) Reset2FA(ctx context.Context, userID uuid.UUID) error {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return apperrors.NewNotFound("Usuario")
+	}
+	if err := s.totpRepo.Delete(ctx, userID); err != nil {
+		return apperrors.NewInternal("Error al restablecer la autenticación en dos pasos")
+	}
+	if err := s.refreshRepo.RevocarUsuario(ctx, userID, domain.TokenReset2FA); err != nil {
+		return apperrors.NewInternal("Error al cerrar las sesiones")
+	}
+	if err := s.userRepo.IncrementTokenVersion(ctx, userID); err != nil {
+		return apperrors.NewInternal("Error al cerrar las sesiones")
+	}
+	s.sesiones.Invalidar(userID)
+	return nil
+}
+
+func (s *Service) GetEstado2FA(ctx context.Context, userID uuid.UUID) (*Estado2FA, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, apperrors.NewNotFound("Usuario")
+	}
+	estado := &Estado2FA{Requerido: s.rolRequiere2FA(user.Rol.Nombre)}
+	estado.Habilitado, err = s.totpHabilitado(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if estado.Habilitado {
+		if estado.CodigosRestantes, err = s.totpRepo.CodigosRestantes(ctx, userID); err != nil {
+			return nil, apperrors.NewInternal("Error al obtener los códigos de recuperación")
+		}
+	}
+	return estado, nil
+}
+
+// codigoIncorrecto registra el código erróneo de un usuario con la sesión
+// abierta como intento fallido del login. Responde 400 en lugar del 401 del
+// login, salvo que la cuenta quede bloqueada.
+func (s *Service) codigoIncorrecto(ctx context.Context, user *domain.Usuario, disp Dispositivo) error {
+	err := s.loginFallido(ctx, user, user.Email, disp, domain.LoginCodigo2FA)
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) && appErr.Code == http.StatusUnauthorized {
+		return apperrors.NewBadRequest("Código incorrecto")
+	}
+	return err
+}
+
+// verificarCodigo acepta un código TOTP no usado antes o, si
+// permitirRecuperacion, un código de recuperación, que queda consumido.
+func (s *Service) verificarCodigo(ctx context.Context, t *domain.TOTPUsuario, codigo string, permitirRecuperacion bool) (bool, error) {
+	secreto, err := s.totpCfg.Cifrador.Open(t.SecretoCifrado)
+	if err != nil {
+		return false, apperrors.NewInternal("Error al descifrar el secreto")
+	}
+	if paso, ok := totp.Validate(secreto, codigo, time.Now(), 1); ok {
+		nuevo, err := s.totpRepo.RegistrarPaso(ctx, t.UsuarioID, paso)
+		if err != nil {
+			return false, apperrors.NewInternal("Error al verificar el código")
+		}
+		return nuevo, nil
+	}
+	if !permitirRecuperacion {
+		return false, nil
+	}
+
+	ok, err := s.totpRepo.ConsumirCodigo(ctx, t.UsuarioID, hashToken(normalizarCodigo(codigo)))
+	if err != nil {
+		return false, apperrors.NewInternal("Error al verificar el código")
+	}
+	return ok, nil
+}
+
+func (s *Service) totpHabilitado(ctx context.Context, userID uuid.UUID) (bool, error) {
+	t, err := s.totpRepo.GetByUsuarioID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, apperrors.NewInternal("Error al obtener la autenticación en dos pasos")
+	}
+	return t.Habilitado, nil
+}
+
+func (s *Service) rolRequiere2FA(rolNombre string) bool {
+	for _, r := range s.totpCfg.RolesRequeridos {
+		if r == rolNombre {
+			return true
+		}
+	}
+	return false
+}
+
+// generarCodigosRecuperacion devuelve los códigos en claro, con formato
+// XXXX-XXXX-XXXX-XXXX (80 bits), y sus hashes para guardar.
+func generarCodigosRecuperacion() ([]string, []string, error) {
+	codigos := make([]string, cantidadCodigosRecuperacion)
+	hashes := make([]string, cantidadCodigosRecuperacion)
+	for i := range codigos {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, apperrors.NewInternal("Error al generar los códigos de recuperación")
+		}
+		c := base32.StdEncoding.EncodeToString(b)
+		codigos[i] = c[0:4] + "-" + c[4:8] + "-" + c[8:12] + "-" + c[12:16]
+		hashes[i] = hashToken(c)
+	}
+	return codigos, hashes, nil
+}
+
+// normalizarCodigo quita guiones y espacios y pasa a mayúsculas, para
+// aceptar el código de recuperación como sea que se haya tipeado.
+func normalizarCodigo(codigo string) string {
+	codigo = strings.ToUpper(codigo)
+	return strings.NewReplacer("-", "", " ", "").Replace(codigo)
+}
@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/tunek/centro-caribel/internal/domain"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+)
+
+// PoliticaLogin limita los intentos de login. Tras cada fallo de una cuenta
+// hay que esperar DemoraBase, duplicada por cada fallo consecutivo y con tope
+// DemoraMax, antes de volver a intentar; al llegar a MaxIntentos la cuenta se
+// bloquea durante Bloqueo. Aparte, una IP con MaxFallidosIP fallos en
+// VentanaIP queda rechazada sin importar la cuenta.
+type PoliticaLogin struct {
+	MaxIntentos   int
+	Bloqueo       time.Duration
+	DemoraBase    time.Duration
+	DemoraMax     time.Duration
+	MaxFallidosIP int
+	VentanaIP     time.Duration
+}
+
+// demora es la espera exigida después de fallos intentos fallidos consecutivos.
+func (p PoliticaLogin) demora(fallos int) time.Duration {
+	if fallos < 1 || p.DemoraBase <= 0 {
+		return 0
+	}
+	if fallos > 30 {
+		fallos = 30 // evita desbordar la duración
+	}
+	d := p.DemoraBase << (fallos - 1)
+	if p.DemoraMax > 0 && (d > p.DemoraMax || d <= 0) {
+		d = p.DemoraMax
+	}
+	return d
+}
+
+// verificarLimites rechaza el intento si la IP superó su límite, si la cuenta
+// está bloqueada o si todavía no pasó la demora desde el último fallo. user
+// es nil si el email no corresponde a ningún usuario.
+func (s *Service) verificarLimites(ctx context.Context, user *domain.Usuario, email string, disp Dispositivo, now time.Time) error {
+	if s.politica.MaxFallidosIP > 0 && disp.IP != "" {
+		n, err := s.intentoRepo.CountFallidosPorIP(ctx, disp.IP, now.Add(-s.politica.VentanaIP))
+		if err != nil {
+			return apperrors.NewInternal("Error al verificar los intentos de login")
+		}
+		if n >= s.politica.MaxFallidosIP {
+			if err := s.registrarIntento(ctx, email, user, disp, domain.LoginLimiteIP); err != nil {
+				return err
+			}
+			return apperrors.NewTooManyRequests("Demasiados intentos fallidos desde esta dirección; intente más tarde",
+				segundos(s.politica.VentanaIP))
+		}
+	}
+	if user == nil {
+		return s.limitesSinUsuario(ctx, email, disp, now)
+	}
+
+	if user.BloqueadoHasta != nil && now.Before(*user.BloqueadoHasta) {
+		if err := s.registrarIntento(ctx, email, user, disp, domain.LoginBloqueado); err != nil {
+			return err
+		}
+		return cuentaBloqueada(user.BloqueadoHasta.Sub(now))
+	}
+
+	if user.IntentosFallidos > 0 && user.UltimoFalloAt != nil {
+		return s.exigirDemora(ctx, user, email, disp, user.IntentosFallidos, *user.UltimoFalloAt, now)
+	}
+	return nil
+}
+
+// limitesSinUsuario aplica a un email sin usuario la misma demora y el mismo
+// bloqueo que a una cuenta, contando sus fallos en el registro de logins, para
+// que las respuestas no revelen qué emails tienen cuenta.
+func (s *Service) limitesSinUsuario(ctx context.Context, email string, disp Dispositivo, now time.Time) error {
+	fallos, ultimo, err := s.intentoRepo.FallidosSinUsuario(ctx, truncar(email, 255), now.Add(-s.politica.Bloqueo))
+	if err != nil {
+		return apperrors.NewInternal("Error al verificar los intentos de login")
+	}
+	if fallos == 0 || ultimo == nil {
+		return nil
+	}
+
+	if s.politica.MaxIntentos > 0 && fallos >= s.politica.MaxIntentos {
+		if restante := ultimo.Add(s.politica.Bloqueo).Sub(now); restante > 0 {
+			if err := s.registrarIntento(ctx, email, nil, disp, domain.LoginBloqueado); err != nil {
+				return err
+			}
+			return cuentaBloqueada(restante)
+		}
+		return nil
+	}
+	return s.exigirDemora(ctx, nil, email, disp, fallos, *ultimo, now)
+}
+
+// exigirDemora rechaza el intento si no pasó la demora correspondiente a
+// fallos desde ultimoFallo.
+func (s *Service) exigirDemora(ctx context.Context, user *domain.Usuario, email string, disp Dispositivo, fallos int, ultimoFallo, now time.Time) error {
+	espera := ultimoFallo.Add(s.politica.demora(fallos)).Sub(now)
+	if espera <= 0 {
+		return nil
+	}
+	if err := s.registrarIntento(ctx, email, user, disp, domain.LoginDemora); err != nil {
+		return err
+	}
+	return apperrors.NewTooManyRequests(
+		fmt.Sprintf("Espere %d segundos antes de volver a intentar", segundos(espera)), segundos(espera))
+}
+
+// loginFallido suma el fallo (contraseña, código 2FA o contraseña actual
+// incorrectos) a la cuenta y devuelve el error para el cliente: 429 si con
+// este fallo la cuenta quedó bloqueada; si no, 401, o 400 para la contraseña
+// actual, que se pide con la sesión ya abierta. Un email sin usuario se
+// bloquea igual que una cuenta.
+func (s *Service) loginFallido(ctx context.Context, user *domain.Usuario, email string, disp Dispositivo, motivo domain.MotivoIntentoLogin) error {
+	if err := s.registrarIntento(ctx, email, user, disp, motivo); err != nil {
+		return err
+	}
+	var invalido error
+	switch motivo {
+	case domain.LoginCodigo2FA:
+		invalido = apperrors.NewUnauthorized("Código de verificación incorrecto")
+	case domain.LoginPasswordActual:
+		invalido = apperrors.NewBadRequest("La contraseña actual es incorrecta")
+	default:
+		invalido = apperrors.NewUnauthorized("Credenciales inválidas")
+	}
+	if user == nil {
+		if s.politica.MaxIntentos <= 0 {
+			return invalido
+		}
+		fallos, _, err := s.intentoRepo.FallidosSinUsuario(ctx, truncar(email, 255), time.Now().Add(-s.politica.Bloqueo))
+		if err != nil {
+			return apperrors.NewInternal("Error al registrar el intento de login")
+		}
+		if fallos >= s.politica.MaxIntentos {
+			return cuentaBloqueada(s.politica.Bloqueo)
+		}
+		return invalido
+	}
+
+	_, bloqueadoHasta, err := s.userRepo.RegistrarLoginFallido(ctx, user.ID, s.politica.MaxIntentos, s.politica.Bloqueo)
+	if err != nil {
+		return apperrors.NewInternal("Error al registrar el intento de login")
+	}
+	if bloqueadoHasta != nil {
+		if restante := time.Until(*bloqueadoHasta); restante > 0 {
+			return cuentaBloqueada(restante)
+		}
+	}
+	return invalido
+}
+
+// loginExitoso reinicia la cuenta de fallos, registra el intento y abre la sesión.
+func (s *Service) loginExitoso(ctx context.Context, user *domain.Usuario, email string, disp Dispositivo) (*ResultadoLogin, error) {
+	if user.IntentosFallidos > 0 || user.BloqueadoHasta != nil {
+		if err := s.userRepo.Desbloquear(ctx, user.ID); err != nil {
+			return nil, apperrors.NewInternal("Error al registrar el intento de login")
+		}
+	}
+	if err := s.registrarIntento(ctx, email, user, disp, ""); err != nil {
+		return nil, err
+	}
+	return s.nuevaSesion(ctx, user, disp)
+}
+
+// reiniciarFallos pone en cero la cuenta de fallos después de que el usuario,
+// con la sesión ya abierta, confirma su contraseña o su código 2FA.
+func (s *Service) reiniciarFallos(ctx context.Context, user *domain.Usuario) error {
+	if user.IntentosFallidos == 0 {
+		return nil
+	}
+	if err := s.userRepo.Desbloquear(ctx, user.ID); err != nil {
+		return apperrors.NewInternal("Error al desbloquear el usuario")
+	}
+	return nil
+}
+
+// registrarIntento guarda el intento en el registro de logins. motivo vacío
+// indica un login exitoso.
+func (s *Service) registrarIntento(ctx context.Context, email string, user *domain.Usuario, disp Dispositivo, motivo domain.MotivoIntentoLogin) error {
+	intento := &domain.IntentoLogin{
+		Email:     truncar(email, 255),
+		Exitoso:   motivo == "",
+		IP:        truncar(disp.IP, 45),
+		UserAgent: truncar(disp.UserAgent, 255),
+	}
+	if user != nil {
+		intento.UsuarioID = &user.ID
+	}
+	if motivo != "" {
+		intento.Motivo = &motivo
+	}
+	if err := s.intentoRepo.Create(ctx, intento); err != nil {
+		return apperrors.NewInternal("Error al registrar el intento de login")
+	}
+	return nil
+}
+
+// GetIntentos lista el registro de intentos de login, del más reciente al más antiguo.
+func (s *Service) GetIntentos(ctx context.Context, filtro domain.FiltroIntentosLogin, page, perPage int) ([]domain.IntentoLogin, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+	offset := (page - 1) * perPage
+
+	intentos, total, err := s.intentoRepo.GetAll(ctx, filtro, offset, perPage)
+	if err != nil {
+		return nil, 0, apperrors.NewInternal("Error al obtener los intentos de login")
+	}
+	return intentos, total, nil
+}
+
+func cuentaBloqueada(restante time.Duration) error {
+	minutos := int(math.Ceil(restante.Minutes()))
+	return apperrors.NewTooManyRequests(
+		fmt.Sprintf("Cuenta bloqueada por intentos fallidos; intente nuevamente en %d minutos", minutos),
+		segundos(restante))
+}
+
+// segundos redondea hacia arriba, para que Retry-After nunca llegue antes de tiempo.
+func segundos(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// CambiarPassword reemplaza la contraseña del usuario autenticado. Cierra
+// todas sus sesiones y devuelve una nueva para el dispositivo actual. La
+// contraseña actual incorrecta cuenta como intento fallido del login, para
+// que un token robado no sirva para adivinarla.
+func (s *Service) CambiarPassword(ctx context.Context, userID uuid.UUID, actual, nueva string, disp Dispositivo) (*ResultadoLogin, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, apperrors.NewNotFound("Usuario")
+	}
+	if err := s.verificarLimites(ctx, user, user.Email, disp, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(actual)); err != nil {
+		return nil, s.loginFallido(ctx, user, user.Email, disp, domain.LoginPasswordActual)
+	}
+	if nueva == actual {
+		return nil, apperrors.NewBadRequest("La nueva contraseña debe ser distinta de la actual")
+	}
+
+	if err := s.reemplazarPassword(ctx, user, nueva); err != nil {
+		return nil, err
+	}
+	if err := s.reiniciarFallos(ctx, user); err != nil {
+		return nil, err
+	}
+	return s.nuevaSesion(ctx, user, disp)
+}
+
+// SolicitarResetPassword emite un token de restablecimiento para el usuario
+// y lo entrega por el notificador. El token anterior pendiente deja de valer.
+// Devuelve el vencimiento del token.
+func (s *Service) SolicitarResetPassword(ctx context.Context, userID, adminID uuid.UUID) (time.Time, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return time.Time{}, apperrors.NewNotFound("Usuario")
+	}
+	if !user.Activo {
+		return time.Time{}, apperrors.NewBadRequest("El usuario está desactivado")
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return time.Time{}, apperrors.NewInternal("Error al generar el token")
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	t := &domain.PasswordResetToken{
+		UsuarioID: user.ID,
+		TokenHash: hashToken(token),
+		CreadoPor: adminID,
+		ExpiresAt: time.Now().Add(s.resetTTL),
+	}
+	if err := s.resetRepo.Create(ctx, t); err != nil {
+		return time.Time{}, apperrors.NewInternal("Error al registrar el token")
+	}
+	if err := s.notificador.EnviarResetPassword(ctx, user, token, t.ExpiresAt); err != nil {
+		return time.Time{}, apperrors.NewInternal("Error al enviar el restablecimiento")
+	}
+	return t.ExpiresAt, nil
+}
+
+// RestablecerPassword canjea un token de restablecimiento por una contraseña
+// nueva. También quita un bloqueo por intentos fallidos.
+func (s *Service) RestablecerPassword(ctx context.Context, token, nueva string) error {
+	// La política se valida antes de consumir el token, para no gastarlo con
+	// una contraseña que se va a rechazar.
+	if err := s.politicaPassword.Validate(nueva); err != nil {
+		return err
+	}
+
+	t, err := s.resetRepo.Consumir(ctx, hashToken(token))
+	if err != nil {
+		return apperrors.NewBadRequest("El token de restablecimiento es inválido o venció")
+	}
+	user, err := s.userRepo.GetByID(ctx, t.UsuarioID)
+	if err != nil {
+		return apperrors.NewNotFound("Usuario")
+	}
+	if !user.Activo {
+		return apperrors.NewBadRequest("El usuario está desactivado")
+	}
+
+	if err := s.reemplazarPassword(ctx, user, nueva); err != nil {
+		return err
+	}
+	if err := s.userRepo.Desbloquear(ctx, user.ID); err != nil {
+		return apperrors.NewInternal("Error al desbloquear el usuario")
+	}
+	return nil
+}
+
+// reemplazarPassword valida y guarda la contraseña nueva, y revoca las
+// sesiones del usuario. Deja user con la versión de tokens vigente.
+func (s *Service) reemplazarPassword(ctx context.Context, user *domain.Usuario, nueva string) error {
+	if err := s.politicaPassword.Validate(nueva); err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(nueva), bcrypt.DefaultCost)
+	if err != nil {
+		return apperrors.NewInternal("Error al procesar la contraseña")
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, string(hash)); err != nil {
+		return apperrors.NewInternal("Error al actualizar la contraseña")
+	}
+	user.PasswordHash = string(hash)
+	user.DebeCambiarPassword = false
+	user.TokenVersion++
+	s.sesiones.Invalidar(user.ID)
+
+	if err := s.refreshRepo.RevocarUsuario(ctx, user.ID, domain.TokenCambioPass); err != nil {
+		return apperrors.NewInternal("Error al cerrar las sesiones")
+	}
+	return nil
+}
@@ -2,101 +2,370 @@ package auth
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/tunek/centro-caribel/internal/domain"
 	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+	"github.com/tunek/centro-caribel/pkg/password"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type JWTService interface {
-	GenerateToken(userID, rolNombre string) (string, error)
-	GenerateRefreshToken(userID string) (string, error)
+	GenerateToken(c Claims) (string, error)
+	GenerateRefreshToken(userID, tokenID string) (string, time.Time, error)
+	GeneratePreAuthToken(userID string, version int) (string, error)
 	ValidateToken(token string) (*Claims, error)
-	ValidateRefreshToken(token string) (string, error)
+	ValidateRefreshToken(token string) (*RefreshClaims, error)
+	ValidatePreAuthToken(token string) (*Claims, error)
+	// PublicKeys devuelve las claves públicas de verificación (JWKS).
+	PublicKeys() []JWK
 }
 
 type Claims struct {
-	UserID    string `json:"user_id"`
-	RolNombre string `json:"rol_nombre"`
+	UserID       string `json:"user_id"`
+	RolNombre    string `json:"rol_nombre"`
+	TokenVersion int    `json:"ver"`
+	// CambioPassword indica que el usuario debe cambiar la contraseña antes
+	// de usar el resto de la API.
+	CambioPassword bool `json:"cpw,omitempty"`
+	// Inscribir2FA indica que el rol exige autenticación en dos pasos y el
+	// usuario todavía no la configuró.
+	Inscribir2FA bool `json:"i2f,omitempty"`
+}
+
+// Restringido indica si el token solo sirve para completar una acción
+// pendiente (cambiar la contraseña o inscribir el segundo factor).
+func (c *Claims) Restringido() bool {
+	return c.CambioPassword || c.Inscribir2FA
+}
+
+// JWK es una clave pública de verificación de tokens en formato JSON Web Key
+// (RFC 7517). N y E son de claves RSA; Crv y X, de claves Ed25519.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// RefreshClaims identifica al usuario y al registro (jti) de un refresh token.
+type RefreshClaims struct {
+	UserID  string
+	TokenID string
+}
+
+// Dispositivo describe desde dónde se inició o renovó la sesión.
+type Dispositivo struct {
+	UserAgent string
+	IP        string
+}
+
+// ResultadoLogin son los tokens de una sesión iniciada o renovada.
+// DebeCambiarPassword y DebeInscribir2FA indican que el access token solo
+// sirve para completar esa acción. Si Requiere2FA es true no hay sesión
+// todavía: PreAuthToken se canjea junto con el código TOTP en Verificar2FA.
+type ResultadoLogin struct {
+	Token               string
+	RefreshToken        string
+	DebeCambiarPassword bool
+	DebeInscribir2FA    bool
+	Requiere2FA         bool
+	PreAuthToken        string
 }
 
 type Service struct {
-	userRepo domain.UsuarioRepository
-	rolRepo  domain.RolRepository
-	jwt      JWTService
+	userRepo         domain.UsuarioRepository
+	rolRepo          domain.RolRepository
+	refreshRepo      domain.RefreshTokenRepository
+	intentoRepo      domain.IntentoLoginRepository
+	resetRepo        domain.PasswordResetTokenRepository
+	notificador      domain.Notificador
+	jwt              JWTService
+	sesiones         *Sesiones
+	politica         PoliticaLogin
+	politicaPassword password.Policy
+	resetTTL         time.Duration
+	totpRepo         domain.TOTPRepository
+	totpCfg          ConfigTOTP
 }
 
-func NewService(userRepo domain.UsuarioRepository, rolRepo domain.RolRepository, jwt JWTService) *Service {
-	return &Service{userRepo: userRepo, rolRepo: rolRepo, jwt: jwt}
+func NewService(userRepo domain.UsuarioRepository, rolRepo domain.RolRepository, refreshRepo domain.RefreshTokenRepository,
+	intentoRepo domain.IntentoLoginRepository, resetRepo domain.PasswordResetTokenRepository, notificador domain.Notificador,
+	jwt JWTService, sesiones *Sesiones, politica PoliticaLogin, politicaPassword password.Policy, resetTTL time.Duration,
+	totpRepo domain.TOTPRepository, totpCfg ConfigTOTP) *Service {
+	return &Service{
+		userRepo:         userRepo,
+		rolRepo:          rolRepo,
+		refreshRepo:      refreshRepo,
+		intentoRepo:      intentoRepo,
+		resetRepo:        resetRepo,
+		notificador:      notificador,
+		jwt:              jwt,
+		sesiones:         sesiones,
+		politica:         politica,
+		politicaPassword: politicaPassword,
+		resetTTL:         resetTTL,
+		totpRepo:         totpRepo,
+		totpCfg:          totpCfg,
+	}
 }
 
-func (s *Service) Login(ctx context.Context, email, password string) (token, refreshToken string, err error) {
-	user, err := s.userRepo.GetByEmail(ctx, email)
+// hashFicticio es un hash bcrypt con el costo de los reales, para comparar
+// cuando el email no corresponde a ningún usuario.
+var hashFicticio = sync.OnceValue(func() []byte {
+	h, err := bcrypt.GenerateFromPassword([]byte("centro-caribel"), bcrypt.DefaultCost)
 	if err != nil {
-		return "", "", apperrors.NewUnauthorized("Credenciales inválidas")
+		panic(err)
+	}
+	return h
+})
+
+// Login valida las credenciales aplicando PoliticaLogin y deja constancia
+// de cada intento, exitoso o no, en el registro de logins. Si el usuario tiene
+// activada la autenticación en dos pasos, devuelve solo un PreAuthToken.
+func (s *Service) Login(ctx context.Context, email, pass string, disp Dispositivo) (*ResultadoLogin, error) {
+	user, _ := s.userRepo.GetByEmail(ctx, email)
+
+	if err := s.verificarLimites(ctx, user, email, disp, time.Now()); err != nil {
+		return nil, err
+	}
+	if user == nil {
+		// Compara contra un hash ficticio para que el tiempo de respuesta no
+		// revele que el email no tiene cuenta.
+		bcrypt.CompareHashAndPassword(hashFicticio(), []byte(pass))
+		return nil, s.loginFallido(ctx, nil, email, disp, domain.LoginCredenciales)
 	}
 
 	if !user.Activo {
-		return "", "", apperrors.NewUnauthorized("Usuario desactivado")
+		if err := s.registrarIntento(ctx, email, user, disp, domain.LoginDesactivado); err != nil {
+			return nil, err
+		}
+		return nil, apperrors.NewUnauthorized("Usuario desactivado")
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return "", "", apperrors.NewUnauthorized("Credenciales inválidas")
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(pass)); err != nil {
+		return nil, s.loginFallido(ctx, user, email, disp, domain.LoginCredenciales)
 	}
 
-	rol, err := s.rolRepo.GetByID(ctx, user.RolID)
+	con2FA, err := s.totpHabilitado(ctx, user.ID)
 	if err != nil {
-		return "", "", apperrors.NewInternal("Error al obtener rol")
+		return nil, err
+	}
+	if con2FA {
+		preAuth, err := s.jwt.GeneratePreAuthToken(user.ID.String(), user.TokenVersion)
+		if err != nil {
+			return nil, apperrors.NewInternal("Error al generar token")
+		}
+		return &ResultadoLogin{Requiere2FA: true, PreAuthToken: preAuth}, nil
 	}
 
-	token, err = s.jwt.GenerateToken(user.ID.String(), rol.Nombre)
+	return s.loginExitoso(ctx, user, email, disp)
+}
+
+// RefreshToken canjea un refresh token por un access token y un refresh token
+// nuevos. Cada refresh token sirve una sola vez: si se presenta uno ya usado,
+// se asume que fue robado y se revoca toda su familia, lo que cierra también
+// la sesión del cliente legítimo.
+func (s *Service) RefreshToken(ctx context.Context, refreshToken string, disp Dispositivo) (*ResultadoLogin, error) {
+	anterior, err := s.refreshRegistrado(ctx, refreshToken)
 	if err != nil {
-		return "", "", apperrors.NewInternal("Error al generar token")
+		return nil, err
+	}
+	if anterior.RevokedAt != nil {
+		return nil, apperrors.NewUnauthorized("La sesión fue cerrada")
+	}
+	if anterior.UsedAt != nil {
+		return nil, s.reutilizado(ctx, anterior)
 	}
 
-	refreshToken, err = s.jwt.GenerateRefreshToken(user.ID.String())
+	user, err := s.userRepo.GetByID(ctx, anterior.UsuarioID)
 	if err != nil {
-		return "", "", apperrors.NewInternal("Error al generar refresh token")
+		return nil, apperrors.NewUnauthorized("Usuario no encontrado")
 	}
 
-	return token, refreshToken, nil
-}
+	if !user.Activo {
+		return nil, apperrors.NewUnauthorized("Usuario desactivado")
+	}
 
-func (s *Service) RefreshToken(ctx context.Context, refreshToken string) (newToken, newRefresh string, err error) {
-	userIDStr, err := s.jwt.ValidateRefreshToken(refreshToken)
+	claims, err := s.claimsAcceso(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	token, err := s.jwt.GenerateToken(*claims)
 	if err != nil {
-		return "", "", apperrors.NewUnauthorized("Refresh token inválido")
+		return nil, apperrors.NewInternal("Error al generar token")
 	}
 
-	userID, err := uuid.Parse(userIDStr)
+	nuevo, newRefresh, err := s.nuevoRefreshToken(user.ID, anterior.FamiliaID, disp)
 	if err != nil {
-		return "", "", apperrors.NewUnauthorized("Token inválido")
+		return nil, err
 	}
+	rotado, err := s.refreshRepo.Rotar(ctx, anterior.ID, nuevo)
+	if err != nil {
+		return nil, apperrors.NewInternal("Error al renovar la sesión")
+	}
+	if !rotado {
+		// Otro refresh con el mismo token ganó la carrera.
+		return nil, s.reutilizado(ctx, anterior)
+	}
+
+	return &ResultadoLogin{
+		Token:               token,
+		RefreshToken:        newRefresh,
+		DebeCambiarPassword: claims.CambioPassword,
+		DebeInscribir2FA:    claims.Inscribir2FA,
+	}, nil
+}
 
-	user, err := s.userRepo.GetByID(ctx, userID)
+// Logout revoca la sesión (familia) a la que pertenece el refresh token.
+func (s *Service) Logout(ctx context.Context, refreshToken string) error {
+	rt, err := s.refreshRegistrado(ctx, refreshToken)
 	if err != nil {
-		return "", "", apperrors.NewUnauthorized("Usuario no encontrado")
+		return err
+	}
+	if err := s.refreshRepo.RevocarFamilia(ctx, rt.FamiliaID, domain.TokenLogout); err != nil {
+		return apperrors.NewInternal("Error al cerrar la sesión")
 	}
+	return nil
+}
 
-	if !user.Activo {
-		return "", "", apperrors.NewUnauthorized("Usuario desactivado")
+// ClavesPublicas devuelve las claves con que otros servicios pueden verificar
+// los access tokens.
+func (s *Service) ClavesPublicas() []JWK {
+	return s.jwt.PublicKeys()
+}
+
+// LogoutAll revoca todos los refresh tokens del usuario, en todos sus
+// dispositivos, e invalida sus access tokens.
+func (s *Service) LogoutAll(ctx context.Context, userID uuid.UUID) error {
+	if err := s.refreshRepo.RevocarUsuario(ctx, userID, domain.TokenLogoutTodos); err != nil {
+		return apperrors.NewInternal("Error al cerrar las sesiones")
+	}
+	if err := s.userRepo.IncrementTokenVersion(ctx, userID); err != nil {
+		return apperrors.NewInternal("Error al cerrar las sesiones")
+	}
+	s.sesiones.Invalidar(userID)
+	return nil
+}
+
+// nuevaSesion emite un access token y un refresh token de una familia nueva.
+func (s *Service) nuevaSesion(ctx context.Context, user *domain.Usuario, disp Dispositivo) (*ResultadoLogin, error) {
+	claims, err := s.claimsAcceso(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	token, err := s.jwt.GenerateToken(*claims)
+	if err != nil {
+		return nil, apperrors.NewInternal("Error al generar token")
 	}
 
+	rt, refreshToken, err := s.nuevoRefreshToken(user.ID, uuid.New(), disp)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.refreshRepo.Create(ctx, rt); err != nil {
+		return nil, apperrors.NewInternal("Error al registrar la sesión")
+	}
+
+	return &ResultadoLogin{
+		Token:               token,
+		RefreshToken:        refreshToken,
+		DebeCambiarPassword: claims.CambioPassword,
+		DebeInscribir2FA:    claims.Inscribir2FA,
+	}, nil
+}
+
+// claimsAcceso arma los claims del access token del usuario, incluidas las
+// acciones pendientes que restringen su uso.
+func (s *Service) claimsAcceso(ctx context.Context, user *domain.Usuario) (*Claims, error) {
 	rol, err := s.rolRepo.GetByID(ctx, user.RolID)
 	if err != nil {
-		return "", "", apperrors.NewInternal("Error al obtener rol")
+		return nil, apperrors.NewInternal("Error al obtener rol")
+	}
+
+	claims := &Claims{
+		UserID:         user.ID.String(),
+		RolNombre:      rol.Nombre,
+		TokenVersion:   user.TokenVersion,
+		CambioPassword: user.DebeCambiarPassword,
+	}
+	if s.rolRequiere2FA(rol.Nombre) {
+		habilitado, err := s.totpHabilitado(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		claims.Inscribir2FA = !habilitado
+	}
+	return claims, nil
+}
+
+// refreshRegistrado valida la firma del refresh token y devuelve su registro,
+// comprobando que el hash coincida con el guardado.
+func (s *Service) refreshRegistrado(ctx context.Context, refreshToken string) (*domain.RefreshToken, error) {
+	invalido := apperrors.NewUnauthorized("Refresh token inválido")
+	claims, err := s.jwt.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		return nil, invalido
+	}
+	tokenID, err := uuid.Parse(claims.TokenID)
+	if err != nil {
+		return nil, invalido
 	}
 
-	newToken, err = s.jwt.GenerateToken(user.ID.String(), rol.Nombre)
+	rt, err := s.refreshRepo.GetByID(ctx, tokenID)
 	if err != nil {
-		return "", "", apperrors.NewInternal("Error al generar token")
+		return nil, invalido
 	}
+	if subtle.ConstantTimeCompare([]byte(rt.TokenHash), []byte(hashToken(refreshToken))) != 1 ||
+		rt.UsuarioID.String() != claims.UserID {
+		return nil, invalido
+	}
+	return rt, nil
+}
 
-	newRefresh, err = s.jwt.GenerateRefreshToken(user.ID.String())
+func (s *Service) reutilizado(ctx context.Context, rt *domain.RefreshToken) error {
+	if err := s.refreshRepo.RevocarFamilia(ctx, rt.FamiliaID, domain.TokenReutilizado); err != nil {
+		return apperrors.NewInternal("Error al revocar la sesión")
+	}
+	return apperrors.NewUnauthorized("Refresh token reutilizado: la sesión fue cerrada por seguridad")
+}
+
+func (s *Service) nuevoRefreshToken(userID, familiaID uuid.UUID, disp Dispositivo) (*domain.RefreshToken, string, error) {
+	rt := &domain.RefreshToken{
+		ID:        uuid.New(),
+		UsuarioID: userID,
+		FamiliaID: familiaID,
+		UserAgent: truncar(disp.UserAgent, 255),
+		IP:        truncar(disp.IP, 45),
+	}
+	token, exp, err := s.jwt.GenerateRefreshToken(userID.String(), rt.ID.String())
 	if err != nil {
-		return "", "", apperrors.NewInternal("Error al generar refresh token")
+		return nil, "", apperrors.NewInternal("Error al generar refresh token")
 	}
+	rt.TokenHash = hashToken(token)
+	rt.ExpiresAt = exp
+	return rt, token, nil
+}
+
+// hashToken es el SHA-256 del token: solo el cliente conoce el token en claro.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
 
-	return newToken, newRefresh, nil
+func truncar(s string, n int) string {
+	r := []rune(s)
+	if len(r) > n {
+		return string(r[:n])
+	}
+	return s
 }
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+)
+
+// Sesiones comprueba que la versión de tokens de un access token siga vigente
+// y que el usuario esté activo. Guarda el resultado en memoria durante ttl
+// para no consultar la base en cada request; Invalidar descarta la entrada
+// en el momento, de modo que en esta instancia el cambio se aplica de
+// inmediato y en otras instancias tarda como máximo ttl.
+type Sesiones struct {
+	repo domain.UsuarioRepository
+	ttl  time.Duration
+
+	mu       sync.Mutex
+	entradas map[uuid.UUID]entradaSesion
+	// generacion cuenta las invalidaciones, para no guardar una versión leída
+	// antes de una invalidación concurrente.
+	generacion uint64
+}
+
+type entradaSesion struct {
+	version int
+	activo  bool
+	vence   time.Time
+}
+
+func NewSesiones(repo domain.UsuarioRepository, ttl time.Duration) *Sesiones {
+	return &Sesiones{repo: repo, ttl: ttl, entradas: make(map[uuid.UUID]entradaSesion)}
+}
+
+// ValidarSesion devuelve error si el usuario no existe, está desactivado o
+// el token se emitió con una versión anterior a la vigente.
+func (s *Sesiones) ValidarSesion(ctx context.Context, userID string, version int) error {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("usuario inválido")
+	}
+
+	s.mu.Lock()
+	e, ok := s.entradas[id]
+	generacion := s.generacion
+	s.mu.Unlock()
+
+	if !ok || time.Now().After(e.vence) {
+		e.version, e.activo, err = s.repo.GetTokenVersion(ctx, id)
+		if err != nil {
+			return fmt.Errorf("usuario no encontrado")
+		}
+		e.vence = time.Now().Add(s.ttl)
+		s.mu.Lock()
+		if s.generacion == generacion {
+			s.entradas[id] = e
+		}
+		s.mu.Unlock()
+	}
+
+	if !e.activo {
+		return fmt.Errorf("usuario desactivado")
+	}
+	if version != e.version {
+		return fmt.Errorf("token revocado")
+	}
+	return nil
+}
+
+// Invalidar descarta la versión guardada del usuario; la próxima validación
+// la vuelve a leer de la base.
+func (s *Sesiones) Invalidar(userID uuid.UUID) {
+	s.mu.Lock()
+	delete(s.entradas, userID)
+	s.generacion++
+	s.mu.Unlock()
+}
@@ -2,6 +2,7 @@ package cita
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,14 +10,47 @@ import (
 	apperrors "github.com/tunek/centro-caribel/pkg/errors"
 )
 
+// RegistradorNotas arma notas de evolución para guardarlas junto con la cita;
+// lo implementa historia.Service.
+type RegistradorNotas interface {
+	PrepararNota(ctx context.Context, pacienteID uuid.UUID, tipo, contenido string, citaID, paqueteID, plantillaID *uuid.UUID,
+		datos map[string]interface{}, firmar bool, createdBy uuid.UUID) (*domain.NotaEvolucion, error)
+	GuardarNota(ctx context.Context, nota *domain.NotaEvolucion) error
+	RecargarNota(ctx context.Context, n *domain.NotaEvolucion) *domain.NotaEvolucion
+}
+
+// VerificadorConsentimiento informa la vigencia del consentimiento de un
+// paciente para un tratamiento; lo implementa consentimiento.Service.
+type VerificadorConsentimiento interface {
+	EstadoPorTratamiento(ctx context.Context, pacienteID uuid.UUID, tratamiento string, fecha time.Time) (*domain.EstadoConsentimientoTratamiento, error)
+}
+
+// NotaAtencion es la nota que se registra junto con el paso a ATENDIDA.
+type NotaAtencion struct {
+	Tipo        string
+	Contenido   string
+	PlantillaID *uuid.UUID
+	Datos       map[string]interface{}
+	Firmar      bool
+}
+
 type Service struct {
 	repo         domain.CitaRepository
 	pacienteRepo domain.PacienteRepository
 	paqueteRepo  domain.PaqueteRepository
+	notas        RegistradorNotas
+	tx           domain.Transactor
+
+	consentimientos VerificadorConsentimiento
+	// bloquearSinConsentimiento rechaza las citas de tratamientos que exigen un
+	// consentimiento vigente que el paciente no tiene; si es false, solo se advierte.
+	bloquearSinConsentimiento bool
 }
 
-func NewService(repo domain.CitaRepository, pacienteRepo domain.PacienteRepository, paqueteRepo domain.PaqueteRepository) *Service {
-	return &Service{repo: repo, pacienteRepo: pacienteRepo, paqueteRepo: paqueteRepo}
+func NewService(repo domain.CitaRepository, pacienteRepo domain.PacienteRepository, paqueteRepo domain.PaqueteRepository, notas RegistradorNotas,
+	tx domain.Transactor, consentimientos VerificadorConsentimiento, bloquearSinConsentimiento bool) *Service {
+	return &Service{repo: repo, pacienteRepo: pacienteRepo, paqueteRepo: paqueteRepo, notas: notas, tx: tx,
+		consentimientos: consentimientos, bloquearSinConsentimiento: bloquearSinConsentimiento}
 }
 
 func validarHorarioAtencion(fecha time.Time, hora string) error {
@@ -48,9 +82,13 @@ func validarHorarioAtencion(fecha time.Time, hora string) error {
 }
 
 func (s *Service) Create(ctx context.Context, pacienteID uuid.UUID, fecha, hora, tipoTratamiento string, turno domain.TurnoCita, observaciones string, paqueteID *uuid.UUID, createdBy uuid.UUID) (*domain.Cita, error) {
-	if _, err := s.pacienteRepo.GetByID(ctx, pacienteID); err != nil {
+	pac, err := s.pacienteRepo.GetByID(ctx, pacienteID)
+	if err != nil {
 		return nil, apperrors.NewNotFound("Paciente")
 	}
+	if pac.Archivado() {
+		return nil, apperrors.NewBadRequest("No se pueden agendar citas para un paciente archivado")
+	}
 
 	fechaParsed, err := time.Parse("2006-01-02", fecha)
 	if err != nil {
@@ -86,6 +124,13 @@ func (s *Service) Create(ctx context.Context, pacienteID uuid.UUID, fecha, hora,
 		}
 	}
 
+	horaCita, _ := time.Parse("15:04", hora)
+	inicio := fechaParsed.Add(time.Duration(horaCita.Hour())*time.Hour + time.Duration(horaCita.Minute())*time.Minute)
+	advertencia, err := s.verificarConsentimiento(ctx, pacienteID, tipoTratamiento, inicio)
+	if err != nil {
+		return nil, err
+	}
+
 	c := &domain.Cita{
 		ID:              uuid.New(),
 		PacienteID:      pacienteID,
@@ -102,10 +147,40 @@ func (s *Service) Create(ctx context.Context, pacienteID uuid.UUID, fecha, hora,
 	if err := s.repo.Create(ctx, c); err != nil {
 		return nil, apperrors.NewInternal("Error al crear la cita")
 	}
+	if advertencia != "" {
+		c.Advertencias = append(c.Advertencias, advertencia)
+	}
 
 	return c, nil
 }
 
+// verificarConsentimiento comprueba que el paciente tenga, al inicio de la cita,
+// un consentimiento vigente si el tratamiento lo exige. Según la política
+// configurada devuelve un error Conflict o el texto de la advertencia.
+func (s *Service) verificarConsentimiento(ctx context.Context, pacienteID uuid.UUID, tipoTratamiento string, fecha time.Time) (string, error) {
+	estado, err := s.consentimientos.EstadoPorTratamiento(ctx, pacienteID, tipoTratamiento, fecha)
+	if err != nil {
+		return "", err
+	}
+	if !estado.Requerido || estado.Estado == domain.ConsentimientoVigente {
+		return "", nil
+	}
+
+	var msg string
+	switch estado.Estado {
+	case domain.ConsentimientoVencido:
+		msg = fmt.Sprintf("El consentimiento del paciente para %s estará vencido en la fecha de la cita", estado.Tratamiento)
+	case domain.ConsentimientoRevocado:
+		msg = fmt.Sprintf("El paciente revocó su consentimiento para %s", estado.Tratamiento)
+	default:
+		msg = fmt.Sprintf("El paciente no tiene consentimiento firmado para %s", estado.Tratamiento)
+	}
+	if s.bloquearSinConsentimiento {
+		return "", apperrors.NewConflict(msg)
+	}
+	return msg, nil
+}
+
 func (s *Service) GetAll(ctx context.Context, page, perPage int, fecha *time.Time, turno *domain.TurnoCita, estado *domain.EstadoCita) ([]domain.Cita, int64, error) {
 	if page < 1 {
 		page = 1
@@ -125,33 +200,77 @@ func (s *Service) GetByID(ctx context.Context, id uuid.UUID) (*domain.Cita, erro
 	return c, nil
 }
 
-func (s *Service) UpdateEstado(ctx context.Context, id uuid.UUID, nuevoEstado domain.EstadoCita) error {
+// UpdateEstado cambia el estado de la cita. Al marcarla ATENDIDA se puede
+// registrar en la misma operación la nota de evolución de la sesión. El cambio
+// de estado, la nota y la sesión sumada al paquete se guardan en una
+// transacción, así que o quedan todos o ninguno.
+func (s *Service) UpdateEstado(ctx context.Context, id uuid.UUID, nuevoEstado domain.EstadoCita, nota *NotaAtencion, userID uuid.UUID) (*domain.NotaEvolucion, error) {
 	c, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		return apperrors.NewNotFound("Cita")
+		return nil, apperrors.NewNotFound("Cita")
 	}
 
 	if !domain.PuedeTransicionar(c.Estado, nuevoEstado) {
-		return apperrors.NewBadRequest("Transición de estado no permitida: " + string(c.Estado) + " -> " + string(nuevoEstado))
+		return nil, apperrors.NewBadRequest("Transición de estado no permitida: " + string(c.Estado) + " -> " + string(nuevoEstado))
 	}
 
-	if err := s.repo.UpdateEstado(ctx, id, nuevoEstado); err != nil {
-		return apperrors.NewInternal("Error actualizando estado")
+	var creada *domain.NotaEvolucion
+	if nota != nil {
+		if nuevoEstado != domain.EstadoAtendida {
+			return nil, apperrors.NewBadRequest("Solo se puede registrar una nota al marcar la cita como ATENDIDA")
+		}
+		creada, err = s.notas.PrepararNota(ctx, c.PacienteID, nota.Tipo, nota.Contenido, &c.ID, c.PaqueteID, nota.PlantillaID, nota.Datos, nota.Firmar, userID)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Al marcar como ATENDIDA, incrementar sesiones del paquete si existe
-	if nuevoEstado == domain.EstadoAtendida && c.PaqueteID != nil {
-		_ = s.paqueteRepo.IncrementSesiones(ctx, *c.PaqueteID)
-		paq, err := s.paqueteRepo.GetByID(ctx, *c.PaqueteID)
-		if err == nil && paq.SesionesCompletadas >= paq.TotalSesiones {
-			_ = s.paqueteRepo.UpdateEstado(ctx, paq.ID, domain.PaqueteCompletado)
+	err = s.tx.EnTransaccion(ctx, func(ctx context.Context) error {
+		if err := s.repo.UpdateEstado(ctx, id, nuevoEstado, userID); err != nil {
+			return apperrors.NewInternal("Error actualizando estado")
+		}
+		if creada != nil {
+			if err := s.notas.GuardarNota(ctx, creada); err != nil {
+				return err
+			}
+		}
+		if nuevoEstado == domain.EstadoAtendida && c.PaqueteID != nil {
+			return s.sumarSesion(ctx, *c.PaqueteID)
+		}
+		return nil
+	})
+	if err != nil {
+		if _, ok := err.(*apperrors.AppError); ok {
+			return nil, err
 		}
+		return nil, apperrors.NewInternal("Error actualizando estado")
 	}
 
+	if creada != nil {
+		creada = s.notas.RecargarNota(ctx, creada)
+	}
+	return creada, nil
+}
+
+// sumarSesion cuenta la sesión atendida en el paquete y lo marca COMPLETADO
+// cuando llega al total.
+func (s *Service) sumarSesion(ctx context.Context, paqueteID uuid.UUID) error {
+	if err := s.paqueteRepo.IncrementSesiones(ctx, paqueteID); err != nil {
+		return apperrors.NewInternal("Error al registrar la sesión del paquete")
+	}
+	paq, err := s.paqueteRepo.GetByID(ctx, paqueteID)
+	if err != nil {
+		return apperrors.NewInternal("Error al registrar la sesión del paquete")
+	}
+	if paq.SesionesCompletadas >= paq.TotalSesiones && paq.Estado != domain.PaqueteCompletado {
+		if err := s.paqueteRepo.UpdateEstado(ctx, paq.ID, domain.PaqueteCompletado); err != nil {
+			return apperrors.NewInternal("Error al completar el paquete")
+		}
+	}
 	return nil
 }
 
-func (s *Service) Reagendar(ctx context.Context, id uuid.UUID, fecha, hora string, turno domain.TurnoCita) error {
+func (s *Service) Reagendar(ctx context.Context, id uuid.UUID, fecha, hora string, turno domain.TurnoCita, userID uuid.UUID) error {
 	c, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return apperrors.NewNotFound("Cita")
@@ -182,5 +301,5 @@ func (s *Service) Reagendar(ctx context.Context, id uuid.UUID, fecha, hora strin
 		return apperrors.NewConflict("Ya existe una cita agendada en esa fecha y hora")
 	}
 
-	return s.repo.Reagendar(ctx, id, fechaParsed, hora, turno)
+	return s.repo.Reagendar(ctx, id, fechaParsed, hora, turno, userID)
 }
@@ -0,0 +1,133 @@
+package consentimiento
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"image/png"
+	"io"
+	"strings"
+
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+)
+
+const (
+	// maxFirmaBytes limita el tamaño de la imagen de firma ya decodificada.
+	maxFirmaBytes = 256 << 10
+	// maxFirmaLado limita el ancho y alto en píxeles de una firma PNG.
+	maxFirmaLado = 4000
+
+	firmaPNG = "image/png"
+	firmaSVG = "image/svg+xml"
+)
+
+var pngMagic = []byte("\x89PNG\r\n\x1a\n")
+
+// elementosSVGProhibidos pueden ejecutar código o cargar contenido externo.
+var elementosSVGProhibidos = map[string]bool{
+	"script": true, "foreignobject": true, "iframe": true, "object": true, "embed": true,
+}
+
+// decodificarFirma acepta base64 puro o un data URL y devuelve la imagen y su
+// tipo. Solo se admiten PNG válidos y SVG sin scripts ni referencias externas.
+func decodificarFirma(valor string) ([]byte, string, error) {
+	valor = strings.TrimSpace(valor)
+	if valor == "" {
+		return nil, "", apperrors.NewBadRequest("La firma digital es requerida")
+	}
+	if strings.HasPrefix(valor, "data:") {
+		_, datos, ok := strings.Cut(valor, ",")
+		if !ok {
+			return nil, "", apperrors.NewBadRequest("La firma digital no es un data URL válido")
+		}
+		valor = datos
+	}
+
+	if base64.StdEncoding.DecodedLen(len(valor)) > maxFirmaBytes+3 {
+		return nil, "", apperrors.NewBadRequest(fmt.Sprintf("La firma digital excede el tamaño máximo de %d KB", maxFirmaBytes>>10))
+	}
+	firma, err := base64.StdEncoding.DecodeString(valor)
+	if err != nil {
+		return nil, "", apperrors.NewBadRequest("La firma digital no es un base64 válido")
+	}
+	if len(firma) == 0 || len(firma) > maxFirmaBytes {
+		return nil, "", apperrors.NewBadRequest(fmt.Sprintf("La firma digital debe tener entre 1 byte y %d KB", maxFirmaBytes>>10))
+	}
+
+	if bytes.HasPrefix(firma, pngMagic) {
+		if err := validarPNG(firma); err != nil {
+			return nil, "", err
+		}
+		return firma, firmaPNG, nil
+	}
+	if err := validarSVG(firma); err != nil {
+		return nil, "", err
+	}
+	return firma, firmaSVG, nil
+}
+
+// validarPNG lee primero la cabecera: png.Decode reserva el mapa de bits
+// completo, y una cabecera con dimensiones enormes en pocos bytes haría
+// reservar gigabytes.
+func validarPNG(firma []byte) error {
+	invalida := apperrors.NewBadRequest("La firma digital no es un PNG válido")
+	cfg, err := png.DecodeConfig(bytes.NewReader(firma))
+	if err != nil {
+		return invalida
+	}
+	if cfg.Width == 0 || cfg.Height == 0 || cfg.Width > maxFirmaLado || cfg.Height > maxFirmaLado {
+		return apperrors.NewBadRequest(fmt.Sprintf("La firma PNG debe medir como máximo %dx%d píxeles", maxFirmaLado, maxFirmaLado))
+	}
+	if _, err := png.Decode(bytes.NewReader(firma)); err != nil {
+		return invalida
+	}
+	return nil
+}
+
+func validarSVG(firma []byte) error {
+	invalida := apperrors.NewBadRequest("La firma digital debe ser una imagen PNG o SVG")
+	dec := xml.NewDecoder(bytes.NewReader(firma))
+	dec.Strict = true
+
+	raiz := true
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return invalida
+		}
+		switch t := tok.(type) {
+		case xml.Directive:
+			// DOCTYPE y ENTITY permiten expansión de entidades y referencias externas.
+			return apperrors.NewBadRequest("La firma SVG no puede contener declaraciones DOCTYPE")
+		case xml.StartElement:
+			nombre := strings.ToLower(t.Name.Local)
+			if raiz {
+				if nombre != "svg" {
+					return invalida
+				}
+				raiz = false
+			}
+			if elementosSVGProhibidos[nombre] {
+				return apperrors.NewBadRequest("La firma SVG contiene elementos no permitidos")
+			}
+			for _, a := range t.Attr {
+				attr := strings.ToLower(a.Name.Local)
+				valor := strings.ToLower(strings.TrimSpace(a.Value))
+				if strings.HasPrefix(attr, "on") {
+					return apperrors.NewBadRequest("La firma SVG contiene atributos no permitidos")
+				}
+				if attr == "href" && !strings.HasPrefix(valor, "#") {
+					return apperrors.NewBadRequest("La firma SVG no puede referenciar recursos externos")
+				}
+			}
+		}
+	}
+	if raiz {
+		return invalida
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package consentimiento
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+)
+
+func (s *Service) GetPlantillas(ctx context.Context, soloActivas bool) ([]domain.PlantillaConsentimiento, error) {
+	plantillas, err := s.plantillaRepo.GetAll(ctx, soloActivas)
+	if err != nil {
+		return nil, apperrors.NewInternal("Error al obtener plantillas de consentimiento")
+	}
+	if plantillas == nil {
+		plantillas = []domain.PlantillaConsentimiento{}
+	}
+	return plantillas, nil
+}
+
+func (s *Service) GetPlantilla(ctx context.Context, id uuid.UUID) (*domain.PlantillaConsentimiento, error) {
+	p, err := s.plantillaRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, apperrors.NewNotFound("Plantilla de consentimiento")
+	}
+	return p, nil
+}
+
+func (s *Service) GetVersionesPlantilla(ctx context.Context, id uuid.UUID) ([]domain.VersionPlantillaConsentimiento, error) {
+	if _, err := s.GetPlantilla(ctx, id); err != nil {
+		return nil, err
+	}
+	versiones, err := s.plantillaRepo.GetVersiones(ctx, id)
+	if err != nil {
+		return nil, apperrors.NewInternal("Error al obtener versiones de la plantilla")
+	}
+	return versiones, nil
+}
+
+func (s *Service) CreatePlantilla(ctx context.Context, nombre, tipoTratamiento, texto string, vigenciaDias *int, requerido bool, createdBy uuid.UUID) (*domain.PlantillaConsentimiento, error) {
+	p := &domain.PlantillaConsentimiento{
+		ID:              uuid.New(),
+		Nombre:          strings.TrimSpace(nombre),
+		TipoTratamiento: strings.TrimSpace(tipoTratamiento),
+		Texto:           strings.TrimSpace(texto),
+		Activa:          true,
+		VigenciaDias:    vigenciaDias,
+		Requerido:       requerido,
+		CreatedBy:       &createdBy,
+	}
+	if err := s.validarPlantilla(ctx, p, nil); err != nil {
+		return nil, err
+	}
+
+	if err := s.plantillaRepo.Create(ctx, p); err != nil {
+		return nil, apperrors.NewInternal("Error al crear la plantilla de consentimiento")
+	}
+	return p, nil
+}
+
+// UpdatePlantilla modifica la plantilla. Un cambio de texto crea una nueva
+// versión; los consentimientos ya firmados siguen apuntando a la suya.
+func (s *Service) UpdatePlantilla(ctx context.Context, id uuid.UUID, nombre, tipoTratamiento, texto string, vigenciaDias *int, requerido, activa bool, autorID uuid.UUID) (*domain.PlantillaConsentimiento, error) {
+	p, err := s.GetPlantilla(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Nombre = strings.TrimSpace(nombre)
+	p.TipoTratamiento = strings.TrimSpace(tipoTratamiento)
+	p.Texto = strings.TrimSpace(texto)
+	p.VigenciaDias = vigenciaDias
+	p.Requerido = requerido
+	p.Activa = activa
+	if err := s.validarPlantilla(ctx, p, &id); err != nil {
+		return nil, err
+	}
+
+	if err := s.plantillaRepo.Update(ctx, p, autorID); err != nil {
+		return nil, apperrors.NewInternal("Error al actualizar la plantilla de consentimiento")
+	}
+	return p, nil
+}
+
+func (s *Service) validarPlantilla(ctx context.Context, p *domain.PlantillaConsentimiento, excludeID *uuid.UUID) error {
+	if p.Nombre == "" {
+		return apperrors.NewBadRequest("El campo nombre es requerido")
+	}
+	if p.VigenciaDias != nil && *p.VigenciaDias <= 0 {
+		return apperrors.NewBadRequest("vigencia_dias debe ser mayor a 0")
+	}
+	if p.Requerido && p.TipoTratamiento == "" {
+		return apperrors.NewBadRequest("Una plantilla requerida debe indicar tipo_tratamiento")
+	}
+	if err := domain.ValidarTextoConsentimiento(p.Texto); err != nil {
+		return apperrors.NewBadRequest("Plantilla inválida: " + err.Error())
+	}
+
+	exists, err := s.plantillaRepo.ExistsByNombre(ctx, p.Nombre, excludeID)
+	if err != nil {
+		return apperrors.NewInternal("Error verificando la plantilla")
+	}
+	if exists {
+		return apperrors.NewConflict("Ya existe una plantilla de consentimiento con ese nombre")
+	}
+	return nil
+}
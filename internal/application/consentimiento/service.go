@@ -2,7 +2,9 @@ package consentimiento
 
 import (
 	"context"
-	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/tunek/centro-caribel/internal/domain"
@@ -10,35 +12,75 @@ import (
 )
 
 type Service struct {
-	repo        domain.ConsentimientoRepository
-	pacienteRepo domain.PacienteRepository
+	repo          domain.ConsentimientoRepository
+	pacienteRepo  domain.PacienteRepository
+	tutorRepo     domain.TutorRepository
+	plantillaRepo domain.PlantillaConsentimientoRepository
+	claveSello    []byte
+	// zona es la zona horaria de la clínica, con la que se escribe {{fecha}}.
+	zona *time.Location
 }
 
-func NewService(repo domain.ConsentimientoRepository, pacienteRepo domain.PacienteRepository) *Service {
-	return &Service{repo: repo, pacienteRepo: pacienteRepo}
+func NewService(repo domain.ConsentimientoRepository, pacienteRepo domain.PacienteRepository, tutorRepo domain.TutorRepository,
+	plantillaRepo domain.PlantillaConsentimientoRepository, claveSello []byte, zona *time.Location) *Service {
+	return &Service{repo: repo, pacienteRepo: pacienteRepo, tutorRepo: tutorRepo, plantillaRepo: plantillaRepo, claveSello: claveSello, zona: zona}
 }
 
-func (s *Service) Create(ctx context.Context, pacienteID uuid.UUID, firmaB64, contenido string, autorizaFotos bool, registradoPor uuid.UUID) (*domain.Consentimiento, error) {
-	if _, err := s.pacienteRepo.GetByID(ctx, pacienteID); err != nil {
-		return nil, apperrors.NewNotFound("Paciente")
+// Create registra un consentimiento a partir de la versión vigente de una
+// plantilla activa. El texto generado y su hash quedan guardados tal como se
+// firmaron. tratamiento reemplaza al de la plantilla si no está vacío.
+func (s *Service) Create(ctx context.Context, pacienteID uuid.UUID, firmaB64 string, plantillaID uuid.UUID, tratamiento string, autorizaFotos bool, tutorID *uuid.UUID, registradoPor uuid.UUID) (*domain.Consentimiento, error) {
+	plantilla, err := s.plantillaRepo.GetByID(ctx, plantillaID)
+	if err != nil {
+		return nil, apperrors.NewNotFound("Plantilla de consentimiento")
+	}
+	if !plantilla.Activa {
+		return nil, apperrors.NewBadRequest("La plantilla de consentimiento no está activa")
 	}
 
-	var firma []byte
-	if firmaB64 != "" {
-		var err error
-		firma, err = base64.StdEncoding.DecodeString(firmaB64)
-		if err != nil {
-			return nil, apperrors.NewBadRequest("La firma digital no es un base64 válido")
-		}
+	firma, firmaTipo, err := decodificarFirma(firmaB64)
+	if err != nil {
+		return nil, err
+	}
+
+	// La fecha se fija aquí, con la precisión de PostgreSQL, porque forma parte del sello.
+	fechaFirma := time.Now().UTC().Truncate(time.Microsecond)
+	tratamiento = tratamientoDe(plantilla, tratamiento)
+	contenido, tutor, err := s.renderizar(ctx, plantilla, pacienteID, tutorID, tratamiento, fechaFirma)
+	if err != nil {
+		return nil, err
+	}
+
+	var vencimiento *time.Time
+	if plantilla.VigenciaDias != nil {
+		v := fechaFirma.AddDate(0, 0, *plantilla.VigenciaDias)
+		vencimiento = &v
 	}
 
 	cons := &domain.Consentimiento{
 		ID:            uuid.New(),
 		PacienteID:    pacienteID,
+		FechaFirma:    fechaFirma,
 		FirmaDigital:  firma,
+		FirmaTipo:     firmaTipo,
 		AutorizaFotos: autorizaFotos,
 		Contenido:     contenido,
+		TutorID:       tutorID,
 		RegistradoPor: registradoPor,
+		Estado:        domain.ConsentimientoVigente,
+
+		Tratamiento:      tratamiento,
+		FechaVencimiento: vencimiento,
+
+		ContenidoSHA256:    domain.HashContenido(contenido),
+		PlantillaVersionID: &plantilla.VersionID,
+		PlantillaNombre:    plantilla.Nombre,
+		PlantillaVersion:   &plantilla.VersionActual,
+	}
+	cons.SelloVersion = domain.SelloVersionActual
+	cons.Sello = cons.CalcularSello(s.claveSello)
+	if tutor != nil {
+		cons.TutorNombre = tutor.NombreCompleto
 	}
 
 	if err := s.repo.Create(ctx, cons); err != nil {
@@ -52,5 +94,114 @@ func (s *Service) GetByPacienteID(ctx context.Context, pacienteID uuid.UUID) ([]
 	if _, err := s.pacienteRepo.GetByID(ctx, pacienteID); err != nil {
 		return nil, apperrors.NewNotFound("Paciente")
 	}
-	return s.repo.GetByPacienteID(ctx, pacienteID)
+	list, err := s.repo.GetByPacienteID(ctx, pacienteID)
+	if err != nil {
+		return nil, apperrors.NewInternal("Error al obtener consentimientos")
+	}
+	ahora := time.Now()
+	for i := range list {
+		list[i].Estado = list[i].EstadoEn(ahora)
+	}
+	return list, nil
+}
+
+// Previsualizar devuelve el texto que se firmaría con la versión vigente de
+// la plantilla, sin registrar nada.
+func (s *Service) Previsualizar(ctx context.Context, plantillaID, pacienteID uuid.UUID, tutorID *uuid.UUID, tratamiento string) (*domain.PlantillaConsentimiento, string, error) {
+	plantilla, err := s.plantillaRepo.GetByID(ctx, plantillaID)
+	if err != nil {
+		return nil, "", apperrors.NewNotFound("Plantilla de consentimiento")
+	}
+	contenido, _, err := s.renderizar(ctx, plantilla, pacienteID, tutorID, tratamiento, time.Now())
+	if err != nil {
+		return nil, "", err
+	}
+	return plantilla, contenido, nil
+}
+
+// renderizar valida paciente y tutor y completa los marcadores de la plantilla.
+func (s *Service) renderizar(ctx context.Context, plantilla *domain.PlantillaConsentimiento, pacienteID uuid.UUID, tutorID *uuid.UUID, tratamiento string, fecha time.Time) (string, *domain.Tutor, error) {
+	pac, err := s.pacienteRepo.GetByID(ctx, pacienteID)
+	if err != nil {
+		return "", nil, apperrors.NewNotFound("Paciente")
+	}
+
+	// Los consentimientos de menores de edad los firma un tutor registrado del paciente
+	var tutor *domain.Tutor
+	if tutorID != nil {
+		tutor, err = s.tutorRepo.GetByID(ctx, *tutorID)
+		if err != nil {
+			return "", nil, apperrors.NewNotFound("Tutor")
+		}
+		if tutor.PacienteID != pacienteID {
+			return "", nil, apperrors.NewBadRequest("El tutor no pertenece al paciente")
+		}
+	} else if pac.EsMenorDeEdad(fecha) {
+		return "", nil, apperrors.NewBadRequest("El paciente es menor de edad: debe indicar el tutor que firma el consentimiento")
+	}
+
+	valores := map[string]string{
+		"paciente_nombre": pac.NombreCompleto,
+		"paciente_ci":     pac.CI,
+		"paciente_codigo": pac.Codigo,
+		"tratamiento":     tratamientoDe(plantilla, tratamiento),
+		"fecha":           fecha.In(s.zona).Format("02/01/2006"),
+	}
+	if tutor != nil {
+		valores["tutor_nombre"] = tutor.NombreCompleto
+		valores["tutor_ci"] = tutor.CI
+		valores["tutor"] = fmt.Sprintf("Firma en representación del paciente: %s, CI %s (%s).",
+			tutor.NombreCompleto, tutor.CI, strings.ToLower(tutor.Parentesco))
+	}
+
+	return domain.RenderizarConsentimiento(plantilla.Texto, valores), tutor, nil
+}
+
+// tratamientoDe devuelve el tratamiento indicado o, si está vacío, el de la plantilla.
+func tratamientoDe(plantilla *domain.PlantillaConsentimiento, tratamiento string) string {
+	if t := strings.TrimSpace(tratamiento); t != "" {
+		return t
+	}
+	return plantilla.TipoTratamiento
+}
+
+// Verificacion es el resultado de recalcular el hash del texto y el sello de
+// un consentimiento.
+type Verificacion struct {
+	ConsentimientoID uuid.UUID `json:"consentimiento_id"`
+	Valido           bool      `json:"valido"`
+	ContenidoIntegro bool      `json:"contenido_integro"`
+	SelloValido      bool      `json:"sello_valido"`
+	// SelloVersion 1 no cubre el tratamiento ni el vencimiento.
+	SelloVersion int       `json:"sello_version,omitempty"`
+	Motivo       string    `json:"motivo,omitempty"`
+	VerificadoAt time.Time `json:"verificado_at"`
+}
+
+// Verificar recalcula el hash del texto firmado y el sello HMAC. Un
+// consentimiento es válido solo si ambos coinciden con lo registrado.
+func (s *Service) Verificar(ctx context.Context, id uuid.UUID) (*Verificacion, error) {
+	c, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, apperrors.NewNotFound("Consentimiento")
+	}
+
+	v := &Verificacion{
+		ConsentimientoID: c.ID,
+		ContenidoIntegro: domain.HashContenido(c.Contenido) == c.ContenidoSHA256,
+		SelloValido:      c.VerificarSello(s.claveSello),
+		SelloVersion:     c.SelloVersion,
+		VerificadoAt:     time.Now(),
+	}
+	v.Valido = v.ContenidoIntegro && v.SelloValido
+
+	switch {
+	case c.Sello == "":
+		v.Motivo = "El consentimiento se registró antes del sellado y no puede verificarse"
+	case !v.ContenidoIntegro:
+		v.Motivo = "El texto no coincide con el hash registrado al firmar"
+	case !v.SelloValido:
+		v.Motivo = "El sello no coincide: la firma, el paciente, el tutor, la fecha, el tratamiento o el vencimiento fueron alterados"
+	}
+	return v, nil
 }
@@ -0,0 +1,191 @@
+package consentimiento
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+)
+
+// Revocar registra que el paciente retiró el consentimiento, completo o solo
+// la autorización de fotografías. El consentimiento firmado no se modifica.
+// fecha es opcional (por defecto, ahora) y no puede ser futura.
+func (s *Service) Revocar(ctx context.Context, id uuid.UUID, alcance domain.AlcanceRevocacion, motivo, fecha string, registradoPor uuid.UUID) (*domain.Consentimiento, error) {
+	c, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, apperrors.NewNotFound("Consentimiento")
+	}
+
+	if !alcance.IsValid() {
+		return nil, apperrors.NewBadRequest("Alcance inválido. Use: TOTAL o FOTOS")
+	}
+	motivo = strings.TrimSpace(motivo)
+	if motivo == "" {
+		return nil, apperrors.NewBadRequest("El campo motivo es requerido")
+	}
+
+	ahora := time.Now()
+	fechaRevocacion := ahora
+	if fecha != "" {
+		if fechaRevocacion, err = parseFecha(fecha); err != nil {
+			return nil, err
+		}
+		if fechaRevocacion.After(ahora) {
+			return nil, apperrors.NewBadRequest("La fecha de revocación no puede ser futura")
+		}
+		if fechaRevocacion.Before(c.FechaFirma.Truncate(24 * time.Hour)) {
+			return nil, apperrors.NewBadRequest("La fecha de revocación no puede ser anterior a la firma")
+		}
+	}
+
+	for _, r := range c.Revocaciones {
+		if r.Alcance == domain.RevocacionTotal {
+			return nil, apperrors.NewConflict("El consentimiento ya fue revocado")
+		}
+		if r.Alcance == alcance {
+			return nil, apperrors.NewConflict("La autorización de fotografías ya fue revocada")
+		}
+	}
+	if alcance == domain.RevocacionFotos && !c.AutorizaFotos {
+		return nil, apperrors.NewBadRequest("El consentimiento no autoriza fotografías")
+	}
+
+	rev := &domain.RevocacionConsentimiento{
+		ID:               uuid.New(),
+		ConsentimientoID: c.ID,
+		Alcance:          alcance,
+		FechaRevocacion:  fechaRevocacion,
+		Motivo:           motivo,
+		RegistradoPor:    registradoPor,
+	}
+	if err := s.repo.Revocar(ctx, rev); err != nil {
+		return nil, apperrors.NewInternal("Error al registrar la revocación")
+	}
+
+	c.Revocaciones = append(c.Revocaciones, *rev)
+	c.Estado = c.EstadoEn(ahora)
+	return c, nil
+}
+
+// GetEstado resume la vigencia de los consentimientos del paciente por
+// tratamiento: los que exigen las plantillas requeridas y los que tienen algún
+// consentimiento firmado. Si tratamiento no está vacío, solo informa ese.
+func (s *Service) GetEstado(ctx context.Context, pacienteID uuid.UUID, tratamiento string) ([]domain.EstadoConsentimientoTratamiento, error) {
+	if _, err := s.pacienteRepo.GetByID(ctx, pacienteID); err != nil {
+		return nil, apperrors.NewNotFound("Paciente")
+	}
+	consentimientos, requeridos, err := s.cargarVigencia(ctx, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+
+	ahora := time.Now()
+	if tratamiento = strings.TrimSpace(tratamiento); tratamiento != "" {
+		return []domain.EstadoConsentimientoTratamiento{estadoTratamiento(consentimientos, requeridos, tratamiento, ahora)}, nil
+	}
+
+	// Se agrupa sin distinguir mayúsculas, conservando el primer nombre visto.
+	nombres := map[string]string{}
+	for clave, nombre := range requeridos {
+		nombres[clave] = nombre
+	}
+	for _, c := range consentimientos {
+		if clave := claveTratamiento(c.Tratamiento); clave != "" {
+			if _, ok := nombres[clave]; !ok {
+				nombres[clave] = c.Tratamiento
+			}
+		}
+	}
+
+	estados := make([]domain.EstadoConsentimientoTratamiento, 0, len(nombres))
+	for _, nombre := range nombres {
+		estados = append(estados, estadoTratamiento(consentimientos, requeridos, nombre, ahora))
+	}
+	sort.Slice(estados, func(i, j int) bool {
+		return claveTratamiento(estados[i].Tratamiento) < claveTratamiento(estados[j].Tratamiento)
+	})
+	return estados, nil
+}
+
+// EstadoPorTratamiento indica si el paciente tiene, en la fecha dada, un
+// consentimiento vigente para el tratamiento y si alguna plantilla activa lo
+// exige. No verifica que el paciente exista.
+func (s *Service) EstadoPorTratamiento(ctx context.Context, pacienteID uuid.UUID, tratamiento string, fecha time.Time) (*domain.EstadoConsentimientoTratamiento, error) {
+	consentimientos, requeridos, err := s.cargarVigencia(ctx, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+	estado := estadoTratamiento(consentimientos, requeridos, tratamiento, fecha)
+	return &estado, nil
+}
+
+// cargarVigencia devuelve los consentimientos del paciente (del más reciente
+// al más antiguo) y los tratamientos que exigen consentimiento, indexados por
+// claveTratamiento.
+func (s *Service) cargarVigencia(ctx context.Context, pacienteID uuid.UUID) ([]domain.Consentimiento, map[string]string, error) {
+	consentimientos, err := s.repo.GetByPacienteID(ctx, pacienteID)
+	if err != nil {
+		return nil, nil, apperrors.NewInternal("Error al obtener consentimientos")
+	}
+	plantillas, err := s.plantillaRepo.GetAll(ctx, true)
+	if err != nil {
+		return nil, nil, apperrors.NewInternal("Error al obtener plantillas de consentimiento")
+	}
+
+	requeridos := map[string]string{}
+	for _, p := range plantillas {
+		if p.Requerido {
+			requeridos[claveTratamiento(p.TipoTratamiento)] = p.TipoTratamiento
+		}
+	}
+	return consentimientos, requeridos, nil
+}
+
+// estadoTratamiento elige el consentimiento vigente más reciente del
+// tratamiento en ref. Si no hay ninguno, informa el estado del último firmado.
+func estadoTratamiento(consentimientos []domain.Consentimiento, requeridos map[string]string, tratamiento string, ref time.Time) domain.EstadoConsentimientoTratamiento {
+	clave := claveTratamiento(tratamiento)
+	_, requerido := requeridos[clave]
+	e := domain.EstadoConsentimientoTratamiento{
+		Tratamiento: strings.TrimSpace(tratamiento),
+		Requerido:   requerido,
+		Estado:      domain.SinConsentimiento,
+	}
+
+	for i := range consentimientos {
+		c := consentimientos[i]
+		if clave == "" || claveTratamiento(c.Tratamiento) != clave {
+			continue
+		}
+		c.Estado = c.EstadoEn(ref)
+		if c.Estado == domain.ConsentimientoVigente {
+			e.Estado = c.Estado
+			e.AutorizaFotos = c.AutorizaFotosEn(ref)
+			e.Consentimiento = &c
+			return e
+		}
+		if e.Consentimiento == nil {
+			e.Estado = c.Estado
+			e.Consentimiento = &c
+		}
+	}
+	return e
+}
+
+func claveTratamiento(t string) string {
+	return strings.ToLower(strings.TrimSpace(t))
+}
+
+func parseFecha(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, apperrors.NewBadRequest("Formato de fecha inválido. Use YYYY-MM-DD o RFC 3339")
+}
@@ -0,0 +1,268 @@
+package foto
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+)
+
+// tiposPermitidos son los formatos de imagen aceptados, detectados por contenido.
+var tiposPermitidos = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+type DatosFoto struct {
+	Zona        string
+	Momento     domain.MomentoFoto
+	Descripcion string
+	CitaID      *uuid.UUID
+	PaqueteID   *uuid.UUID
+	TomadaEn    string // RFC 3339 o YYYY-MM-DD; vacío usa la fecha actual
+}
+
+type Service struct {
+	repo               domain.FotoRepository
+	pacienteRepo       domain.PacienteRepository
+	consentimientoRepo domain.ConsentimientoRepository
+	citaRepo           domain.CitaRepository
+	paqueteRepo        domain.PaqueteRepository
+	storage            domain.FileStorage
+}
+
+func NewService(repo domain.FotoRepository, pacienteRepo domain.PacienteRepository, consentimientoRepo domain.ConsentimientoRepository,
+	citaRepo domain.CitaRepository, paqueteRepo domain.PaqueteRepository, storage domain.FileStorage) *Service {
+	return &Service{
+		repo:               repo,
+		pacienteRepo:       pacienteRepo,
+		consentimientoRepo: consentimientoRepo,
+		citaRepo:           citaRepo,
+		paqueteRepo:        paqueteRepo,
+		storage:            storage,
+	}
+}
+
+// Subir guarda una foto clínica. Decide el consentimiento vigente más reciente
+// del paciente (del tratamiento de la cita o el paquete, si se indica): si no
+// autoriza fotografías, la foto se rechaza aunque uno anterior lo hiciera. El
+// consentimiento usado queda registrado.
+func (s *Service) Subir(ctx context.Context, pacienteID uuid.UUID, archivo io.ReadSeeker, tamano int64, nombreArchivo string, datos DatosFoto, tomadaPor uuid.UUID) (*domain.Foto, error) {
+	pac, err := s.pacienteRepo.GetByID(ctx, pacienteID)
+	if err != nil {
+		return nil, apperrors.NewNotFound("Paciente")
+	}
+	if pac.Archivado() {
+		return nil, apperrors.NewBadRequest("No se pueden registrar fotos de un paciente archivado")
+	}
+
+	zona := strings.TrimSpace(datos.Zona)
+	if zona == "" {
+		return nil, apperrors.NewBadRequest("El campo zona es requerido")
+	}
+	if !datos.Momento.IsValid() {
+		return nil, apperrors.NewBadRequest("Momento inválido. Use: ANTES, DESPUES o SEGUIMIENTO")
+	}
+
+	tomadaEn := time.Now()
+	if datos.TomadaEn != "" {
+		if tomadaEn, err = parseFecha(datos.TomadaEn); err != nil {
+			return nil, err
+		}
+	}
+
+	paqueteID, tratamiento, err := s.validarVinculos(ctx, pacienteID, datos.CitaID, datos.PaqueteID)
+	if err != nil {
+		return nil, err
+	}
+
+	consentimientos, err := s.consentimientoRepo.GetByPacienteID(ctx, pacienteID)
+	if err != nil {
+		return nil, apperrors.NewInternal("Error al verificar el consentimiento del paciente")
+	}
+	ahora := time.Now()
+	consentimiento := domain.ConsentimientoFotos(consentimientos, tratamiento, ahora)
+	if consentimiento == nil {
+		return nil, apperrors.NewForbidden("El paciente no tiene un consentimiento que autorice fotografías")
+	}
+	if !consentimiento.AutorizaFotosEn(ahora) {
+		return nil, apperrors.NewForbidden(fmt.Sprintf("El consentimiento más reciente del paciente (%s) no autoriza fotografías",
+			strings.ToLower(string(consentimiento.EstadoEn(ahora)))))
+	}
+
+	contentType, err := detectarTipo(archivo)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &domain.Foto{
+		ID:               uuid.New(),
+		PacienteID:       pacienteID,
+		ConsentimientoID: consentimiento.ID,
+		CitaID:           datos.CitaID,
+		PaqueteID:        paqueteID,
+		Zona:             zona,
+		Momento:          datos.Momento,
+		Descripcion:      strings.TrimSpace(datos.Descripcion),
+		NombreArchivo:    path.Base(nombreArchivo),
+		ContentType:      contentType,
+		TamanoBytes:      tamano,
+		TomadaEn:         tomadaEn,
+		TomadaPor:        tomadaPor,
+	}
+	f.StorageKey = fmt.Sprintf("fotos/%s/%s%s", pacienteID, f.ID, tiposPermitidos[contentType])
+
+	if err := s.storage.Put(ctx, f.StorageKey, archivo, tamano, contentType); err != nil {
+		return nil, apperrors.NewInternal("Error al guardar la foto")
+	}
+	if err := s.repo.Create(ctx, f); err != nil {
+		_ = s.storage.Delete(ctx, f.StorageKey)
+		return nil, apperrors.NewInternal("Error al registrar la foto")
+	}
+
+	return f, nil
+}
+
+func (s *Service) GetByPaciente(ctx context.Context, pacienteID uuid.UUID, zona string, paqueteID *uuid.UUID) ([]domain.Foto, error) {
+	if _, err := s.pacienteRepo.GetByID(ctx, pacienteID); err != nil {
+		return nil, apperrors.NewNotFound("Paciente")
+	}
+
+	fotos, err := s.repo.GetByPacienteID(ctx, pacienteID, strings.TrimSpace(zona), paqueteID)
+	if err != nil {
+		return nil, apperrors.NewInternal("Error al obtener fotos")
+	}
+	if fotos == nil {
+		fotos = []domain.Foto{}
+	}
+	return fotos, nil
+}
+
+// Abrir devuelve la foto y su contenido. El llamador debe cerrar el lector.
+func (s *Service) Abrir(ctx context.Context, pacienteID, fotoID uuid.UUID) (*domain.Foto, io.ReadSeekCloser, error) {
+	f, err := s.repo.GetByID(ctx, fotoID)
+	if err != nil || f.PacienteID != pacienteID {
+		return nil, nil, apperrors.NewNotFound("Foto")
+	}
+
+	contenido, err := s.storage.Get(ctx, f.StorageKey)
+	if err != nil {
+		return nil, nil, apperrors.NewInternal("Error al leer la foto")
+	}
+	return f, contenido, nil
+}
+
+// Comparaciones arma un par antes/después por zona: la primera foto ANTES (o
+// la primera de la zona) frente a la última DESPUES (o la última de la zona).
+func (s *Service) Comparaciones(ctx context.Context, pacienteID uuid.UUID, paqueteID *uuid.UUID) ([]domain.ComparacionFotos, error) {
+	fotos, err := s.GetByPaciente(ctx, pacienteID, "", paqueteID)
+	if err != nil {
+		return nil, err
+	}
+
+	porZona := make(map[string][]domain.Foto)
+	var zonas []string
+	for _, f := range fotos {
+		clave := strings.ToLower(f.Zona)
+		if _, ok := porZona[clave]; !ok {
+			zonas = append(zonas, clave)
+		}
+		porZona[clave] = append(porZona[clave], f)
+	}
+	sort.Strings(zonas)
+
+	comparaciones := []domain.ComparacionFotos{}
+	for _, z := range zonas {
+		lista := porZona[z] // ordenadas por tomada_en
+		antes, despues := &lista[0], &lista[len(lista)-1]
+		for i := range lista {
+			if lista[i].Momento == domain.FotoAntes {
+				antes = &lista[i]
+				break
+			}
+		}
+		for i := len(lista) - 1; i >= 0; i-- {
+			if lista[i].Momento == domain.FotoDespues {
+				despues = &lista[i]
+				break
+			}
+		}
+		if antes.ID == despues.ID || despues.TomadaEn.Before(antes.TomadaEn) {
+			continue
+		}
+		comparaciones = append(comparaciones, domain.ComparacionFotos{
+			Zona:    antes.Zona,
+			Antes:   antes,
+			Despues: despues,
+		})
+	}
+	return comparaciones, nil
+}
+
+func (s *Service) validarVinculos(ctx context.Context, pacienteID uuid.UUID, citaID, paqueteID *uuid.UUID) (*uuid.UUID, string, error) {
+	tratamiento := ""
+	if citaID != nil {
+		c, err := s.citaRepo.GetByID(ctx, *citaID)
+		if err != nil {
+			return nil, "", apperrors.NewNotFound("Cita")
+		}
+		if c.PacienteID != pacienteID {
+			return nil, "", apperrors.NewBadRequest("La cita no pertenece al paciente")
+		}
+		if paqueteID == nil {
+			paqueteID = c.PaqueteID
+		}
+		tratamiento = c.TipoTratamiento
+	}
+	if paqueteID != nil {
+		p, err := s.paqueteRepo.GetByID(ctx, *paqueteID)
+		if err != nil {
+			return nil, "", apperrors.NewNotFound("Paquete de tratamiento")
+		}
+		if p.PacienteID != pacienteID {
+			return nil, "", apperrors.NewBadRequest("El paquete no pertenece al paciente")
+		}
+		if tratamiento == "" {
+			tratamiento = p.TipoTratamiento
+		}
+	}
+	return paqueteID, tratamiento, nil
+}
+
+// detectarTipo identifica el formato por los primeros bytes del archivo y
+// vuelve al inicio para la copia posterior.
+func detectarTipo(archivo io.ReadSeeker) (string, error) {
+	cabecera := make([]byte, 512)
+	n, err := io.ReadFull(archivo, cabecera)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", apperrors.NewBadRequest("No se pudo leer el archivo")
+	}
+	if _, err := archivo.Seek(0, io.SeekStart); err != nil {
+		return "", apperrors.NewInternal("Error al procesar el archivo")
+	}
+
+	contentType := http.DetectContentType(cabecera[:n])
+	if _, ok := tiposPermitidos[contentType]; !ok {
+		return "", apperrors.NewBadRequest("Formato de imagen no permitido. Use JPEG, PNG o WebP")
+	}
+	return contentType, nil
+}
+
+func parseFecha(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, apperrors.NewBadRequest("Formato de fecha inválido. Use YYYY-MM-DD o RFC 3339")
+}
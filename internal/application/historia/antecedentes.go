@@ -0,0 +1,601 @@
+package historia
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+)
+
+type MedicamentoInput struct {
+	Nombre      string
+	Dosis       string
+	Frecuencia  string
+	FechaInicio string // YYYY-MM-DD
+	FechaFin    string // YYYY-MM-DD, vacío si continúa
+	Indicacion  string
+}
+
+type CondicionInput struct {
+	Tipo          domain.TipoCondicion
+	Codigo        string
+	Descripcion   string
+	Parentesco    string
+	Observaciones string
+}
+
+// cargarAntecedentes completa las listas estructuradas de la historia.
+func (s *Service) cargarAntecedentes(ctx context.Context, h *domain.HistoriaClinica) error {
+	alergias, err := s.alergiaRepo.GetByHistoriaID(ctx, h.ID)
+	if err != nil {
+		return apperrors.NewInternal("Error al obtener alergias")
+	}
+	medicamentos, err := s.medicamentoRepo.GetByHistoriaID(ctx, h.ID)
+	if err != nil {
+		return apperrors.NewInternal("Error al obtener medicamentos")
+	}
+	condiciones, err := s.condicionRepo.GetByHistoriaID(ctx, h.ID)
+	if err != nil {
+		return apperrors.NewInternal("Error al obtener antecedentes")
+	}
+
+	h.ListaAlergias = alergias
+	h.ListaMedicamentos = medicamentos
+	h.Condiciones = condiciones
+	if h.ListaAlergias == nil {
+		h.ListaAlergias = []domain.Alergia{}
+	}
+	if h.ListaMedicamentos == nil {
+		h.ListaMedicamentos = []domain.Medicamento{}
+	}
+	if h.Condiciones == nil {
+		h.Condiciones = []domain.Condicion{}
+	}
+	return nil
+}
+
+// seccion identifica el campo de texto de la historia que resume una lista de
+// antecedentes estructurados.
+type seccion int
+
+const (
+	seccionAlergias seccion = iota
+	seccionMedicamentos
+	seccionPersonales
+	seccionFamiliares
+)
+
+func seccionCondicion(tipo domain.TipoCondicion) seccion {
+	if tipo == domain.CondicionFamiliar {
+		return seccionFamiliares
+	}
+	return seccionPersonales
+}
+
+// actualizarResumen regenera los campos de texto de la historia a partir de los
+// antecedentes estructurados. Un campo sin registros estructurados conserva el
+// texto libre que tenía, salvo que esté entre las secciones modificadas: si se
+// quitó el último registro, el texto anterior ya no vale. Solo se registra una
+// versión si el resumen cambió.
+func (s *Service) actualizarResumen(ctx context.Context, h *domain.HistoriaClinica, autorID uuid.UUID, modificadas ...seccion) error {
+	if err := s.cargarAntecedentes(ctx, h); err != nil {
+		return err
+	}
+	anterior := *h
+
+	var personales, familiares []domain.Condicion
+	for _, c := range h.Condiciones {
+		if c.Tipo == domain.CondicionFamiliar {
+			familiares = append(familiares, c)
+		} else {
+			personales = append(personales, c)
+		}
+	}
+
+	modificada := func(sec seccion) bool {
+		for _, m := range modificadas {
+			if m == sec {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(h.ListaAlergias) > 0 || modificada(seccionAlergias) {
+		h.Alergias = conTextoAnterior(h.Alergias, renderAlergias(h.ListaAlergias))
+	}
+	if len(h.ListaMedicamentos) > 0 || modificada(seccionMedicamentos) {
+		h.MedicamentosActuales = conTextoAnterior(h.MedicamentosActuales, renderMedicamentos(h.ListaMedicamentos))
+	}
+	if len(personales) > 0 || modificada(seccionPersonales) {
+		h.AntecedentesPersonales = conTextoAnterior(h.AntecedentesPersonales, renderCondiciones(personales, sinPersonales))
+	}
+	if len(familiares) > 0 || modificada(seccionFamiliares) {
+		h.AntecedentesFamiliares = conTextoAnterior(h.AntecedentesFamiliares, renderCondiciones(familiares, sinFamiliares))
+	}
+
+	if mismosAntecedentes(&anterior, h) {
+		return nil
+	}
+	if err := s.repo.UpdateAntecedentes(ctx, h, autorID); err != nil {
+		return apperrors.NewInternal("Error al actualizar el resumen de antecedentes")
+	}
+	return nil
+}
+
+const (
+	sinAlergias     = "Sin alergias registradas"
+	sinMedicamentos = "Sin medicamentos actuales"
+	sinPersonales   = "Sin antecedentes personales registrados"
+	sinFamiliares   = "Sin antecedentes familiares registrados"
+
+	// prefijoTextoAnterior encabeza el texto libre que tenía una sección antes
+	// de su primer registro estructurado. Se conserva delante del resumen,
+	// separado por una línea en blanco.
+	prefijoTextoAnterior = "Texto anterior:\n"
+)
+
+// campoSeccion devuelve el campo de texto de la historia de la sección y el
+// texto que indica que no tiene registros.
+func campoSeccion(h *domain.HistoriaClinica, sec seccion) (*string, string) {
+	switch sec {
+	case seccionAlergias:
+		return &h.Alergias, sinAlergias
+	case seccionMedicamentos:
+		return &h.MedicamentosActuales, sinMedicamentos
+	case seccionFamiliares:
+		return &h.AntecedentesFamiliares, sinFamiliares
+	default:
+		return &h.AntecedentesPersonales, sinPersonales
+	}
+}
+
+// conservarTextoLibre se llama antes de agregar un registro a sec: si la
+// sección todavía no tiene registros estructurados, su texto libre se marca
+// como texto anterior para que el resumen no lo reemplace.
+func (s *Service) conservarTextoLibre(ctx context.Context, h *domain.HistoriaClinica, sec seccion) error {
+	if err := s.cargarAntecedentes(ctx, h); err != nil {
+		return err
+	}
+	switch sec {
+	case seccionAlergias:
+		if len(h.ListaAlergias) > 0 {
+			return nil
+		}
+	case seccionMedicamentos:
+		if len(h.ListaMedicamentos) > 0 {
+			return nil
+		}
+	default:
+		tipo := domain.CondicionPersonal
+		if sec == seccionFamiliares {
+			tipo = domain.CondicionFamiliar
+		}
+		for _, c := range h.Condiciones {
+			if c.Tipo == tipo {
+				return nil
+			}
+		}
+	}
+
+	campo, vacio := campoSeccion(h, sec)
+	texto := strings.TrimSpace(*campo)
+	if texto == "" || texto == vacio || strings.HasPrefix(texto, prefijoTextoAnterior) {
+		return nil
+	}
+	*campo = prefijoTextoAnterior + texto + "\n\n"
+	return nil
+}
+
+// conTextoAnterior antepone a resumen el texto anterior que conserve actual.
+func conTextoAnterior(actual, resumen string) string {
+	if !strings.HasPrefix(actual, prefijoTextoAnterior) {
+		return resumen
+	}
+	i := strings.LastIndex(actual, "\n\n")
+	if i < len(prefijoTextoAnterior) {
+		return resumen
+	}
+	return actual[:i] + "\n\n" + resumen
+}
+
+// medicamentosVigentes vuelve a generar el resumen de medicamentos al leer la
+// historia: el guardado se generó en la última modificación y puede incluir
+// medicamentos cuya fecha de fin ya pasó.
+func medicamentosVigentes(h *domain.HistoriaClinica) {
+	if len(h.ListaMedicamentos) > 0 {
+		h.MedicamentosActuales = conTextoAnterior(h.MedicamentosActuales, renderMedicamentos(h.ListaMedicamentos))
+	}
+}
+
+func mismosAntecedentes(a, b *domain.HistoriaClinica) bool {
+	return a.AntecedentesPersonales == b.AntecedentesPersonales &&
+		a.AntecedentesFamiliares == b.AntecedentesFamiliares &&
+		a.Alergias == b.Alergias &&
+		a.MedicamentosActuales == b.MedicamentosActuales
+}
+
+func renderAlergias(alergias []domain.Alergia) string {
+	if len(alergias) == 0 {
+		return sinAlergias
+	}
+	lineas := make([]string, 0, len(alergias))
+	for _, a := range alergias {
+		l := fmt.Sprintf("- %s (%s)", a.Sustancia, a.Severidad)
+		if a.Reaccion != "" {
+			l += ": " + a.Reaccion
+		}
+		lineas = append(lineas, l)
+	}
+	return strings.Join(lineas, "\n")
+}
+
+// renderMedicamentos lista solo los medicamentos vigentes hoy.
+func renderMedicamentos(medicamentos []domain.Medicamento) string {
+	var lineas []string
+	for _, m := range medicamentos {
+		if !m.Activo {
+			continue
+		}
+		l := fmt.Sprintf("- %s %s, %s (desde %s)", m.Nombre, m.Dosis, m.Frecuencia, m.FechaInicio.Format("2006-01-02"))
+		if m.FechaFin != nil {
+			l = strings.TrimSuffix(l, ")") + " hasta " + m.FechaFin.Format("2006-01-02") + ")"
+		}
+		if m.Indicacion != "" {
+			l += ": " + m.Indicacion
+		}
+		lineas = append(lineas, l)
+	}
+	if len(lineas) == 0 {
+		return sinMedicamentos
+	}
+	return strings.Join(lineas, "\n")
+}
+
+func renderCondiciones(condiciones []domain.Condicion, vacio string) string {
+	if len(condiciones) == 0 {
+		return vacio
+	}
+	lineas := make([]string, 0, len(condiciones))
+	for _, c := range condiciones {
+		l := "- "
+		if c.Codigo != "" {
+			l += "[" + c.Codigo + "] "
+		}
+		l += c.Descripcion
+		if c.Parentesco != "" {
+			l += " (" + c.Parentesco + ")"
+		}
+		if c.Observaciones != "" {
+			l += ": " + c.Observaciones
+		}
+		lineas = append(lineas, l)
+	}
+	return strings.Join(lineas, "\n")
+}
+
+func (s *Service) historiaDePaciente(ctx context.Context, pacienteID uuid.UUID) (*domain.HistoriaClinica, error) {
+	historia, err := s.repo.GetByPacienteID(ctx, pacienteID)
+	if err != nil {
+		return nil, apperrors.NewNotFound("Historia clínica")
+	}
+	return historia, nil
+}
+
+// historiaEditable es historiaDePaciente para operaciones de escritura: una
+// historia cerrada o archivada no admite cambios hasta que se reabra.
+func (s *Service) historiaEditable(ctx context.Context, pacienteID uuid.UUID) (*domain.HistoriaClinica, error) {
+	historia, err := s.historiaDePaciente(ctx, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+	if !historia.Abierta() {
+		return nil, apperrors.NewConflict(fmt.Sprintf("La historia clínica está %s y no admite cambios", strings.ToLower(string(historia.Estado))))
+	}
+	return historia, nil
+}
+
+// Alergias
+
+func (s *Service) GetAlergias(ctx context.Context, pacienteID uuid.UUID) ([]domain.Alergia, error) {
+	historia, err := s.historiaDePaciente(ctx, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+	alergias, err := s.alergiaRepo.GetByHistoriaID(ctx, historia.ID)
+	if err != nil {
+		return nil, apperrors.NewInternal("Error al obtener alergias")
+	}
+	if alergias == nil {
+		alergias = []domain.Alergia{}
+	}
+	return alergias, nil
+}
+
+func (s *Service) CreateAlergia(ctx context.Context, pacienteID uuid.UUID, sustancia, reaccion string, severidad domain.SeveridadAlergia, createdBy uuid.UUID) (*domain.Alergia, error) {
+	historia, err := s.historiaEditable(ctx, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+	if !severidad.IsValid() {
+		return nil, apperrors.NewBadRequest("Severidad inválida. Use: LEVE, MODERADA o SEVERA")
+	}
+
+	a := &domain.Alergia{
+		ID:         uuid.New(),
+		HistoriaID: historia.ID,
+		Sustancia:  sustancia,
+		Reaccion:   reaccion,
+		Severidad:  severidad,
+		CreatedBy:  createdBy,
+	}
+	if err := s.conservarTextoLibre(ctx, historia, seccionAlergias); err != nil {
+		return nil, err
+	}
+	if err := s.alergiaRepo.Create(ctx, a); err != nil {
+		return nil, apperrors.NewInternal("Error al registrar la alergia")
+	}
+	if err := s.actualizarResumen(ctx, historia, createdBy, seccionAlergias); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *Service) UpdateAlergia(ctx context.Context, pacienteID, id uuid.UUID, sustancia, reaccion string, severidad domain.SeveridadAlergia, autorID uuid.UUID) (*domain.Alergia, error) {
+	historia, err := s.historiaEditable(ctx, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+	a, err := s.alergiaRepo.GetByID(ctx, id)
+	if err != nil || a.HistoriaID != historia.ID {
+		return nil, apperrors.NewNotFound("Alergia")
+	}
+	if !severidad.IsValid() {
+		return nil, apperrors.NewBadRequest("Severidad inválida. Use: LEVE, MODERADA o SEVERA")
+	}
+
+	a.Sustancia = sustancia
+	a.Reaccion = reaccion
+	a.Severidad = severidad
+	if err := s.alergiaRepo.Update(ctx, a); err != nil {
+		return nil, apperrors.NewInternal("Error al actualizar la alergia")
+	}
+	if err := s.actualizarResumen(ctx, historia, autorID, seccionAlergias); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *Service) DeleteAlergia(ctx context.Context, pacienteID, id, autorID uuid.UUID) error {
+	historia, err := s.historiaEditable(ctx, pacienteID)
+	if err != nil {
+		return err
+	}
+	a, err := s.alergiaRepo.GetByID(ctx, id)
+	if err != nil || a.HistoriaID != historia.ID {
+		return apperrors.NewNotFound("Alergia")
+	}
+	if err := s.alergiaRepo.Delete(ctx, id); err != nil {
+		return apperrors.NewInternal("Error al eliminar la alergia")
+	}
+	return s.actualizarResumen(ctx, historia, autorID, seccionAlergias)
+}
+
+// Medicamentos
+
+func (s *Service) GetMedicamentos(ctx context.Context, pacienteID uuid.UUID, soloActivos bool) ([]domain.Medicamento, error) {
+	historia, err := s.historiaDePaciente(ctx, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+	medicamentos, err := s.medicamentoRepo.GetByHistoriaID(ctx, historia.ID)
+	if err != nil {
+		return nil, apperrors.NewInternal("Error al obtener medicamentos")
+	}
+
+	result := []domain.Medicamento{}
+	for _, m := range medicamentos {
+		if soloActivos && !m.Activo {
+			continue
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+func (s *Service) CreateMedicamento(ctx context.Context, pacienteID uuid.UUID, in MedicamentoInput, createdBy uuid.UUID) (*domain.Medicamento, error) {
+	historia, err := s.historiaEditable(ctx, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &domain.Medicamento{
+		ID:         uuid.New(),
+		HistoriaID: historia.ID,
+		CreatedBy:  createdBy,
+	}
+	if err := aplicarMedicamento(m, in); err != nil {
+		return nil, err
+	}
+	if err := s.conservarTextoLibre(ctx, historia, seccionMedicamentos); err != nil {
+		return nil, err
+	}
+	if err := s.medicamentoRepo.Create(ctx, m); err != nil {
+		return nil, apperrors.NewInternal("Error al registrar el medicamento")
+	}
+	if err := s.actualizarResumen(ctx, historia, createdBy, seccionMedicamentos); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (s *Service) UpdateMedicamento(ctx context.Context, pacienteID, id uuid.UUID, in MedicamentoInput, autorID uuid.UUID) (*domain.Medicamento, error) {
+	historia, err := s.historiaEditable(ctx, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.medicamentoRepo.GetByID(ctx, id)
+	if err != nil || m.HistoriaID != historia.ID {
+		return nil, apperrors.NewNotFound("Medicamento")
+	}
+
+	if err := aplicarMedicamento(m, in); err != nil {
+		return nil, err
+	}
+	if err := s.medicamentoRepo.Update(ctx, m); err != nil {
+		return nil, apperrors.NewInternal("Error al actualizar el medicamento")
+	}
+	if err := s.actualizarResumen(ctx, historia, autorID, seccionMedicamentos); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (s *Service) DeleteMedicamento(ctx context.Context, pacienteID, id, autorID uuid.UUID) error {
+	historia, err := s.historiaEditable(ctx, pacienteID)
+	if err != nil {
+		return err
+	}
+	m, err := s.medicamentoRepo.GetByID(ctx, id)
+	if err != nil || m.HistoriaID != historia.ID {
+		return apperrors.NewNotFound("Medicamento")
+	}
+	if err := s.medicamentoRepo.Delete(ctx, id); err != nil {
+		return apperrors.NewInternal("Error al eliminar el medicamento")
+	}
+	return s.actualizarResumen(ctx, historia, autorID, seccionMedicamentos)
+}
+
+func aplicarMedicamento(m *domain.Medicamento, in MedicamentoInput) error {
+	inicio, err := time.Parse("2006-01-02", in.FechaInicio)
+	if err != nil {
+		return apperrors.NewBadRequest("Formato de fecha_inicio inválido. Use YYYY-MM-DD")
+	}
+
+	var fin *time.Time
+	if in.FechaFin != "" {
+		f, err := time.Parse("2006-01-02", in.FechaFin)
+		if err != nil {
+			return apperrors.NewBadRequest("Formato de fecha_fin inválido. Use YYYY-MM-DD")
+		}
+		if f.Before(inicio) {
+			return apperrors.NewBadRequest("La fecha de fin no puede ser anterior a la de inicio")
+		}
+		fin = &f
+	}
+
+	m.Nombre = in.Nombre
+	m.Dosis = in.Dosis
+	m.Frecuencia = in.Frecuencia
+	m.FechaInicio = inicio
+	m.FechaFin = fin
+	m.Indicacion = in.Indicacion
+	m.Activo = m.ActivoEn(time.Now())
+	return nil
+}
+
+// Condiciones (antecedentes personales y familiares)
+
+func (s *Service) GetCondiciones(ctx context.Context, pacienteID uuid.UUID) ([]domain.Condicion, error) {
+	historia, err := s.historiaDePaciente(ctx, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+	condiciones, err := s.condicionRepo.GetByHistoriaID(ctx, historia.ID)
+	if err != nil {
+		return nil, apperrors.NewInternal("Error al obtener antecedentes")
+	}
+	if condiciones == nil {
+		condiciones = []domain.Condicion{}
+	}
+	return condiciones, nil
+}
+
+func (s *Service) CreateCondicion(ctx context.Context, pacienteID uuid.UUID, in CondicionInput, createdBy uuid.UUID) (*domain.Condicion, error) {
+	historia, err := s.historiaEditable(ctx, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &domain.Condicion{
+		ID:         uuid.New(),
+		HistoriaID: historia.ID,
+		CreatedBy:  createdBy,
+	}
+	if err := aplicarCondicion(c, in); err != nil {
+		return nil, err
+	}
+	if err := s.conservarTextoLibre(ctx, historia, seccionCondicion(c.Tipo)); err != nil {
+		return nil, err
+	}
+	if err := s.condicionRepo.Create(ctx, c); err != nil {
+		return nil, apperrors.NewInternal("Error al registrar el antecedente")
+	}
+	if err := s.actualizarResumen(ctx, historia, createdBy, seccionCondicion(c.Tipo)); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *Service) UpdateCondicion(ctx context.Context, pacienteID, id uuid.UUID, in CondicionInput, autorID uuid.UUID) (*domain.Condicion, error) {
+	historia, err := s.historiaEditable(ctx, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.condicionRepo.GetByID(ctx, id)
+	if err != nil || c.HistoriaID != historia.ID {
+		return nil, apperrors.NewNotFound("Antecedente")
+	}
+
+	tipoAnterior := c.Tipo
+	if err := aplicarCondicion(c, in); err != nil {
+		return nil, err
+	}
+	if c.Tipo != tipoAnterior {
+		if err := s.conservarTextoLibre(ctx, historia, seccionCondicion(c.Tipo)); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.condicionRepo.Update(ctx, c); err != nil {
+		return nil, apperrors.NewInternal("Error al actualizar el antecedente")
+	}
+	if err := s.actualizarResumen(ctx, historia, autorID, seccionCondicion(tipoAnterior), seccionCondicion(c.Tipo)); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *Service) DeleteCondicion(ctx context.Context, pacienteID, id, autorID uuid.UUID) error {
+	historia, err := s.historiaEditable(ctx, pacienteID)
+	if err != nil {
+		return err
+	}
+	c, err := s.condicionRepo.GetByID(ctx, id)
+	if err != nil || c.HistoriaID != historia.ID {
+		return apperrors.NewNotFound("Antecedente")
+	}
+	if err := s.condicionRepo.Delete(ctx, id); err != nil {
+		return apperrors.NewInternal("Error al eliminar el antecedente")
+	}
+	return s.actualizarResumen(ctx, historia, autorID, seccionCondicion(c.Tipo))
+}
+
+func aplicarCondicion(c *domain.Condicion, in CondicionInput) error {
+	if !in.Tipo.IsValid() {
+		return apperrors.NewBadRequest("Tipo de antecedente inválido. Use: PERSONAL o FAMILIAR")
+	}
+	if in.Tipo == domain.CondicionFamiliar && strings.TrimSpace(in.Parentesco) == "" {
+		return apperrors.NewBadRequest("El parentesco es requerido para antecedentes familiares")
+	}
+
+	c.Tipo = in.Tipo
+	c.Codigo = strings.ToUpper(strings.TrimSpace(in.Codigo))
+	c.Descripcion = in.Descripcion
+	c.Parentesco = in.Parentesco
+	c.Observaciones = in.Observaciones
+	return nil
+}
@@ -0,0 +1,70 @@
+package historia
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+)
+
+// Cerrar bloquea la historia para nuevas notas y cambios de antecedentes.
+func (s *Service) Cerrar(ctx context.Context, pacienteID uuid.UUID, motivo string, userID uuid.UUID) (*domain.HistoriaClinica, error) {
+	return s.cambiarEstado(ctx, pacienteID, domain.HistoriaCerrada, motivo, userID, domain.HistoriaActiva)
+}
+
+// Archivar retira la historia del uso habitual; también es de solo lectura.
+func (s *Service) Archivar(ctx context.Context, pacienteID uuid.UUID, motivo string, userID uuid.UUID) (*domain.HistoriaClinica, error) {
+	return s.cambiarEstado(ctx, pacienteID, domain.HistoriaArchivada, motivo, userID, domain.HistoriaActiva, domain.HistoriaCerrada)
+}
+
+// Reabrir devuelve a ACTIVA una historia cerrada o archivada.
+func (s *Service) Reabrir(ctx context.Context, pacienteID uuid.UUID, motivo string, userID uuid.UUID) (*domain.HistoriaClinica, error) {
+	return s.cambiarEstado(ctx, pacienteID, domain.HistoriaActiva, motivo, userID, domain.HistoriaCerrada, domain.HistoriaArchivada)
+}
+
+func (s *Service) cambiarEstado(ctx context.Context, pacienteID uuid.UUID, nuevo domain.EstadoHistoria, motivo string, userID uuid.UUID, desde ...domain.EstadoHistoria) (*domain.HistoriaClinica, error) {
+	if userID == uuid.Nil {
+		return nil, apperrors.NewUnauthorized("Usuario no identificado")
+	}
+	motivo = strings.TrimSpace(motivo)
+	if motivo == "" {
+		return nil, apperrors.NewBadRequest("El campo motivo es requerido")
+	}
+
+	historia, err := s.historiaDePaciente(ctx, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+
+	permitido := false
+	for _, e := range desde {
+		if historia.Estado == e {
+			permitido = true
+			break
+		}
+	}
+	if !permitido {
+		return nil, apperrors.NewConflict("La historia clínica ya está " + strings.ToLower(string(historia.Estado)))
+	}
+
+	// Un borrador en una historia cerrada no podría firmarse ni editarse.
+	if nuevo != domain.HistoriaActiva {
+		notas, err := s.notaRepo.GetByHistoriaID(ctx, historia.ID)
+		if err != nil {
+			return nil, apperrors.NewInternal("Error al obtener notas de evolución")
+		}
+		for _, n := range notas {
+			if !n.Firmada() {
+				return nil, apperrors.NewConflict("La historia tiene notas en borrador; su autor debe firmarlas o descartarlas (o la Administradora descartarlas indicando el motivo) antes de cerrarla")
+			}
+		}
+	}
+
+	if err := s.repo.CambiarEstado(ctx, historia.ID, nuevo, motivo, userID); err != nil {
+		return nil, apperrors.NewInternal("Error al cambiar el estado de la historia clínica")
+	}
+
+	return s.GetByPacienteID(ctx, pacienteID)
+}
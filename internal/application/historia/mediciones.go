@@ -0,0 +1,217 @@
+package historia
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+)
+
+type MedicionInput struct {
+	Fecha              string // RFC 3339 o YYYY-MM-DD; vacío usa la fecha actual
+	CitaID             *uuid.UUID
+	PaqueteID          *uuid.UUID
+	PesoKg             *float64
+	TallaCm            *float64
+	CinturaCm          *float64
+	CaderaCm           *float64
+	AbdomenCm          *float64
+	PresionSistolica   *int
+	PresionDiastolica  *int
+	FrecuenciaCardiaca *int
+	EscalaDolor        *int
+	Observaciones      string
+}
+
+// SerieMedicion es la evolución de una métrica en el tiempo, con el primer y
+// último valor para mostrar el progreso (por ejemplo, a lo largo de un paquete).
+type SerieMedicion struct {
+	Metrica   domain.MetricaMedicion `json:"metrica"`
+	Unidad    string                 `json:"unidad"`
+	Puntos    []domain.PuntoSerie    `json:"puntos"`
+	Inicial   *float64               `json:"inicial,omitempty"`
+	Actual    *float64               `json:"actual,omitempty"`
+	Variacion *float64               `json:"variacion,omitempty"`
+}
+
+func (s *Service) GetMediciones(ctx context.Context, pacienteID uuid.UUID, paqueteID *uuid.UUID) ([]domain.Medicion, error) {
+	historia, err := s.historiaDePaciente(ctx, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+
+	mediciones, err := s.medicionRepo.GetByHistoriaID(ctx, historia.ID, paqueteID)
+	if err != nil {
+		return nil, apperrors.NewInternal("Error al obtener mediciones")
+	}
+	if mediciones == nil {
+		mediciones = []domain.Medicion{}
+	}
+	return mediciones, nil
+}
+
+// CreateMedicion registra las medidas de una sesión. Si se informa el peso, el
+// IMC se calcula con la talla de la medición o, si no viene, con la última
+// talla registrada del paciente.
+func (s *Service) CreateMedicion(ctx context.Context, pacienteID uuid.UUID, in MedicionInput, createdBy uuid.UUID) (*domain.Medicion, error) {
+	historia, err := s.historiaEditable(ctx, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validarMedicion(in); err != nil {
+		return nil, err
+	}
+
+	fecha := time.Now()
+	if in.Fecha != "" {
+		if fecha, err = parseFechaMedicion(in.Fecha); err != nil {
+			return nil, err
+		}
+	}
+
+	paqueteID, err := s.validarVinculos(ctx, pacienteID, in.CitaID, in.PaqueteID)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &domain.Medicion{
+		ID:                 uuid.New(),
+		HistoriaID:         historia.ID,
+		CitaID:             in.CitaID,
+		PaqueteID:          paqueteID,
+		Fecha:              fecha,
+		PesoKg:             in.PesoKg,
+		TallaCm:            in.TallaCm,
+		CinturaCm:          in.CinturaCm,
+		CaderaCm:           in.CaderaCm,
+		AbdomenCm:          in.AbdomenCm,
+		PresionSistolica:   in.PresionSistolica,
+		PresionDiastolica:  in.PresionDiastolica,
+		FrecuenciaCardiaca: in.FrecuenciaCardiaca,
+		EscalaDolor:        in.EscalaDolor,
+		Observaciones:      strings.TrimSpace(in.Observaciones),
+		CreatedBy:          createdBy,
+	}
+
+	if m.PesoKg != nil {
+		talla := m.TallaCm
+		if talla == nil {
+			if talla, err = s.medicionRepo.UltimaTalla(ctx, historia.ID); err != nil {
+				return nil, apperrors.NewInternal("Error al obtener la talla del paciente")
+			}
+		}
+		if talla != nil {
+			imc := domain.CalcularIMC(*m.PesoKg, *talla)
+			if imc < domain.IMCMinimo || imc > domain.IMCMaximo {
+				origen := "talla_cm"
+				if m.TallaCm == nil {
+					origen = "la última talla registrada"
+				}
+				return nil, apperrors.NewBadRequest(fmt.Sprintf(
+					"El IMC calculado (%.2f) está fuera de rango; revise peso_kg (%.1f) y %s (%.1f)", imc, *m.PesoKg, origen, *talla))
+			}
+			m.IMC = &imc
+		}
+	}
+
+	if err := s.medicionRepo.Create(ctx, m); err != nil {
+		return nil, apperrors.NewInternal("Error al registrar la medición")
+	}
+	return m, nil
+}
+
+func (s *Service) GetSerie(ctx context.Context, pacienteID uuid.UUID, metrica domain.MetricaMedicion, paqueteID *uuid.UUID) (*SerieMedicion, error) {
+	if !metrica.IsValid() {
+		return nil, apperrors.NewBadRequest("Métrica inválida: " + string(metrica))
+	}
+
+	historia, err := s.historiaDePaciente(ctx, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+
+	puntos, err := s.medicionRepo.GetSerie(ctx, historia.ID, metrica, paqueteID)
+	if err != nil {
+		return nil, apperrors.NewInternal("Error al obtener la serie de mediciones")
+	}
+
+	serie := &SerieMedicion{
+		Metrica: metrica,
+		Unidad:  domain.UnidadesMetrica[metrica],
+		Puntos:  []domain.PuntoSerie{},
+	}
+	if len(puntos) > 0 {
+		serie.Puntos = puntos
+		inicial, actual := puntos[0].Valor, puntos[len(puntos)-1].Valor
+		variacion := math.Round((actual-inicial)*100) / 100
+		serie.Inicial = &inicial
+		serie.Actual = &actual
+		serie.Variacion = &variacion
+	}
+	return serie, nil
+}
+
+func validarMedicion(in MedicionInput) error {
+	if in.PesoKg == nil && in.TallaCm == nil && in.CinturaCm == nil && in.CaderaCm == nil &&
+		in.AbdomenCm == nil && in.PresionSistolica == nil && in.PresionDiastolica == nil &&
+		in.FrecuenciaCardiaca == nil && in.EscalaDolor == nil {
+		return apperrors.NewBadRequest("Debe registrar al menos una medida")
+	}
+
+	rangos := []struct {
+		valor    *float64
+		nombre   string
+		min, max float64
+	}{
+		{in.PesoKg, "peso_kg", 1, 400},
+		{in.TallaCm, "talla_cm", 30, 250},
+		{in.CinturaCm, "cintura_cm", 20, 300},
+		{in.CaderaCm, "cadera_cm", 20, 300},
+		{in.AbdomenCm, "abdomen_cm", 20, 300},
+	}
+	for _, r := range rangos {
+		if r.valor != nil && (*r.valor < r.min || *r.valor > r.max) {
+			return apperrors.NewBadRequest("Valor fuera de rango para " + r.nombre)
+		}
+	}
+
+	enteros := []struct {
+		valor    *int
+		nombre   string
+		min, max int
+	}{
+		{in.PresionSistolica, "presion_sistolica", 50, 300},
+		{in.PresionDiastolica, "presion_diastolica", 20, 200},
+		{in.FrecuenciaCardiaca, "frecuencia_cardiaca", 20, 250},
+		{in.EscalaDolor, "escala_dolor", 0, 10},
+	}
+	for _, r := range enteros {
+		if r.valor != nil && (*r.valor < r.min || *r.valor > r.max) {
+			return apperrors.NewBadRequest("Valor fuera de rango para " + r.nombre)
+		}
+	}
+
+	if (in.PresionSistolica == nil) != (in.PresionDiastolica == nil) {
+		return apperrors.NewBadRequest("La presión arterial requiere sistólica y diastólica")
+	}
+	if in.PresionSistolica != nil && *in.PresionSistolica <= *in.PresionDiastolica {
+		return apperrors.NewBadRequest("La presión sistólica debe ser mayor que la diastólica")
+	}
+	return nil
+}
+
+func parseFechaMedicion(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, apperrors.NewBadRequest("Formato de fecha inválido. Use YYYY-MM-DD o RFC 3339")
+}
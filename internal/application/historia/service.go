@@ -2,6 +2,11 @@ package historia
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/tunek/centro-caribel/internal/domain"
@@ -9,13 +14,34 @@ import (
 )
 
 type Service struct {
-	repo         domain.HistoriaClinicaRepository
-	notaRepo     domain.NotaEvolucionRepository
-	pacienteRepo domain.PacienteRepository
+	repo            domain.HistoriaClinicaRepository
+	notaRepo        domain.NotaEvolucionRepository
+	pacienteRepo    domain.PacienteRepository
+	alergiaRepo     domain.AlergiaRepository
+	medicamentoRepo domain.MedicamentoRepository
+	condicionRepo   domain.CondicionRepository
+	citaRepo        domain.CitaRepository
+	paqueteRepo     domain.PaqueteRepository
+	plantillaRepo   domain.PlantillaNotaRepository
+	medicionRepo    domain.MedicionRepository
 }
 
-func NewService(repo domain.HistoriaClinicaRepository, notaRepo domain.NotaEvolucionRepository, pacienteRepo domain.PacienteRepository) *Service {
-	return &Service{repo: repo, notaRepo: notaRepo, pacienteRepo: pacienteRepo}
+func NewService(repo domain.HistoriaClinicaRepository, notaRepo domain.NotaEvolucionRepository, pacienteRepo domain.PacienteRepository,
+	alergiaRepo domain.AlergiaRepository, medicamentoRepo domain.MedicamentoRepository, condicionRepo domain.CondicionRepository,
+	citaRepo domain.CitaRepository, paqueteRepo domain.PaqueteRepository, plantillaRepo domain.PlantillaNotaRepository,
+	medicionRepo domain.MedicionRepository) *Service {
+	return &Service{
+		repo:            repo,
+		notaRepo:        notaRepo,
+		pacienteRepo:    pacienteRepo,
+		alergiaRepo:     alergiaRepo,
+		medicamentoRepo: medicamentoRepo,
+		condicionRepo:   condicionRepo,
+		citaRepo:        citaRepo,
+		paqueteRepo:     paqueteRepo,
+		plantillaRepo:   plantillaRepo,
+		medicionRepo:    medicionRepo,
+	}
 }
 
 func (s *Service) GetByPacienteID(ctx context.Context, pacienteID uuid.UUID) (*domain.HistoriaClinica, error) {
@@ -28,65 +54,374 @@ func (s *Service) GetByPacienteID(ctx context.Context, pacienteID uuid.UUID) (*d
 		return nil, apperrors.NewNotFound("Historia clínica")
 	}
 
+	if err := s.cargarAntecedentes(ctx, historia); err != nil {
+		return nil, err
+	}
+	medicamentosVigentes(historia)
+
 	return historia, nil
 }
 
-func (s *Service) UpdateAntecedentes(ctx context.Context, pacienteID uuid.UUID, antPersonales, antFamiliares, alergias, medicamentos string) (*domain.HistoriaClinica, error) {
-	historia, err := s.repo.GetByPacienteID(ctx, pacienteID)
+// UpdateAntecedentes reemplaza los antecedentes en texto libre. Cada cambio
+// queda registrado como una nueva versión a nombre de autorID.
+func (s *Service) UpdateAntecedentes(ctx context.Context, pacienteID uuid.UUID, antPersonales, antFamiliares, alergias, medicamentos string, autorID uuid.UUID) (*domain.HistoriaClinica, error) {
+	if autorID == uuid.Nil {
+		return nil, apperrors.NewUnauthorized("Usuario no identificado")
+	}
+
+	historia, err := s.historiaEditable(ctx, pacienteID)
 	if err != nil {
-		return nil, apperrors.NewNotFound("Historia clínica")
+		return nil, err
 	}
 
+	anterior := *historia
 	historia.AntecedentesPersonales = antPersonales
 	historia.AntecedentesFamiliares = antFamiliares
 	historia.Alergias = alergias
 	historia.MedicamentosActuales = medicamentos
 
-	if err := s.repo.UpdateAntecedentes(ctx, historia); err != nil {
-		return nil, apperrors.NewInternal("Error al actualizar antecedentes")
+	if !mismosAntecedentes(&anterior, historia) {
+		if err := s.repo.UpdateAntecedentes(ctx, historia, autorID); err != nil {
+			return nil, apperrors.NewInternal("Error al actualizar antecedentes")
+		}
 	}
 
+	if err := s.cargarAntecedentes(ctx, historia); err != nil {
+		return nil, err
+	}
 	return historia, nil
 }
 
-func (s *Service) GetNotas(ctx context.Context, pacienteID uuid.UUID) ([]domain.NotaEvolucion, error) {
+// GetNotas devuelve las notas firmadas con sus adendas anidadas, más los
+// borradores del usuario que consulta.
+func (s *Service) GetNotas(ctx context.Context, pacienteID, userID uuid.UUID) ([]domain.NotaEvolucion, error) {
 	historia, err := s.repo.GetByPacienteID(ctx, pacienteID)
 	if err != nil {
 		return nil, apperrors.NewNotFound("Historia clínica")
 	}
 
-	notas, err := s.notaRepo.GetByHistoriaID(ctx, historia.ID)
+	todas, err := s.notaRepo.GetByHistoriaID(ctx, historia.ID)
 	if err != nil {
 		return nil, apperrors.NewInternal("Error al obtener notas de evolución")
 	}
-	if notas == nil {
-		notas = []domain.NotaEvolucion{}
+
+	adendas := make(map[uuid.UUID][]domain.NotaEvolucion)
+	for _, n := range todas {
+		if n.EsAdenda() {
+			adendas[*n.NotaOriginalID] = append(adendas[*n.NotaOriginalID], n)
+		}
+	}
+
+	notas := []domain.NotaEvolucion{}
+	for _, n := range todas {
+		if n.EsAdenda() || (!n.Firmada() && n.CreatedBy != userID) {
+			continue
+		}
+		// Las adendas se listan en orden cronológico bajo la nota original.
+		for i := len(adendas[n.ID]) - 1; i >= 0; i-- {
+			n.Adendas = append(n.Adendas, adendas[n.ID][i])
+		}
+		notas = append(notas, n)
 	}
 	return notas, nil
 }
 
-func (s *Service) CreateNota(ctx context.Context, pacienteID uuid.UUID, tipo, contenido string, createdBy uuid.UUID) (*domain.NotaEvolucion, error) {
-	historia, err := s.repo.GetByPacienteID(ctx, pacienteID)
+// CreateNota registra una nota como borrador; con firmar=true queda firmada
+// por su autor en el mismo paso. citaID y paqueteID son opcionales y deben
+// pertenecer al mismo paciente; si solo se indica la cita, el paquete se toma
+// de ella. Con plantillaID la nota se arma a partir de datos, validados contra
+// la plantilla, y contenido se reemplaza por su texto.
+func (s *Service) CreateNota(ctx context.Context, pacienteID uuid.UUID, tipo, contenido string, citaID, paqueteID, plantillaID *uuid.UUID,
+	datos map[string]interface{}, firmar bool, createdBy uuid.UUID) (*domain.NotaEvolucion, error) {
+	nota, err := s.PrepararNota(ctx, pacienteID, tipo, contenido, citaID, paqueteID, plantillaID, datos, firmar, createdBy)
 	if err != nil {
-		return nil, apperrors.NewNotFound("Historia clínica")
+		return nil, err
+	}
+	if err := s.GuardarNota(ctx, nota); err != nil {
+		return nil, err
 	}
 
-	validTipos := map[string]bool{"TRATAMIENTO": true, "EVOLUCION": true, "NOTA": true}
-	if !validTipos[tipo] {
-		return nil, apperrors.NewBadRequest("Tipo de nota inválido. Use: TRATAMIENTO, EVOLUCION o NOTA")
+	return s.RecargarNota(ctx, nota), nil
+}
+
+// GuardarNota guarda una nota armada con PrepararNota. Si ctx lleva una
+// transacción, la nota se guarda en ella.
+func (s *Service) GuardarNota(ctx context.Context, nota *domain.NotaEvolucion) error {
+	if err := s.notaRepo.Create(ctx, nota); err != nil {
+		return apperrors.NewInternal("Error al crear nota de evolución")
+	}
+	return nil
+}
+
+// PrepararNota valida y arma la nota como CreateNota, sin guardarla, para
+// quien deba guardarla junto con otros cambios.
+func (s *Service) PrepararNota(ctx context.Context, pacienteID uuid.UUID, tipo, contenido string, citaID, paqueteID, plantillaID *uuid.UUID,
+	datos map[string]interface{}, firmar bool, createdBy uuid.UUID) (*domain.NotaEvolucion, error) {
+	historia, err := s.historiaEditable(ctx, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+
+	paqueteID, err = s.validarVinculos(ctx, pacienteID, citaID, paqueteID)
+	if err != nil {
+		return nil, err
 	}
 
 	nota := &domain.NotaEvolucion{
-		ID:         uuid.New(),
-		HistoriaID: historia.ID,
-		Tipo:       tipo,
-		Contenido:  contenido,
-		CreatedBy:  createdBy,
+		ID:          uuid.New(),
+		HistoriaID:  historia.ID,
+		Tipo:        tipo,
+		Contenido:   contenido,
+		Estado:      domain.NotaBorrador,
+		CitaID:      citaID,
+		PaqueteID:   paqueteID,
+		PlantillaID: plantillaID,
+		CreatedBy:   createdBy,
+	}
+	if plantillaID != nil {
+		if err := s.aplicarPlantilla(ctx, nota, datos, true); err != nil {
+			return nil, err
+		}
 	}
 
-	if err := s.notaRepo.Create(ctx, nota); err != nil {
-		return nil, apperrors.NewInternal("Error al crear nota de evolución")
+	if !tipoNotaValido(nota.Tipo) {
+		return nil, apperrors.NewBadRequest("Tipo de nota inválido. Use: TRATAMIENTO, EVOLUCION o NOTA")
+	}
+	if strings.TrimSpace(nota.Contenido) == "" {
+		return nil, apperrors.NewBadRequest("El campo contenido es requerido")
+	}
+	if firmar {
+		firmarEn(nota, createdBy)
+	}
+	return nota, nil
+}
+
+// aplicarPlantilla valida los datos contra la plantilla de la nota y genera su
+// contenido. Solo una plantilla activa admite notas nuevas; el tipo de la
+// plantilla se usa cuando la nota no indica uno.
+func (s *Service) aplicarPlantilla(ctx context.Context, nota *domain.NotaEvolucion, datos map[string]interface{}, nueva bool) error {
+	plantilla, err := s.plantillaRepo.GetByID(ctx, *nota.PlantillaID)
+	if err != nil {
+		return apperrors.NewNotFound("Plantilla")
 	}
+	if nueva && !plantilla.Activa {
+		return apperrors.NewBadRequest("La plantilla no está activa")
+	}
+
+	limpios, err := plantilla.Validar(datos)
+	if err != nil {
+		return apperrors.NewBadRequest("Datos inválidos: " + err.Error())
+	}
+	raw, err := json.Marshal(limpios)
+	if err != nil {
+		return apperrors.NewInternal("Error al procesar los datos de la nota")
+	}
+
+	if nota.Tipo == "" {
+		nota.Tipo = plantilla.TipoNota
+	}
+	nota.Datos = raw
+	nota.Contenido = plantilla.Renderizar(limpios)
+	return nil
+}
+
+// validarVinculos comprueba que la cita y el paquete pertenezcan al paciente y
+// sean coherentes entre sí. Devuelve el paquete efectivo de la nota.
+func (s *Service) validarVinculos(ctx context.Context, pacienteID uuid.UUID, citaID, paqueteID *uuid.UUID) (*uuid.UUID, error) {
+	if citaID != nil {
+		c, err := s.citaRepo.GetByID(ctx, *citaID)
+		if err != nil {
+			return nil, apperrors.NewNotFound("Cita")
+		}
+		if c.PacienteID != pacienteID {
+			return nil, apperrors.NewBadRequest("La cita no pertenece al paciente")
+		}
+		if c.PaqueteID != nil {
+			if paqueteID != nil && *paqueteID != *c.PaqueteID {
+				return nil, apperrors.NewBadRequest("El paquete indicado no corresponde al de la cita")
+			}
+			paqueteID = c.PaqueteID
+		}
+	}
+
+	if paqueteID != nil {
+		p, err := s.paqueteRepo.GetByID(ctx, *paqueteID)
+		if err != nil {
+			return nil, apperrors.NewNotFound("Paquete de tratamiento")
+		}
+		if p.PacienteID != pacienteID {
+			return nil, apperrors.NewBadRequest("El paquete no pertenece al paciente")
+		}
+	}
+
+	return paqueteID, nil
+}
+
+// RecargarNota vuelve a leer la nota para completar los datos calculados
+// (número de sesión). Si la lectura falla se devuelve la nota tal cual.
+func (s *Service) RecargarNota(ctx context.Context, n *domain.NotaEvolucion) *domain.NotaEvolucion {
+	if actual, err := s.notaRepo.GetByID(ctx, n.ID); err == nil {
+		return actual
+	}
+	return n
+}
 
+// UpdateNota modifica un borrador. Solo su autor puede editarlo y una nota
+// firmada no admite cambios: la corrección debe hacerse con una adenda. En
+// notas con plantilla se envían los datos y el contenido se vuelve a generar;
+// un tipo vacío conserva el actual.
+func (s *Service) UpdateNota(ctx context.Context, pacienteID, notaID uuid.UUID, tipo, contenido string, datos map[string]interface{}, userID uuid.UUID) (*domain.NotaEvolucion, error) {
+	nota, err := s.notaDePaciente(ctx, pacienteID, notaID)
+	if err != nil {
+		return nil, err
+	}
+	if nota.Firmada() {
+		return nil, apperrors.NewConflict("La nota está firmada y no puede modificarse. Registre una adenda")
+	}
+	if nota.CreatedBy != userID {
+		return nil, apperrors.NewForbidden("Solo el autor puede editar el borrador")
+	}
+
+	if tipo != "" {
+		nota.Tipo = tipo
+	}
+	if nota.PlantillaID != nil {
+		if err := s.aplicarPlantilla(ctx, nota, datos, false); err != nil {
+			return nil, err
+		}
+	} else {
+		nota.Contenido = contenido
+	}
+	if !tipoNotaValido(nota.Tipo) {
+		return nil, apperrors.NewBadRequest("Tipo de nota inválido. Use: TRATAMIENTO, EVOLUCION o NOTA")
+	}
+	if strings.TrimSpace(nota.Contenido) == "" {
+		return nil, apperrors.NewBadRequest("El campo contenido es requerido")
+	}
+
+	if err := s.notaRepo.Update(ctx, nota); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NewConflict("La nota está firmada y no puede modificarse. Registre una adenda")
+		}
+		return nil, apperrors.NewInternal("Error al actualizar nota de evolución")
+	}
+
+	return s.RecargarNota(ctx, nota), nil
+}
+
+// FirmarNota firma un borrador a nombre de su autor.
+func (s *Service) FirmarNota(ctx context.Context, pacienteID, notaID, userID uuid.UUID) (*domain.NotaEvolucion, error) {
+	nota, err := s.notaDePaciente(ctx, pacienteID, notaID)
+	if err != nil {
+		return nil, err
+	}
+	if nota.Firmada() {
+		return nil, apperrors.NewConflict("La nota ya está firmada")
+	}
+	if nota.CreatedBy != userID {
+		return nil, apperrors.NewForbidden("Solo el autor puede firmar la nota")
+	}
+
+	if err := s.notaRepo.Firmar(ctx, nota.ID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NewConflict("La nota ya está firmada")
+		}
+		return nil, apperrors.NewInternal("Error al firmar nota de evolución")
+	}
+
+	firmada, err := s.notaRepo.GetByID(ctx, nota.ID)
+	if err != nil {
+		return nil, apperrors.NewInternal("Error al obtener nota de evolución")
+	}
+	return firmada, nil
+}
+
+// DescartarNota elimina un borrador; su contenido queda en el registro de
+// notas descartadas, con quién lo descartó y cuándo. Lo descarta su autor; la
+// Administradora puede descartar el de otro, para que un borrador abandonado no
+// impida cerrar la historia, indicando el motivo, que queda registrado. Las
+// notas firmadas no se eliminan.
+func (s *Service) DescartarNota(ctx context.Context, pacienteID, notaID, userID uuid.UUID, rolNombre, motivo string) error {
+	nota, err := s.notaDePaciente(ctx, pacienteID, notaID)
+	if err != nil {
+		return err
+	}
+	if nota.Firmada() {
+		return apperrors.NewConflict("La nota está firmada y no puede descartarse")
+	}
+	if nota.CreatedBy != userID {
+		if rolNombre != "Administradora" {
+			return apperrors.NewForbidden("Solo el autor puede descartar el borrador")
+		}
+		if strings.TrimSpace(motivo) == "" {
+			return apperrors.NewBadRequest("Para descartar el borrador de otro usuario indique el motivo")
+		}
+	}
+
+	if err := s.notaRepo.Descartar(ctx, nota.ID, userID, motivo); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperrors.NewConflict("La nota está firmada y no puede descartarse")
+		}
+		return apperrors.NewInternal("Error al descartar la nota de evolución")
+	}
+	return nil
+}
+
+// CreateAdenda agrega una corrección a una nota firmada. La adenda queda
+// firmada por quien la registra y se enlaza siempre a la nota original.
+func (s *Service) CreateAdenda(ctx context.Context, pacienteID, notaID uuid.UUID, contenido string, createdBy uuid.UUID) (*domain.NotaEvolucion, error) {
+	original, err := s.notaDePaciente(ctx, pacienteID, notaID)
+	if err != nil {
+		return nil, err
+	}
+	if original.EsAdenda() {
+		if original, err = s.notaRepo.GetByID(ctx, *original.NotaOriginalID); err != nil {
+			return nil, apperrors.NewNotFound("Nota de evolución")
+		}
+	}
+	if !original.Firmada() {
+		return nil, apperrors.NewConflict("La nota aún es un borrador; edítela en lugar de registrar una adenda")
+	}
+
+	adenda := &domain.NotaEvolucion{
+		ID:             uuid.New(),
+		HistoriaID:     original.HistoriaID,
+		Tipo:           original.Tipo,
+		Contenido:      contenido,
+		NotaOriginalID: &original.ID,
+		CitaID:         original.CitaID,
+		PaqueteID:      original.PaqueteID,
+		CreatedBy:      createdBy,
+	}
+	firmarEn(adenda, createdBy)
+
+	if err := s.notaRepo.Create(ctx, adenda); err != nil {
+		return nil, apperrors.NewInternal("Error al registrar la adenda")
+	}
+
+	return s.RecargarNota(ctx, adenda), nil
+}
+
+func (s *Service) notaDePaciente(ctx context.Context, pacienteID, notaID uuid.UUID) (*domain.NotaEvolucion, error) {
+	historia, err := s.historiaEditable(ctx, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+	nota, err := s.notaRepo.GetByID(ctx, notaID)
+	if err != nil || nota.HistoriaID != historia.ID {
+		return nil, apperrors.NewNotFound("Nota de evolución")
+	}
 	return nota, nil
 }
+
+func firmarEn(n *domain.NotaEvolucion, firmadoPor uuid.UUID) {
+	ahora := time.Now()
+	n.Estado = domain.NotaFirmada
+	n.FirmadoPor = &firmadoPor
+	n.FirmadoAt = &ahora
+}
+
+func tipoNotaValido(tipo string) bool {
+	validTipos := map[string]bool{"TRATAMIENTO": true, "EVOLUCION": true, "NOTA": true}
+	return validTipos[tipo]
+}
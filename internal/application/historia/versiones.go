@@ -0,0 +1,152 @@
+package historia
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+)
+
+// LineaDiff es una línea del diff: Op es "=" (sin cambios), "+" (agregada) o "-" (eliminada).
+type LineaDiff struct {
+	Op    string `json:"op"`
+	Texto string `json:"texto"`
+}
+
+type CampoDiff struct {
+	Campo      string      `json:"campo"`
+	Modificado bool        `json:"modificado"`
+	Lineas     []LineaDiff `json:"lineas"`
+}
+
+type DiffVersiones struct {
+	Desde  domain.VersionAntecedentes `json:"desde"`
+	Hasta  domain.VersionAntecedentes `json:"hasta"`
+	Campos []CampoDiff                `json:"campos"`
+}
+
+func (s *Service) GetVersiones(ctx context.Context, pacienteID uuid.UUID) ([]domain.VersionAntecedentes, error) {
+	historia, err := s.historiaDePaciente(ctx, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+
+	versiones, err := s.repo.GetVersiones(ctx, historia.ID)
+	if err != nil {
+		return nil, apperrors.NewInternal("Error al obtener versiones de antecedentes")
+	}
+	if versiones == nil {
+		versiones = []domain.VersionAntecedentes{}
+	}
+	return versiones, nil
+}
+
+// Diff compara dos versiones de los antecedentes. Si hasta es 0 se usa la
+// última versión; si desde es 0 se usa la inmediatamente anterior a hasta.
+func (s *Service) Diff(ctx context.Context, pacienteID uuid.UUID, desde, hasta int) (*DiffVersiones, error) {
+	historia, err := s.historiaDePaciente(ctx, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+
+	if hasta == 0 {
+		versiones, err := s.repo.GetVersiones(ctx, historia.ID)
+		if err != nil {
+			return nil, apperrors.NewInternal("Error al obtener versiones de antecedentes")
+		}
+		if len(versiones) == 0 {
+			return nil, apperrors.NewNotFound("Versión de antecedentes")
+		}
+		hasta = versiones[0].Version
+	}
+	if desde == 0 {
+		desde = hasta - 1
+		if desde < 1 {
+			desde = 1
+		}
+	}
+	if desde < 0 || hasta < 0 || desde > hasta {
+		return nil, apperrors.NewBadRequest("Rango de versiones inválido")
+	}
+
+	vDesde, err := s.repo.GetVersion(ctx, historia.ID, desde)
+	if err != nil {
+		return nil, apperrors.NewNotFound("Versión de antecedentes")
+	}
+	vHasta, err := s.repo.GetVersion(ctx, historia.ID, hasta)
+	if err != nil {
+		return nil, apperrors.NewNotFound("Versión de antecedentes")
+	}
+
+	return &DiffVersiones{
+		Desde: *vDesde,
+		Hasta: *vHasta,
+		Campos: []CampoDiff{
+			diffCampo("antecedentes_personales", vDesde.AntecedentesPersonales, vHasta.AntecedentesPersonales),
+			diffCampo("antecedentes_familiares", vDesde.AntecedentesFamiliares, vHasta.AntecedentesFamiliares),
+			diffCampo("alergias", vDesde.Alergias, vHasta.Alergias),
+			diffCampo("medicamentos_actuales", vDesde.MedicamentosActuales, vHasta.MedicamentosActuales),
+		},
+	}, nil
+}
+
+func diffCampo(campo, antes, despues string) CampoDiff {
+	return CampoDiff{
+		Campo:      campo,
+		Modificado: antes != despues,
+		Lineas:     diffLineas(dividirLineas(antes), dividirLineas(despues)),
+	}
+}
+
+func dividirLineas(texto string) []string {
+	if texto == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(texto, "\r\n", "\n"), "\n")
+}
+
+// diffLineas calcula un diff por líneas a partir de la subsecuencia común más
+// larga. Los textos de antecedentes son cortos, así que la tabla O(n·m) basta.
+func diffLineas(a, b []string) []LineaDiff {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lineas := []LineaDiff{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lineas = append(lineas, LineaDiff{Op: "=", Texto: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lineas = append(lineas, LineaDiff{Op: "-", Texto: a[i]})
+			i++
+		default:
+			lineas = append(lineas, LineaDiff{Op: "+", Texto: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lineas = append(lineas, LineaDiff{Op: "-", Texto: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lineas = append(lineas, LineaDiff{Op: "+", Texto: b[j]})
+	}
+	return lineas
+}
@@ -0,0 +1,227 @@
+package paciente
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+	"github.com/tunek/centro-caribel/pkg/spreadsheet"
+)
+
+// CamposImportacion son los campos del paciente que se pueden mapear desde una columna de la planilla.
+var CamposImportacion = []string{"nombre_completo", "ci", "fecha_nacimiento", "celular", "direccion"}
+
+var camposRequeridos = map[string]bool{
+	"nombre_completo":  true,
+	"ci":               true,
+	"fecha_nacimiento": true,
+	"celular":          true,
+}
+
+// aliasColumnas permite reconocer encabezados habituales sin un mapeo explícito.
+var aliasColumnas = map[string][]string{
+	"nombre_completo":  {"nombre", "nombres", "paciente", "nombre y apellido"},
+	"ci":               {"carnet", "carnet de identidad", "documento", "cedula"},
+	"fecha_nacimiento": {"fecha de nacimiento", "fecha nacimiento", "nacimiento", "fecha nac"},
+	"celular":          {"telefono", "cel", "movil", "whatsapp"},
+	"direccion":        {"domicilio"},
+}
+
+type FilaImportacion struct {
+	Fila            int
+	NombreCompleto  string
+	CI              string
+	FechaNacimiento string
+	Celular         string
+	Direccion       string
+}
+
+type ErrorFila struct {
+	Fila  int    `json:"fila"`
+	CI    string `json:"ci,omitempty"`
+	Error string `json:"error"`
+}
+
+type PacienteImportado struct {
+	Fila           int       `json:"fila"`
+	ID             uuid.UUID `json:"id"`
+	Codigo         string    `json:"codigo"`
+	NumeroHistoria string    `json:"numero_historia,omitempty"`
+}
+
+type ResultadoImportacion struct {
+	DryRun     bool                `json:"dry_run"`
+	TotalFilas int                 `json:"total_filas"`
+	Validas    int                 `json:"validas"`
+	Importadas int                 `json:"importadas"`
+	Errores    []ErrorFila         `json:"errores"`
+	Pacientes  []PacienteImportado `json:"pacientes,omitempty"`
+}
+
+// MapearFilas convierte los registros de una planilla (la primera fila no
+// vacía es el encabezado) en filas de importación; el registro i es la fila
+// i+1 del archivo. mapeo indica, por campo, el nombre de la
+// columna de origen; los campos sin mapeo se buscan por nombre o alias.
+func MapearFilas(registros [][]string, mapeo map[string]string) ([]FilaImportacion, error) {
+	inicio := 0
+	for inicio < len(registros) && filaVacia(registros[inicio]) {
+		inicio++
+	}
+	if inicio == len(registros) {
+		return nil, apperrors.NewBadRequest("El archivo está vacío")
+	}
+
+	encabezado := make(map[string]int)
+	for i, h := range registros[inicio] {
+		encabezado[normalizarColumna(h)] = i
+	}
+
+	columnas := make(map[string]int)
+	for _, campo := range CamposImportacion {
+		candidatos := []string{campo}
+		if origen, ok := mapeo[campo]; ok {
+			candidatos = []string{origen}
+		} else {
+			candidatos = append(candidatos, aliasColumnas[campo]...)
+		}
+
+		for _, c := range candidatos {
+			if idx, ok := encabezado[normalizarColumna(c)]; ok {
+				columnas[campo] = idx
+				break
+			}
+		}
+		if _, ok := columnas[campo]; !ok && camposRequeridos[campo] {
+			return nil, apperrors.NewBadRequest(fmt.Sprintf("No se encontró la columna para el campo '%s'", campo))
+		}
+	}
+	for campo := range mapeo {
+		if _, ok := aliasColumnas[campo]; !ok {
+			return nil, apperrors.NewBadRequest(fmt.Sprintf("Campo de mapeo desconocido: '%s'", campo))
+		}
+	}
+
+	valor := func(reg []string, campo string) string {
+		idx, ok := columnas[campo]
+		if !ok || idx >= len(reg) {
+			return ""
+		}
+		return strings.TrimSpace(reg[idx])
+	}
+
+	var filas []FilaImportacion
+	for i := inicio + 1; i < len(registros); i++ {
+		reg := registros[i]
+		if filaVacia(reg) {
+			continue
+		}
+		filas = append(filas, FilaImportacion{
+			Fila:            i + 1,
+			NombreCompleto:  valor(reg, "nombre_completo"),
+			CI:              valor(reg, "ci"),
+			FechaNacimiento: valor(reg, "fecha_nacimiento"),
+			Celular:         valor(reg, "celular"),
+			Direccion:       valor(reg, "direccion"),
+		})
+	}
+	return filas, nil
+}
+
+func filaVacia(reg []string) bool {
+	return strings.TrimSpace(strings.Join(reg, "")) == ""
+}
+
+// Importar registra cada fila con las mismas validaciones que Create. Con
+// dryRun solo se valida y se devuelve el reporte de errores por fila.
+func (s *Service) Importar(ctx context.Context, filas []FilaImportacion, dryRun bool, createdBy uuid.UUID) (*ResultadoImportacion, error) {
+	res := &ResultadoImportacion{
+		DryRun:     dryRun,
+		TotalFilas: len(filas),
+		Errores:    []ErrorFila{},
+	}
+
+	vistos := make(map[string]int)
+	for _, f := range filas {
+		if err := f.validar(); err != nil {
+			res.Errores = append(res.Errores, ErrorFila{Fila: f.Fila, CI: f.CI, Error: mensajeError(err)})
+			continue
+		}
+		if prev, ok := vistos[f.CI]; ok {
+			res.Errores = append(res.Errores, ErrorFila{Fila: f.Fila, CI: f.CI, Error: fmt.Sprintf("CI duplicado en el archivo (fila %d)", prev)})
+			continue
+		}
+		vistos[f.CI] = f.Fila
+
+		fecha, err := s.validarNuevo(ctx, f.CI, normalizarFecha(f.FechaNacimiento))
+		if err != nil {
+			res.Errores = append(res.Errores, ErrorFila{Fila: f.Fila, CI: f.CI, Error: mensajeError(err)})
+			continue
+		}
+		res.Validas++
+		if dryRun {
+			continue
+		}
+
+		pac, err := s.registrar(ctx, f.NombreCompleto, f.CI, fecha, f.Celular, f.Direccion, createdBy)
+		if err != nil {
+			res.Errores = append(res.Errores, ErrorFila{Fila: f.Fila, CI: f.CI, Error: mensajeError(err)})
+			continue
+		}
+		res.Importadas++
+
+		importado := PacienteImportado{Fila: f.Fila, ID: pac.ID, Codigo: pac.Codigo}
+		historia, err := s.crearHistoria(ctx, pac.ID)
+		if err != nil {
+			res.Errores = append(res.Errores, ErrorFila{Fila: f.Fila, CI: f.CI, Error: "Paciente " + pac.Codigo + " creado sin historia clínica: " + mensajeError(err)})
+		} else {
+			importado.NumeroHistoria = historia.NumeroHistoria
+		}
+		res.Pacientes = append(res.Pacientes, importado)
+	}
+
+	return res, nil
+}
+
+func (f FilaImportacion) validar() error {
+	if f.NombreCompleto == "" {
+		return apperrors.NewBadRequest("El campo 'nombre_completo' es requerido")
+	}
+	if f.CI == "" {
+		return apperrors.NewBadRequest("El campo 'ci' es requerido")
+	}
+	if f.FechaNacimiento == "" {
+		return apperrors.NewBadRequest("El campo 'fecha_nacimiento' es requerido")
+	}
+	if f.Celular == "" {
+		return apperrors.NewBadRequest("El campo 'celular' es requerido")
+	}
+	return nil
+}
+
+// normalizarFecha convierte las celdas de fecha de XLSX (número de serie) a
+// YYYY-MM-DD; cualquier otro valor se valida tal cual.
+func normalizarFecha(v string) string {
+	if _, err := time.Parse("2006-01-02", v); err == nil {
+		return v
+	}
+	if t, ok := spreadsheet.ExcelSerialToDate(v); ok {
+		return t.Format("2006-01-02")
+	}
+	return v
+}
+
+func normalizarColumna(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ñ", "n", ".", "", "_", " ").Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func mensajeError(err error) string {
+	if appErr, ok := err.(*apperrors.AppError); ok {
+		return appErr.Detail
+	}
+	return err.Error()
+}
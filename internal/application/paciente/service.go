@@ -10,24 +10,47 @@ import (
 )
 
 type Service struct {
-	repo        domain.PacienteRepository
+	repo         domain.PacienteRepository
 	historiaRepo domain.HistoriaClinicaRepository
+	tutorRepo    domain.TutorRepository
 }
 
-func NewService(repo domain.PacienteRepository, historiaRepo domain.HistoriaClinicaRepository) *Service {
-	return &Service{repo: repo, historiaRepo: historiaRepo}
+func NewService(repo domain.PacienteRepository, historiaRepo domain.HistoriaClinicaRepository, tutorRepo domain.TutorRepository) *Service {
+	return &Service{repo: repo, historiaRepo: historiaRepo, tutorRepo: tutorRepo}
 }
 
 func (s *Service) Create(ctx context.Context, nombre, ci, fechaNac, celular, direccion string, createdBy uuid.UUID) (*domain.Paciente, error) {
+	fecha, err := s.validarNuevo(ctx, ci, fechaNac)
+	if err != nil {
+		return nil, err
+	}
+
+	pac, err := s.registrar(ctx, nombre, ci, fecha, celular, direccion, createdBy)
+	if err != nil {
+		return nil, err
+	}
+
+	// Crear historia clínica automáticamente
+	_, _ = s.crearHistoria(ctx, pac.ID)
+
+	return pac, nil
+}
+
+// validarNuevo aplica las reglas de alta de paciente: CI único y fecha de
+// nacimiento en formato YYYY-MM-DD.
+func (s *Service) validarNuevo(ctx context.Context, ci, fechaNac string) (time.Time, error) {
 	if existing, _ := s.repo.GetByCI(ctx, ci); existing != nil {
-		return nil, apperrors.NewConflict("Ya existe un paciente con ese CI")
+		return time.Time{}, apperrors.NewConflict("Ya existe un paciente con ese CI")
 	}
 
 	fecha, err := time.Parse("2006-01-02", fechaNac)
 	if err != nil {
-		return nil, apperrors.NewBadRequest("Formato de fecha inválido. Use YYYY-MM-DD")
+		return time.Time{}, apperrors.NewBadRequest("Formato de fecha inválido. Use YYYY-MM-DD")
 	}
+	return fecha, nil
+}
 
+func (s *Service) registrar(ctx context.Context, nombre, ci string, fecha time.Time, celular, direccion string, createdBy uuid.UUID) (*domain.Paciente, error) {
 	codigo, err := s.repo.NextCodigo(ctx)
 	if err != nil {
 		return nil, apperrors.NewInternal("Error al generar código de paciente")
@@ -41,26 +64,35 @@ func (s *Service) Create(ctx context.Context, nombre, ci, fechaNac, celular, dir
 		FechaNacimiento: fecha,
 		Celular:         celular,
 		Direccion:       direccion,
+		Estado:          domain.PacienteActivo,
 		CreatedBy:       createdBy,
 	}
+	pac.CalcularEdad()
 
 	if err := s.repo.Create(ctx, pac); err != nil {
 		return nil, apperrors.NewInternal("Error al crear el paciente")
 	}
 
-	// Crear historia clínica automáticamente
+	return pac, nil
+}
+
+func (s *Service) crearHistoria(ctx context.Context, pacienteID uuid.UUID) (*domain.HistoriaClinica, error) {
 	numHistoria, err := s.historiaRepo.NextNumero(ctx)
-	if err == nil {
-		historia := &domain.HistoriaClinica{
-			ID:             uuid.New(),
-			PacienteID:     pac.ID,
-			NumeroHistoria: numHistoria,
-			Estado:         "ACTIVA",
-		}
-		_ = s.historiaRepo.Create(ctx, historia)
+	if err != nil {
+		return nil, apperrors.NewInternal("Error al generar número de historia clínica")
 	}
 
-	return pac, nil
+	historia := &domain.HistoriaClinica{
+		ID:             uuid.New(),
+		PacienteID:     pacienteID,
+		NumeroHistoria: numHistoria,
+		Estado:         domain.HistoriaActiva,
+	}
+	if err := s.historiaRepo.Create(ctx, historia); err != nil {
+		return nil, apperrors.NewInternal("Error al crear la historia clínica")
+	}
+
+	return historia, nil
 }
 
 func (s *Service) GetByID(ctx context.Context, id uuid.UUID) (*domain.Paciente, error) {
@@ -71,7 +103,7 @@ func (s *Service) GetByID(ctx context.Context, id uuid.UUID) (*domain.Paciente,
 	return pac, nil
 }
 
-func (s *Service) GetAll(ctx context.Context, page, perPage int, query string) ([]domain.Paciente, int64, error) {
+func (s *Service) GetAll(ctx context.Context, page, perPage int, query string, incluirArchivados bool) ([]domain.Paciente, int64, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -81,7 +113,76 @@ func (s *Service) GetAll(ctx context.Context, page, perPage int, query string) (
 	offset := (page - 1) * perPage
 
 	if query != "" {
-		return s.repo.Search(ctx, query, offset, perPage)
+		return s.repo.Search(ctx, query, offset, perPage, incluirArchivados)
+	}
+	return s.repo.GetAll(ctx, offset, perPage, incluirArchivados)
+}
+
+func (s *Service) Archivar(ctx context.Context, id uuid.UUID, motivo string, archivadoPor uuid.UUID) (*domain.Paciente, error) {
+	pac, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, apperrors.NewNotFound("Paciente")
+	}
+	if pac.Archivado() {
+		return nil, apperrors.NewConflict("El paciente ya está archivado")
+	}
+
+	if err := s.repo.Archivar(ctx, id, motivo, archivadoPor); err != nil {
+		return nil, apperrors.NewInternal("Error al archivar el paciente")
+	}
+
+	return s.GetByID(ctx, id)
+}
+
+func (s *Service) Restaurar(ctx context.Context, id uuid.UUID) (*domain.Paciente, error) {
+	pac, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, apperrors.NewNotFound("Paciente")
+	}
+	if !pac.Archivado() {
+		return nil, apperrors.NewConflict("El paciente no está archivado")
+	}
+
+	if err := s.repo.Restaurar(ctx, id); err != nil {
+		return nil, apperrors.NewInternal("Error al restaurar el paciente")
+	}
+
+	return s.GetByID(ctx, id)
+}
+
+func (s *Service) CreateTutor(ctx context.Context, pacienteID uuid.UUID, nombre, ci, parentesco, celular string, createdBy uuid.UUID) (*domain.Tutor, error) {
+	if _, err := s.repo.GetByID(ctx, pacienteID); err != nil {
+		return nil, apperrors.NewNotFound("Paciente")
+	}
+
+	t := &domain.Tutor{
+		ID:             uuid.New(),
+		PacienteID:     pacienteID,
+		NombreCompleto: nombre,
+		CI:             ci,
+		Parentesco:     parentesco,
+		Celular:        celular,
+		CreatedBy:      createdBy,
+	}
+
+	if err := s.tutorRepo.Create(ctx, t); err != nil {
+		return nil, apperrors.NewInternal("Error al registrar el tutor")
+	}
+
+	return t, nil
+}
+
+func (s *Service) GetTutores(ctx context.Context, pacienteID uuid.UUID) ([]domain.Tutor, error) {
+	if _, err := s.repo.GetByID(ctx, pacienteID); err != nil {
+		return nil, apperrors.NewNotFound("Paciente")
+	}
+
+	tutores, err := s.tutorRepo.GetByPacienteID(ctx, pacienteID)
+	if err != nil {
+		return nil, apperrors.NewInternal("Error al obtener tutores")
+	}
+	if tutores == nil {
+		tutores = []domain.Tutor{}
 	}
-	return s.repo.GetAll(ctx, offset, perPage)
+	return tutores, nil
 }
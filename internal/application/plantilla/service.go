@@ -0,0 +1,104 @@
+package plantilla
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+)
+
+// Service administra las plantillas de notas de evolución.
+type Service struct {
+	repo domain.PlantillaNotaRepository
+}
+
+func NewService(repo domain.PlantillaNotaRepository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) GetAll(ctx context.Context, soloActivas bool) ([]domain.PlantillaNota, error) {
+	plantillas, err := s.repo.GetAll(ctx, soloActivas)
+	if err != nil {
+		return nil, apperrors.NewInternal("Error al obtener plantillas")
+	}
+	if plantillas == nil {
+		plantillas = []domain.PlantillaNota{}
+	}
+	return plantillas, nil
+}
+
+func (s *Service) GetByID(ctx context.Context, id uuid.UUID) (*domain.PlantillaNota, error) {
+	p, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, apperrors.NewNotFound("Plantilla")
+	}
+	return p, nil
+}
+
+func (s *Service) Create(ctx context.Context, nombre, descripcion, tipoNota string, campos []domain.CampoPlantilla, createdBy uuid.UUID) (*domain.PlantillaNota, error) {
+	p := &domain.PlantillaNota{
+		ID:          uuid.New(),
+		Nombre:      strings.TrimSpace(nombre),
+		Descripcion: descripcion,
+		TipoNota:    tipoNota,
+		Campos:      campos,
+		Activa:      true,
+		CreatedBy:   &createdBy,
+	}
+	if err := s.validar(ctx, p, nil); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, p); err != nil {
+		return nil, apperrors.NewInternal("Error al crear la plantilla")
+	}
+	return p, nil
+}
+
+// Update reemplaza la definición de la plantilla. Las notas ya registradas
+// conservan su texto y datos; el cambio solo afecta a las nuevas.
+func (s *Service) Update(ctx context.Context, id uuid.UUID, nombre, descripcion, tipoNota string, campos []domain.CampoPlantilla, activa bool) (*domain.PlantillaNota, error) {
+	p, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, apperrors.NewNotFound("Plantilla")
+	}
+
+	p.Nombre = strings.TrimSpace(nombre)
+	p.Descripcion = descripcion
+	p.TipoNota = tipoNota
+	p.Campos = campos
+	p.Activa = activa
+	if err := s.validar(ctx, p, &id); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, p); err != nil {
+		return nil, apperrors.NewInternal("Error al actualizar la plantilla")
+	}
+	return p, nil
+}
+
+func (s *Service) validar(ctx context.Context, p *domain.PlantillaNota, excludeID *uuid.UUID) error {
+	if p.TipoNota == "" {
+		p.TipoNota = "EVOLUCION"
+	}
+	switch p.TipoNota {
+	case "TRATAMIENTO", "EVOLUCION", "NOTA":
+	default:
+		return apperrors.NewBadRequest("Tipo de nota inválido. Use: TRATAMIENTO, EVOLUCION o NOTA")
+	}
+	if err := p.ValidarDefinicion(); err != nil {
+		return apperrors.NewBadRequest("Plantilla inválida: " + err.Error())
+	}
+
+	exists, err := s.repo.ExistsByNombre(ctx, p.Nombre, excludeID)
+	if err != nil {
+		return apperrors.NewInternal("Error verificando la plantilla")
+	}
+	if exists {
+		return apperrors.NewConflict("Ya existe una plantilla con ese nombre")
+	}
+	return nil
+}
@@ -0,0 +1,391 @@
+package reporte
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/application/historia"
+	"github.com/tunek/centro-caribel/internal/domain"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+	"github.com/tunek/centro-caribel/pkg/pdf"
+)
+
+const formatoFecha = "02/01/2006"
+const formatoFechaHora = "02/01/2006 15:04"
+
+type Service struct {
+	pacienteRepo       domain.PacienteRepository
+	usuarioRepo        domain.UsuarioRepository
+	consentimientoRepo domain.ConsentimientoRepository
+	paqueteRepo        domain.PaqueteRepository
+	historiaSvc        *historia.Service
+}
+
+func NewService(pacienteRepo domain.PacienteRepository, usuarioRepo domain.UsuarioRepository, consentimientoRepo domain.ConsentimientoRepository,
+	paqueteRepo domain.PaqueteRepository, historiaSvc *historia.Service) *Service {
+	return &Service{
+		pacienteRepo:       pacienteRepo,
+		usuarioRepo:        usuarioRepo,
+		consentimientoRepo: consentimientoRepo,
+		paqueteRepo:        paqueteRepo,
+		historiaSvc:        historiaSvc,
+	}
+}
+
+// HistoriaPDF genera la copia de la historia clínica del paciente. Solo se
+// incluyen notas firmadas; los borradores no forman parte del registro. Devuelve
+// el documento y un nombre de archivo sugerido.
+func (s *Service) HistoriaPDF(ctx context.Context, pacienteID, exportadoPor uuid.UUID) ([]byte, string, error) {
+	pac, err := s.pacienteRepo.GetByID(ctx, pacienteID)
+	if err != nil {
+		return nil, "", apperrors.NewNotFound("Paciente")
+	}
+	pac.CalcularEdad()
+
+	exportador, err := s.usuarioRepo.GetByID(ctx, exportadoPor)
+	if err != nil {
+		return nil, "", apperrors.NewUnauthorized("Usuario no identificado")
+	}
+
+	h, err := s.historiaSvc.GetByPacienteID(ctx, pacienteID)
+	if err != nil {
+		return nil, "", err
+	}
+	notas, err := s.historiaSvc.GetNotas(ctx, pacienteID, exportadoPor)
+	if err != nil {
+		return nil, "", err
+	}
+	consentimientos, err := s.consentimientoRepo.GetByPacienteID(ctx, pacienteID)
+	if err != nil {
+		return nil, "", apperrors.NewInternal("Error al obtener consentimientos")
+	}
+	paquetes, err := s.paqueteRepo.GetByPacienteID(ctx, pacienteID)
+	if err != nil {
+		return nil, "", apperrors.NewInternal("Error al obtener paquetes de tratamiento")
+	}
+
+	ahora := time.Now()
+	doc := pdf.New("Historia clínica " + h.NumeroHistoria)
+	doc.Pie = func(pagina, total int) string {
+		return fmt.Sprintf("Historia %s · Exportado por %s el %s · Página %d de %d",
+			h.NumeroHistoria, exportador.NombreCompleto, ahora.Format(formatoFechaHora), pagina, total)
+	}
+
+	doc.Title("Historia clínica " + h.NumeroHistoria)
+	s.escribirPaciente(doc, pac, h)
+	s.escribirAntecedentes(doc, h)
+	s.escribirNotas(ctx, doc, notas)
+	escribirConsentimientos(doc, consentimientos)
+	escribirPaquetes(doc, paquetes)
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		return nil, "", apperrors.NewInternal("Error al generar el PDF")
+	}
+	return buf.Bytes(), fmt.Sprintf("historia-%s.pdf", h.NumeroHistoria), nil
+}
+
+// ConsentimientoPDF genera el documento de un consentimiento para entregar
+// al paciente: texto firmado, datos del paciente, autorización de fotos,
+// imagen de la firma y quién lo registró.
+func (s *Service) ConsentimientoPDF(ctx context.Context, id uuid.UUID) ([]byte, string, error) {
+	c, err := s.consentimientoRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, "", apperrors.NewNotFound("Consentimiento")
+	}
+	pac, err := s.pacienteRepo.GetByID(ctx, c.PacienteID)
+	if err != nil {
+		return nil, "", apperrors.NewNotFound("Paciente")
+	}
+
+	titulo := "Consentimiento informado"
+	if c.Tratamiento != "" {
+		titulo += " — " + c.Tratamiento
+	}
+	doc := pdf.New(titulo)
+	doc.Pie = func(pagina, total int) string {
+		return fmt.Sprintf("Consentimiento %s · %s · Página %d de %d",
+			c.ID, pac.NombreCompleto, pagina, total)
+	}
+
+	doc.Title(titulo)
+	doc.Field("Paciente", pac.NombreCompleto)
+	doc.Field("CI", pac.CI)
+	doc.Field("Código", pac.Codigo)
+	if c.TutorNombre != "" {
+		doc.Field("Firmado por tutor", c.TutorNombre)
+	}
+	doc.Field("Fecha de firma", c.FechaFirma.Format(formatoFechaHora))
+	if c.FechaVencimiento != nil {
+		doc.Field("Vigente hasta", c.FechaVencimiento.Format(formatoFecha))
+	}
+	if c.PlantillaNombre != "" && c.PlantillaVersion != nil {
+		doc.Field("Plantilla", fmt.Sprintf("%s (versión %d)", c.PlantillaNombre, *c.PlantillaVersion))
+	}
+
+	doc.Heading("Texto del consentimiento")
+	doc.Text(c.Contenido)
+
+	doc.Heading("Autorización de fotografías")
+	if c.AutorizaFotos {
+		doc.Text("El paciente SÍ autoriza la toma de fotografías clínicas de la zona tratada.")
+	} else {
+		doc.Text("El paciente NO autoriza la toma de fotografías clínicas.")
+	}
+
+	if len(c.Revocaciones) > 0 {
+		doc.Heading("Revocaciones")
+		for _, r := range c.Revocaciones {
+			alcance := "Revocación total del consentimiento"
+			if r.Alcance == domain.RevocacionFotos {
+				alcance = "Revocación de la autorización de fotografías"
+			}
+			doc.Field(alcance, fmt.Sprintf("%s. Motivo: %s", r.FechaRevocacion.Format(formatoFecha), r.Motivo))
+		}
+	}
+
+	doc.Heading("Firma")
+	escribirFirma(doc, c)
+	firmante := pac.NombreCompleto
+	if c.TutorNombre != "" {
+		firmante = c.TutorNombre + ", en representación de " + pac.NombreCompleto
+	}
+	doc.Small(firmante)
+	nombres := make(map[uuid.UUID]string)
+	doc.Field("Registrado por", s.nombreUsuario(ctx, nombres, &c.RegistradoPor))
+
+	doc.Space(10)
+	doc.Small("Hash SHA-256 del texto: " + c.ContenidoSHA256)
+	if c.Sello != "" {
+		doc.Small("Sello de verificación: " + c.Sello)
+	}
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		return nil, "", apperrors.NewInternal("Error al generar el PDF")
+	}
+	return buf.Bytes(), fmt.Sprintf("consentimiento-%s-%s.pdf", pac.Codigo, c.FechaFirma.Format("20060102")), nil
+}
+
+// escribirFirma dibuja la firma PNG o SVG. Si no puede representarse, lo
+// indica en lugar de omitirla en silencio.
+func escribirFirma(doc *pdf.Document, c *domain.Consentimiento) {
+	const ancho, altoMax = 220, 90
+	// Los consentimientos anteriores al registro de firma_tipo se detectan por contenido.
+	var err error
+	switch {
+	case len(c.FirmaDigital) == 0:
+		err = fmt.Errorf("sin firma")
+	case bytes.HasPrefix(c.FirmaDigital, []byte("\x89PNG")):
+		var img image.Image
+		if img, err = png.Decode(bytes.NewReader(c.FirmaDigital)); err == nil {
+			doc.Image(img, ancho, altoMax)
+		}
+	default:
+		err = doc.SVG(c.FirmaDigital, ancho, altoMax)
+	}
+	if err != nil {
+		doc.Small("(La imagen de la firma no puede representarse en este documento.)")
+	}
+	doc.Space(2)
+}
+
+func (s *Service) escribirPaciente(doc *pdf.Document, pac *domain.Paciente, h *domain.HistoriaClinica) {
+	doc.Heading("Datos del paciente")
+	doc.Field("Nombre", pac.NombreCompleto)
+	doc.Field("Código", pac.Codigo)
+	doc.Field("CI", pac.CI)
+	doc.Field("Fecha de nacimiento", fmt.Sprintf("%s (%d años)", pac.FechaNacimiento.Format(formatoFecha), pac.Edad))
+	doc.Field("Celular", pac.Celular)
+	if pac.Direccion != "" {
+		doc.Field("Dirección", pac.Direccion)
+	}
+	doc.Field("Historia abierta el", h.CreatedAt.Format(formatoFecha))
+	doc.Field("Estado de la historia", string(h.Estado))
+	if h.MotivoEstado != "" {
+		doc.Field("Motivo", h.MotivoEstado)
+	}
+	if pac.Archivado() {
+		doc.Field("Paciente archivado", pac.MotivoArchivo)
+	}
+}
+
+func (s *Service) escribirAntecedentes(doc *pdf.Document, h *domain.HistoriaClinica) {
+	doc.Heading("Antecedentes")
+
+	doc.Subheading("Alergias")
+	if len(h.ListaAlergias) == 0 {
+		textoLibre(doc, h.Alergias, "Sin alergias registradas.")
+	}
+	for _, a := range h.ListaAlergias {
+		linea := fmt.Sprintf("• %s (%s)", a.Sustancia, strings.ToLower(string(a.Severidad)))
+		if a.Reaccion != "" {
+			linea += ": " + a.Reaccion
+		}
+		doc.Indented(linea)
+	}
+
+	doc.Subheading("Medicamentos")
+	if len(h.ListaMedicamentos) == 0 {
+		textoLibre(doc, h.MedicamentosActuales, "Sin medicamentos registrados.")
+	}
+	for _, m := range h.ListaMedicamentos {
+		periodo := "desde " + m.FechaInicio.Format(formatoFecha)
+		if m.FechaFin != nil {
+			periodo += " hasta " + m.FechaFin.Format(formatoFecha)
+		}
+		linea := fmt.Sprintf("• %s %s, %s (%s)", m.Nombre, m.Dosis, m.Frecuencia, periodo)
+		if m.Indicacion != "" {
+			linea += ". Indicación: " + m.Indicacion
+		}
+		doc.Indented(linea)
+	}
+
+	for _, tipo := range []domain.TipoCondicion{domain.CondicionPersonal, domain.CondicionFamiliar} {
+		libre := h.AntecedentesPersonales
+		if tipo == domain.CondicionPersonal {
+			doc.Subheading("Antecedentes personales")
+		} else {
+			doc.Subheading("Antecedentes familiares")
+			libre = h.AntecedentesFamiliares
+		}
+		n := 0
+		for _, c := range h.Condiciones {
+			if c.Tipo != tipo {
+				continue
+			}
+			n++
+			linea := "• " + c.Descripcion
+			if c.Codigo != "" {
+				linea += " [" + c.Codigo + "]"
+			}
+			if c.Parentesco != "" {
+				linea += " — " + c.Parentesco
+			}
+			if c.Observaciones != "" {
+				linea += ". " + c.Observaciones
+			}
+			doc.Indented(linea)
+		}
+		if n == 0 {
+			textoLibre(doc, libre, "Sin registros.")
+		}
+	}
+}
+
+// textoLibre escribe el campo de texto de la historia cuando la lista
+// estructurada está vacía. Las historias anteriores a las listas solo tienen
+// ese texto, y omitirlo haría decir al informe que no hay registros.
+func textoLibre(doc *pdf.Document, texto, vacio string) {
+	if strings.TrimSpace(texto) == "" {
+		doc.Text(vacio)
+		return
+	}
+	doc.Text(texto)
+}
+
+func (s *Service) escribirNotas(ctx context.Context, doc *pdf.Document, notas []domain.NotaEvolucion) {
+	doc.Heading("Notas de evolución")
+
+	// Las notas llegan de la más reciente a la más antigua.
+	firmadas := make([]domain.NotaEvolucion, 0, len(notas))
+	for i := len(notas) - 1; i >= 0; i-- {
+		if notas[i].Firmada() {
+			firmadas = append(firmadas, notas[i])
+		}
+	}
+	if len(firmadas) == 0 {
+		doc.Text("Sin notas firmadas.")
+		return
+	}
+
+	nombres := make(map[uuid.UUID]string)
+	for _, n := range firmadas {
+		doc.Subheading(fmt.Sprintf("%s · %s", fechaNota(&n), n.Tipo))
+		if sesion := n.ResumenSesion(); sesion != "" {
+			doc.Small(sesion)
+		}
+		doc.Text(n.Contenido)
+		doc.Small("Firmada por " + s.nombreUsuario(ctx, nombres, n.FirmadoPor))
+
+		for _, a := range n.Adendas {
+			doc.Space(4)
+			doc.Indented(fmt.Sprintf("Adenda del %s: %s", fechaNota(&a), a.Contenido))
+			doc.Small("Firmada por " + s.nombreUsuario(ctx, nombres, a.FirmadoPor))
+		}
+	}
+}
+
+func escribirConsentimientos(doc *pdf.Document, consentimientos []domain.Consentimiento) {
+	doc.Heading("Consentimientos")
+	if len(consentimientos) == 0 {
+		doc.Text("Sin consentimientos registrados.")
+		return
+	}
+
+	sort.Slice(consentimientos, func(i, j int) bool {
+		return consentimientos[i].FechaFirma.Before(consentimientos[j].FechaFirma)
+	})
+	for _, c := range consentimientos {
+		doc.Subheading("Firmado el " + c.FechaFirma.Format(formatoFechaHora))
+		if c.TutorNombre != "" {
+			doc.Field("Firmado por tutor", c.TutorNombre)
+		}
+		doc.Field("Autoriza fotografías", siNo(c.AutorizaFotos))
+		doc.Text(c.Contenido)
+	}
+}
+
+func escribirPaquetes(doc *pdf.Document, paquetes []domain.PaqueteTratamiento) {
+	doc.Heading("Paquetes de tratamiento")
+	if len(paquetes) == 0 {
+		doc.Text("Sin paquetes registrados.")
+		return
+	}
+
+	for _, p := range paquetes {
+		doc.Subheading(p.TipoTratamiento)
+		doc.Field("Estado", string(p.Estado))
+		doc.Field("Sesiones", fmt.Sprintf("%d de %d completadas", p.SesionesCompletadas, p.TotalSesiones))
+		doc.Field("Inicio", p.CreatedAt.Format(formatoFecha))
+		if p.Notas != "" {
+			doc.Field("Notas", p.Notas)
+		}
+	}
+}
+
+func (s *Service) nombreUsuario(ctx context.Context, cache map[uuid.UUID]string, id *uuid.UUID) string {
+	if id == nil {
+		return "—"
+	}
+	if nombre, ok := cache[*id]; ok {
+		return nombre
+	}
+	nombre := "usuario desconocido"
+	if u, err := s.usuarioRepo.GetByID(ctx, *id); err == nil {
+		nombre = u.NombreCompleto
+	}
+	cache[*id] = nombre
+	return nombre
+}
+
+func fechaNota(n *domain.NotaEvolucion) string {
+	if n.FirmadoAt != nil {
+		return n.FirmadoAt.Format(formatoFechaHora)
+	}
+	return n.CreatedAt.Format(formatoFechaHora)
+}
+
+func siNo(v bool) string {
+	if v {
+		return "Sí"
+	}
+	return "No"
+}
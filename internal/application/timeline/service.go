@@ -0,0 +1,47 @@
+package timeline
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+)
+
+type Service struct {
+	repo         domain.TimelineRepository
+	pacienteRepo domain.PacienteRepository
+}
+
+func NewService(repo domain.TimelineRepository, pacienteRepo domain.PacienteRepository) *Service {
+	return &Service{repo: repo, pacienteRepo: pacienteRepo}
+}
+
+func (s *Service) GetByPacienteID(ctx context.Context, pacienteID uuid.UUID, tipos []domain.TipoEventoTimeline, page, perPage int) ([]domain.EventoTimeline, int64, error) {
+	if _, err := s.pacienteRepo.GetByID(ctx, pacienteID); err != nil {
+		return nil, 0, apperrors.NewNotFound("Paciente")
+	}
+
+	for _, t := range tipos {
+		if !t.IsValid() {
+			return nil, 0, apperrors.NewBadRequest("Tipo de evento inválido: " + string(t) + ". Use: CITA, NOTA, CONSENTIMIENTO o PAQUETE")
+		}
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+	offset := (page - 1) * perPage
+
+	eventos, total, err := s.repo.GetByPacienteID(ctx, pacienteID, tipos, offset, perPage)
+	if err != nil {
+		return nil, 0, apperrors.NewInternal("Error al obtener la línea de tiempo")
+	}
+	if eventos == nil {
+		eventos = []domain.EventoTimeline{}
+	}
+	return eventos, total, nil
+}
@@ -6,19 +6,30 @@ import (
 	"github.com/google/uuid"
 	"github.com/tunek/centro-caribel/internal/domain"
 	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+	"github.com/tunek/centro-caribel/pkg/password"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// InvalidadorSesiones descarta la versión de tokens cacheada de un usuario;
+// lo implementa auth.Sesiones.
+type InvalidadorSesiones interface {
+	Invalidar(userID uuid.UUID)
+}
+
 type Service struct {
-	repo    domain.UsuarioRepository
-	rolRepo domain.RolRepository
+	repo             domain.UsuarioRepository
+	rolRepo          domain.RolRepository
+	sesiones         InvalidadorSesiones
+	politicaPassword password.Policy
 }
 
-func NewService(repo domain.UsuarioRepository, rolRepo domain.RolRepository) *Service {
-	return &Service{repo: repo, rolRepo: rolRepo}
+func NewService(repo domain.UsuarioRepository, rolRepo domain.RolRepository, sesiones InvalidadorSesiones, politicaPassword password.Policy) *Service {
+	return &Service{repo: repo, rolRepo: rolRepo, sesiones: sesiones, politicaPassword: politicaPassword}
 }
 
-func (s *Service) Create(ctx context.Context, nombreCompleto, email, password string, rolID uuid.UUID) (*domain.Usuario, error) {
+// Create registra un usuario con una contraseña inicial elegida por la
+// Administradora; el usuario debe cambiarla en su primer login.
+func (s *Service) Create(ctx context.Context, nombreCompleto, email, pass string, rolID uuid.UUID) (*domain.Usuario, error) {
 	if _, err := s.rolRepo.GetByID(ctx, rolID); err != nil {
 		return nil, apperrors.NewBadRequest("El rol especificado no existe")
 	}
@@ -27,7 +38,11 @@ func (s *Service) Create(ctx context.Context, nombreCompleto, email, password st
 		return nil, apperrors.NewConflict("Ya existe un usuario con ese email")
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err := s.politicaPassword.Validate(pass); err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, apperrors.NewInternal("Error al procesar la contraseña")
 	}
@@ -39,6 +54,8 @@ func (s *Service) Create(ctx context.Context, nombreCompleto, email, password st
 		PasswordHash:   string(hash),
 		RolID:          rolID,
 		Activo:         true,
+		// La contraseña inicial la conoce la Administradora.
+		DebeCambiarPassword: true,
 	}
 
 	if err := s.repo.Create(ctx, user); err != nil {
@@ -78,6 +95,7 @@ func (s *Service) Update(ctx context.Context, id uuid.UUID, nombreCompleto, emai
 	if err != nil {
 		return nil, apperrors.NewNotFound("Usuario")
 	}
+	rolAnterior, activoAnterior := user.RolID, user.Activo
 
 	if nombreCompleto != "" {
 		user.NombreCompleto = nombreCompleto
@@ -98,9 +116,16 @@ func (s *Service) Update(ctx context.Context, id uuid.UUID, nombreCompleto, emai
 		user.Activo = *activo
 	}
 
-	if err := s.repo.Update(ctx, user); err != nil {
+	// Un cambio de rol o de estado invalida los access tokens emitidos: llevan
+	// el rol anterior y no se vuelven a comprobar hasta que vencen.
+	revocar := user.RolID != rolAnterior || user.Activo != activoAnterior
+
+	if err := s.repo.Update(ctx, user, revocar); err != nil {
 		return nil, apperrors.NewInternal("Error al actualizar el usuario")
 	}
+	if revocar {
+		s.sesiones.Invalidar(user.ID)
+	}
 
 	return user, nil
 }
@@ -109,5 +134,21 @@ func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
 	if _, err := s.repo.GetByID(ctx, id); err != nil {
 		return apperrors.NewNotFound("Usuario")
 	}
-	return s.repo.Delete(ctx, id)
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return apperrors.NewInternal("Error al eliminar el usuario")
+	}
+	s.sesiones.Invalidar(id)
+	return nil
+}
+
+// Desbloquear quita el bloqueo por intentos de login fallidos y reinicia la
+// demora progresiva de la cuenta.
+func (s *Service) Desbloquear(ctx context.Context, id uuid.UUID) (*domain.Usuario, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, apperrors.NewNotFound("Usuario")
+	}
+	if err := s.repo.Desbloquear(ctx, id); err != nil {
+		return nil, apperrors.NewInternal("Error al desbloquear el usuario")
+	}
+	return s.GetByID(ctx, id)
 }
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VisibilidadAdjunto restringe qué roles pueden ver un adjunto.
+type VisibilidadAdjunto string
+
+const (
+	AdjuntoGeneral VisibilidadAdjunto = "GENERAL"
+	AdjuntoClinico VisibilidadAdjunto = "CLINICO"
+	AdjuntoAdmin   VisibilidadAdjunto = "ADMIN"
+)
+
+func (v VisibilidadAdjunto) IsValid() bool {
+	return v == AdjuntoGeneral || v == AdjuntoClinico || v == AdjuntoAdmin
+}
+
+// VisibilidadesPara devuelve los niveles de visibilidad accesibles para un rol.
+func VisibilidadesPara(rolNombre string) []VisibilidadAdjunto {
+	switch rolNombre {
+	case "Administradora":
+		return []VisibilidadAdjunto{AdjuntoGeneral, AdjuntoClinico, AdjuntoAdmin}
+	case "Licenciada", "Medico":
+		return []VisibilidadAdjunto{AdjuntoGeneral, AdjuntoClinico}
+	default:
+		return []VisibilidadAdjunto{AdjuntoGeneral}
+	}
+}
+
+// VisiblePara indica si el rol puede acceder a un adjunto con esta visibilidad.
+func (v VisibilidadAdjunto) VisiblePara(rolNombre string) bool {
+	for _, permitida := range VisibilidadesPara(rolNombre) {
+		if v == permitida {
+			return true
+		}
+	}
+	return false
+}
+
+type Adjunto struct {
+	ID              uuid.UUID          `json:"id"`
+	PacienteID      uuid.UUID          `json:"paciente_id"`
+	NotaID          *uuid.UUID         `json:"nota_id,omitempty"`
+	Descripcion     string             `json:"descripcion,omitempty"`
+	Visibilidad     VisibilidadAdjunto `json:"visibilidad"`
+	NombreArchivo   string             `json:"nombre_archivo"`
+	ContentType     string             `json:"content_type"`
+	TamanoBytes     int64              `json:"tamano_bytes"`
+	SHA256          string             `json:"sha256"`
+	StorageKey      string             `json:"-"`
+	SubidoPor       uuid.UUID          `json:"subido_por"`
+	SubidoPorNombre string             `json:"subido_por_nombre,omitempty"`
+	CreatedAt       time.Time          `json:"created_at"`
+}
+
+type AdjuntoRepository interface {
+	Create(ctx context.Context, a *Adjunto) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Adjunto, error)
+	// GetByPacienteID devuelve solo los adjuntos con alguna de las visibilidades
+	// indicadas; notaID opcional filtra por nota de evolución.
+	GetByPacienteID(ctx context.Context, pacienteID uuid.UUID, notaID *uuid.UUID, visibilidades []VisibilidadAdjunto) ([]Adjunto, error)
+	GetByChecksum(ctx context.Context, pacienteID uuid.UUID, sha256 string) (*Adjunto, error)
+	// CountByStorageKey cuenta los adjuntos que comparten el mismo archivo almacenado.
+	CountByStorageKey(ctx context.Context, storageKey string) (int, error)
+	// BloquearArchivo toma, hasta el fin de la transacción de ctx, un bloqueo
+	// sobre storageKey, para contar sus adjuntos y guardar o borrar el archivo
+	// sin que otra subida o borrado intervenga. Fuera de una transacción no
+	// bloquea nada.
+	BloquearArchivo(ctx context.Context, storageKey string) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
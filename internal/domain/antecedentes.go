@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SeveridadAlergia string
+
+const (
+	SeveridadLeve     SeveridadAlergia = "LEVE"
+	SeveridadModerada SeveridadAlergia = "MODERADA"
+	SeveridadSevera   SeveridadAlergia = "SEVERA"
+)
+
+func (s SeveridadAlergia) IsValid() bool {
+	switch s {
+	case SeveridadLeve, SeveridadModerada, SeveridadSevera:
+		return true
+	}
+	return false
+}
+
+type Alergia struct {
+	ID         uuid.UUID        `json:"id"`
+	HistoriaID uuid.UUID        `json:"historia_id"`
+	Sustancia  string           `json:"sustancia"`
+	Reaccion   string           `json:"reaccion,omitempty"`
+	Severidad  SeveridadAlergia `json:"severidad"`
+	CreatedBy  uuid.UUID        `json:"created_by"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+}
+
+type Medicamento struct {
+	ID          uuid.UUID  `json:"id"`
+	HistoriaID  uuid.UUID  `json:"historia_id"`
+	Nombre      string     `json:"nombre"`
+	Dosis       string     `json:"dosis"`
+	Frecuencia  string     `json:"frecuencia"`
+	FechaInicio time.Time  `json:"fecha_inicio"`
+	FechaFin    *time.Time `json:"fecha_fin,omitempty"`
+	Indicacion  string     `json:"indicacion,omitempty"`
+	Activo      bool       `json:"activo"`
+	CreatedBy   uuid.UUID  `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ActivoEn indica si el medicamento está vigente en la fecha ref.
+func (m *Medicamento) ActivoEn(ref time.Time) bool {
+	dia := time.Date(ref.Year(), ref.Month(), ref.Day(), 0, 0, 0, 0, time.UTC)
+	if m.FechaInicio.After(dia) {
+		return false
+	}
+	return m.FechaFin == nil || !m.FechaFin.Before(dia)
+}
+
+type TipoCondicion string
+
+const (
+	CondicionPersonal TipoCondicion = "PERSONAL"
+	CondicionFamiliar TipoCondicion = "FAMILIAR"
+)
+
+func (t TipoCondicion) IsValid() bool {
+	return t == CondicionPersonal || t == CondicionFamiliar
+}
+
+// Condicion es un antecedente patológico personal o familiar, opcionalmente
+// codificado (CIE-10).
+type Condicion struct {
+	ID            uuid.UUID     `json:"id"`
+	HistoriaID    uuid.UUID     `json:"historia_id"`
+	Tipo          TipoCondicion `json:"tipo"`
+	Codigo        string        `json:"codigo,omitempty"`
+	Descripcion   string        `json:"descripcion"`
+	Parentesco    string        `json:"parentesco,omitempty"`
+	Observaciones string        `json:"observaciones,omitempty"`
+	CreatedBy     uuid.UUID     `json:"created_by"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+type AlergiaRepository interface {
+	Create(ctx context.Context, a *Alergia) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Alergia, error)
+	GetByHistoriaID(ctx context.Context, historiaID uuid.UUID) ([]Alergia, error)
+	Update(ctx context.Context, a *Alergia) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type MedicamentoRepository interface {
+	Create(ctx context.Context, m *Medicamento) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Medicamento, error)
+	GetByHistoriaID(ctx context.Context, historiaID uuid.UUID) ([]Medicamento, error)
+	Update(ctx context.Context, m *Medicamento) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type CondicionRepository interface {
+	Create(ctx context.Context, c *Condicion) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Condicion, error)
+	GetByHistoriaID(ctx context.Context, historiaID uuid.UUID) ([]Condicion, error)
+	Update(ctx context.Context, c *Condicion) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
@@ -74,6 +74,9 @@ type Cita struct {
 	CreatedBy       uuid.UUID  `json:"created_by"`
 	CreatedAt       time.Time  `json:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at"`
+
+	// Advertencias se informan al crear la cita y no se guardan.
+	Advertencias []string `json:"advertencias,omitempty"`
 }
 
 type CitaRepository interface {
@@ -82,8 +85,8 @@ type CitaRepository interface {
 	GetAll(ctx context.Context, offset, limit int) ([]Cita, int64, error)
 	GetByPacienteID(ctx context.Context, pacienteID uuid.UUID) ([]Cita, error)
 	GetByFecha(ctx context.Context, fecha time.Time) ([]Cita, error)
-	UpdateEstado(ctx context.Context, id uuid.UUID, estado EstadoCita) error
-	Reagendar(ctx context.Context, id uuid.UUID, fecha time.Time, hora string, turno TurnoCita) error
+	UpdateEstado(ctx context.Context, id uuid.UUID, estado EstadoCita, cambiadoPor uuid.UUID) error
+	Reagendar(ctx context.Context, id uuid.UUID, fecha time.Time, hora string, turno TurnoCita, cambiadoPor uuid.UUID) error
 	GetAllFiltered(ctx context.Context, offset, limit int, fecha *time.Time, turno *TurnoCita, estado *EstadoCita) ([]Cita, int64, error)
 	ExistsByFechaHora(ctx context.Context, fecha time.Time, hora string, excludeID *uuid.UUID) (bool, error)
 }
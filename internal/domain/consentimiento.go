@@ -2,24 +2,188 @@ package domain
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type Consentimiento struct {
-	ID             uuid.UUID `json:"id"`
-	PacienteID     uuid.UUID `json:"paciente_id"`
-	FechaFirma     time.Time `json:"fecha_firma"`
-	FirmaDigital   []byte    `json:"firma_digital,omitempty"`
-	AutorizaFotos  bool      `json:"autoriza_fotos"`
-	Contenido      string    `json:"contenido"`
-	RegistradoPor  uuid.UUID `json:"registrado_por"`
-	CreatedAt      time.Time `json:"created_at"`
+	ID            uuid.UUID `json:"id"`
+	PacienteID    uuid.UUID `json:"paciente_id"`
+	FechaFirma    time.Time `json:"fecha_firma"`
+	FirmaDigital  []byte    `json:"firma_digital,omitempty"`
+	FirmaTipo     string    `json:"firma_tipo,omitempty"` // image/png o image/svg+xml
+	AutorizaFotos bool      `json:"autoriza_fotos"`
+	Contenido     string    `json:"contenido"`
+	// ContenidoSHA256 es el hash del texto firmado, para probar que no cambió.
+	ContenidoSHA256    string     `json:"contenido_sha256"`
+	PlantillaVersionID *uuid.UUID `json:"plantilla_version_id,omitempty"`
+	PlantillaNombre    string     `json:"plantilla_nombre,omitempty"`
+	PlantillaVersion   *int       `json:"plantilla_version,omitempty"`
+	Tratamiento        string     `json:"tratamiento"`
+	FechaVencimiento   *time.Time `json:"fecha_vencimiento,omitempty"`
+	// Sello es un HMAC-SHA256 sobre firma, contenido, paciente y fecha de
+	// firma; desde la versión 2, también sobre tratamiento y vencimiento.
+	Sello         string     `json:"sello,omitempty"`
+	SelloVersion  int        `json:"sello_version,omitempty"`
+	TutorID       *uuid.UUID `json:"tutor_id,omitempty"`
+	TutorNombre   string     `json:"tutor_nombre,omitempty"`
+	RegistradoPor uuid.UUID  `json:"registrado_por"`
+	CreatedAt     time.Time  `json:"created_at"`
+
+	Revocaciones []RevocacionConsentimiento `json:"revocaciones,omitempty"`
+	// Estado se calcula al consultar a partir del vencimiento y las revocaciones.
+	Estado EstadoVigencia `json:"estado,omitempty"`
+}
+
+type EstadoVigencia string
+
+const (
+	ConsentimientoVigente  EstadoVigencia = "VIGENTE"
+	ConsentimientoVencido  EstadoVigencia = "VENCIDO"
+	ConsentimientoRevocado EstadoVigencia = "REVOCADO"
+	SinConsentimiento      EstadoVigencia = "SIN_CONSENTIMIENTO"
+)
+
+// AlcanceRevocacion indica si se retira todo el consentimiento o solo la
+// autorización de fotografías.
+type AlcanceRevocacion string
+
+const (
+	RevocacionTotal AlcanceRevocacion = "TOTAL"
+	RevocacionFotos AlcanceRevocacion = "FOTOS"
+)
+
+func (a AlcanceRevocacion) IsValid() bool {
+	return a == RevocacionTotal || a == RevocacionFotos
+}
+
+type RevocacionConsentimiento struct {
+	ID                  uuid.UUID         `json:"id"`
+	ConsentimientoID    uuid.UUID         `json:"consentimiento_id"`
+	Alcance             AlcanceRevocacion `json:"alcance"`
+	FechaRevocacion     time.Time         `json:"fecha_revocacion"`
+	Motivo              string            `json:"motivo"`
+	RegistradoPor       uuid.UUID         `json:"registrado_por"`
+	RegistradoPorNombre string            `json:"registrado_por_nombre,omitempty"`
+	CreatedAt           time.Time         `json:"created_at"`
+}
+
+func (c *Consentimiento) revocadoEn(alcance AlcanceRevocacion, ref time.Time) bool {
+	for _, r := range c.Revocaciones {
+		if r.Alcance == alcance && !r.FechaRevocacion.After(ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// EstadoEn devuelve la vigencia del consentimiento en la fecha ref.
+func (c *Consentimiento) EstadoEn(ref time.Time) EstadoVigencia {
+	if c.revocadoEn(RevocacionTotal, ref) {
+		return ConsentimientoRevocado
+	}
+	if c.FechaVencimiento != nil && ref.After(*c.FechaVencimiento) {
+		return ConsentimientoVencido
+	}
+	return ConsentimientoVigente
+}
+
+// AutorizaFotosEn indica si en la fecha ref el consentimiento está vigente y
+// su autorización de fotografías no fue retirada.
+func (c *Consentimiento) AutorizaFotosEn(ref time.Time) bool {
+	return c.AutorizaFotos && c.EstadoEn(ref) == ConsentimientoVigente && !c.revocadoEn(RevocacionFotos, ref)
+}
+
+// ConsentimientoFotos devuelve el consentimiento que decide si en ref se pueden
+// tomar fotos: el más reciente firmado hasta ref, cualquiera sea su estado
+// (consentimientos va del más reciente al más antiguo). Si tratamiento no está
+// vacío, solo cuentan los de ese tratamiento y los generales, sin tratamiento.
+// Si ese consentimiento está revocado, vencido o no autoriza fotos, no se
+// recurre a uno anterior.
+func ConsentimientoFotos(consentimientos []Consentimiento, tratamiento string, ref time.Time) *Consentimiento {
+	tratamiento = strings.TrimSpace(tratamiento)
+	for i := range consentimientos {
+		c := &consentimientos[i]
+		if c.FechaFirma.After(ref) {
+			continue
+		}
+		propio := strings.TrimSpace(c.Tratamiento)
+		if tratamiento != "" && propio != "" && !strings.EqualFold(propio, tratamiento) {
+			continue
+		}
+		return c
+	}
+	return nil
+}
+
+// EstadoConsentimientoTratamiento resume la situación del paciente para un
+// tratamiento: el consentimiento que lo cubre (si hay) y su vigencia.
+type EstadoConsentimientoTratamiento struct {
+	Tratamiento    string          `json:"tratamiento"`
+	Requerido      bool            `json:"requerido"`
+	Estado         EstadoVigencia  `json:"estado"`
+	AutorizaFotos  bool            `json:"autoriza_fotos"`
+	Consentimiento *Consentimiento `json:"consentimiento,omitempty"`
+}
+
+// SelloVersionActual es la versión del sello de los consentimientos nuevos.
+const SelloVersionActual = 2
+
+// CalcularSello devuelve el HMAC-SHA256 (hex) que vincula la firma, el texto
+// firmado, el paciente, el tutor y la fecha de firma, y en la versión 2 además
+// el tratamiento y el vencimiento. Cualquier cambio en esos datos invalida el
+// sello. Devuelve "" si la versión es desconocida.
+func (c *Consentimiento) CalcularSello(clave []byte) string {
+	firma := sha256.Sum256(c.FirmaDigital)
+	tutor := ""
+	if c.TutorID != nil {
+		tutor = c.TutorID.String()
+	}
+	version := ""
+	if c.PlantillaVersionID != nil {
+		version = c.PlantillaVersionID.String()
+	}
+
+	mac := hmac.New(sha256.New, clave)
+	switch c.SelloVersion {
+	case 1:
+		fmt.Fprintf(mac, "consentimiento/v1\n%s\n%s\n%s\n%s\n%t\n%s\n%s\n%s",
+			c.ID, c.PacienteID, tutor, c.FechaFirma.UTC().Format(time.RFC3339Nano), c.AutorizaFotos,
+			version, HashContenido(c.Contenido), hex.EncodeToString(firma[:]))
+	case 2:
+		vencimiento := ""
+		if c.FechaVencimiento != nil {
+			vencimiento = c.FechaVencimiento.UTC().Format(time.RFC3339Nano)
+		}
+		// El tratamiento va como hash para que no pueda contener separadores.
+		fmt.Fprintf(mac, "consentimiento/v2\n%s\n%s\n%s\n%s\n%t\n%s\n%s\n%s\n%s\n%s",
+			c.ID, c.PacienteID, tutor, c.FechaFirma.UTC().Format(time.RFC3339Nano), c.AutorizaFotos,
+			version, HashContenido(c.Contenido), hex.EncodeToString(firma[:]),
+			HashContenido(c.Tratamiento), vencimiento)
+	default:
+		return ""
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerificarSello compara en tiempo constante el sello guardado con el recalculado.
+func (c *Consentimiento) VerificarSello(clave []byte) bool {
+	calculado := c.CalcularSello(clave)
+	if c.Sello == "" || calculado == "" {
+		return false
+	}
+	return hmac.Equal([]byte(c.Sello), []byte(calculado))
 }
 
 type ConsentimientoRepository interface {
 	Create(ctx context.Context, c *Consentimiento) error
 	GetByID(ctx context.Context, id uuid.UUID) (*Consentimiento, error)
 	GetByPacienteID(ctx context.Context, pacienteID uuid.UUID) ([]Consentimiento, error)
+	Revocar(ctx context.Context, r *RevocacionConsentimiento) error
 }
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type MomentoFoto string
+
+const (
+	FotoAntes       MomentoFoto = "ANTES"
+	FotoDespues     MomentoFoto = "DESPUES"
+	FotoSeguimiento MomentoFoto = "SEGUIMIENTO"
+)
+
+func (m MomentoFoto) IsValid() bool {
+	return m == FotoAntes || m == FotoDespues || m == FotoSeguimiento
+}
+
+type Foto struct {
+	ID               uuid.UUID   `json:"id"`
+	PacienteID       uuid.UUID   `json:"paciente_id"`
+	ConsentimientoID uuid.UUID   `json:"consentimiento_id"`
+	CitaID           *uuid.UUID  `json:"cita_id,omitempty"`
+	PaqueteID        *uuid.UUID  `json:"paquete_id,omitempty"`
+	Zona             string      `json:"zona"`
+	Momento          MomentoFoto `json:"momento"`
+	Descripcion      string      `json:"descripcion,omitempty"`
+	StorageKey       string      `json:"-"`
+	NombreArchivo    string      `json:"nombre_archivo"`
+	ContentType      string      `json:"content_type"`
+	TamanoBytes      int64       `json:"tamano_bytes"`
+	TomadaEn         time.Time   `json:"tomada_en"`
+	TomadaPor        uuid.UUID   `json:"tomada_por"`
+	TomadaPorNombre  string      `json:"tomada_por_nombre,omitempty"`
+	CreatedAt        time.Time   `json:"created_at"`
+}
+
+// ComparacionFotos empareja la primera foto "antes" con la última "después"
+// de una misma zona.
+type ComparacionFotos struct {
+	Zona    string `json:"zona"`
+	Antes   *Foto  `json:"antes"`
+	Despues *Foto  `json:"despues"`
+}
+
+type FotoRepository interface {
+	Create(ctx context.Context, f *Foto) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Foto, error)
+	// GetByPacienteID filtra opcionalmente por zona y paquete; ordena por tomada_en.
+	GetByPacienteID(ctx context.Context, pacienteID uuid.UUID, zona string, paqueteID *uuid.UUID) ([]Foto, error)
+}
@@ -2,41 +2,131 @@ package domain
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type EstadoHistoria string
+
+const (
+	HistoriaActiva    EstadoHistoria = "ACTIVA"
+	HistoriaCerrada   EstadoHistoria = "CERRADA"
+	HistoriaArchivada EstadoHistoria = "ARCHIVADA"
+)
+
+func (e EstadoHistoria) IsValid() bool {
+	return e == HistoriaActiva || e == HistoriaCerrada || e == HistoriaArchivada
+}
+
 type HistoriaClinica struct {
-	ID                      uuid.UUID `json:"id"`
-	PacienteID              uuid.UUID `json:"paciente_id"`
-	NumeroHistoria          string    `json:"numero_historia"`
-	Estado                  string    `json:"estado"`
-	AntecedentesPersonales  string    `json:"antecedentes_personales"`
-	AntecedentesFamiliares  string    `json:"antecedentes_familiares"`
-	Alergias                string    `json:"alergias"`
-	MedicamentosActuales    string    `json:"medicamentos_actuales"`
-	CreatedAt               time.Time `json:"created_at"`
-	UpdatedAt               time.Time `json:"updated_at"`
+	ID                     uuid.UUID      `json:"id"`
+	PacienteID             uuid.UUID      `json:"paciente_id"`
+	NumeroHistoria         string         `json:"numero_historia"`
+	Estado                 EstadoHistoria `json:"estado"`
+	MotivoEstado           string         `json:"motivo_estado,omitempty"`
+	EstadoCambiadoPor      *uuid.UUID     `json:"estado_cambiado_por,omitempty"`
+	EstadoCambiadoAt       *time.Time     `json:"estado_cambiado_at,omitempty"`
+	AntecedentesPersonales string         `json:"antecedentes_personales"`
+	AntecedentesFamiliares string         `json:"antecedentes_familiares"`
+	Alergias               string         `json:"alergias"`
+	MedicamentosActuales   string         `json:"medicamentos_actuales"`
+	ListaAlergias          []Alergia      `json:"lista_alergias"`
+	ListaMedicamentos      []Medicamento  `json:"lista_medicamentos"`
+	Condiciones            []Condicion    `json:"condiciones"`
+	CreatedAt              time.Time      `json:"created_at"`
+	UpdatedAt              time.Time      `json:"updated_at"`
+}
+
+// Abierta indica si la historia admite nuevas notas y cambios de antecedentes.
+func (h *HistoriaClinica) Abierta() bool {
+	return h.Estado == HistoriaActiva
+}
+
+// VersionAntecedentes es una copia de los antecedentes tras cada actualización.
+// La versión 1 corresponde al estado inicial de la historia.
+type VersionAntecedentes struct {
+	ID                     uuid.UUID  `json:"id"`
+	HistoriaID             uuid.UUID  `json:"historia_id"`
+	Version                int        `json:"version"`
+	AntecedentesPersonales string     `json:"antecedentes_personales"`
+	AntecedentesFamiliares string     `json:"antecedentes_familiares"`
+	Alergias               string     `json:"alergias"`
+	MedicamentosActuales   string     `json:"medicamentos_actuales"`
+	AutorID                *uuid.UUID `json:"autor_id"`
+	AutorNombre            string     `json:"autor_nombre,omitempty"`
+	CreatedAt              time.Time  `json:"created_at"`
 }
 
+type EstadoNota string
+
+const (
+	NotaBorrador EstadoNota = "BORRADOR"
+	NotaFirmada  EstadoNota = "FIRMADA"
+)
+
+// NotaEvolucion se crea como borrador y se vuelve inmutable al firmarse. Las
+// correcciones posteriores son adendas que apuntan a la nota original.
 type NotaEvolucion struct {
-	ID        uuid.UUID `json:"id"`
-	HistoriaID uuid.UUID `json:"historia_id"`
-	Tipo      string    `json:"tipo"` // TRATAMIENTO, EVOLUCION, NOTA
-	Contenido string    `json:"contenido"`
-	CreatedBy uuid.UUID `json:"created_by"`
-	CreatedAt time.Time `json:"created_at"`
+	ID             uuid.UUID       `json:"id"`
+	HistoriaID     uuid.UUID       `json:"historia_id"`
+	Tipo           string          `json:"tipo"` // TRATAMIENTO, EVOLUCION, NOTA
+	Contenido      string          `json:"contenido"`
+	Estado         EstadoNota      `json:"estado"`
+	FirmadoPor     *uuid.UUID      `json:"firmado_por,omitempty"`
+	FirmadoAt      *time.Time      `json:"firmado_at,omitempty"`
+	NotaOriginalID *uuid.UUID      `json:"nota_original_id,omitempty"`
+	CitaID         *uuid.UUID      `json:"cita_id,omitempty"`
+	PaqueteID      *uuid.UUID      `json:"paquete_id,omitempty"`
+	NumeroSesion   *int            `json:"numero_sesion,omitempty"`
+	TotalSesiones  *int            `json:"total_sesiones,omitempty"`
+	Sesion         string          `json:"sesion,omitempty"` // "Sesión 4 de 10"
+	PlantillaID    *uuid.UUID      `json:"plantilla_id,omitempty"`
+	Datos          json.RawMessage `json:"datos,omitempty"` // campos de la plantilla; Contenido guarda su texto
+	Adendas        []NotaEvolucion `json:"adendas,omitempty"`
+	CreatedBy      uuid.UUID       `json:"created_by"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+func (n *NotaEvolucion) Firmada() bool {
+	return n.Estado == NotaFirmada
+}
+
+func (n *NotaEvolucion) EsAdenda() bool {
+	return n.NotaOriginalID != nil
+}
+
+// ResumenSesion arma el texto "Sesión N de M" cuando la nota documenta una
+// cita de un paquete de tratamiento.
+func (n *NotaEvolucion) ResumenSesion() string {
+	if n.NumeroSesion == nil || n.TotalSesiones == nil {
+		return ""
+	}
+	return fmt.Sprintf("Sesión %d de %d", *n.NumeroSesion, *n.TotalSesiones)
 }
 
 type HistoriaClinicaRepository interface {
 	Create(ctx context.Context, h *HistoriaClinica) error
 	GetByPacienteID(ctx context.Context, pacienteID uuid.UUID) (*HistoriaClinica, error)
-	UpdateAntecedentes(ctx context.Context, h *HistoriaClinica) error
+	UpdateAntecedentes(ctx context.Context, h *HistoriaClinica, autorID uuid.UUID) error
+	GetVersiones(ctx context.Context, historiaID uuid.UUID) ([]VersionAntecedentes, error)
+	GetVersion(ctx context.Context, historiaID uuid.UUID, version int) (*VersionAntecedentes, error)
+	CambiarEstado(ctx context.Context, id uuid.UUID, estado EstadoHistoria, motivo string, cambiadoPor uuid.UUID) error
 	NextNumero(ctx context.Context) (string, error)
 }
 
 type NotaEvolucionRepository interface {
 	Create(ctx context.Context, n *NotaEvolucion) error
+	GetByID(ctx context.Context, id uuid.UUID) (*NotaEvolucion, error)
 	GetByHistoriaID(ctx context.Context, historiaID uuid.UUID) ([]NotaEvolucion, error)
+	// Update y Firmar solo afectan borradores; devuelven sql.ErrNoRows si la nota ya está firmada.
+	Update(ctx context.Context, n *NotaEvolucion) error
+	Firmar(ctx context.Context, id, firmadoPor uuid.UUID) error
+	// Descartar elimina un borrador, desvincula sus adjuntos y guarda una copia
+	// en el registro de notas descartadas con quién lo descartó, cuándo y el
+	// motivo; devuelve sql.ErrNoRows si la nota ya está firmada.
+	Descartar(ctx context.Context, id, descartadaPor uuid.UUID, motivo string) error
 }
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type MotivoIntentoLogin string
+
+const (
+	LoginCredenciales   MotivoIntentoLogin = "CREDENCIALES"
+	LoginCodigo2FA      MotivoIntentoLogin = "CODIGO_2FA"
+	LoginPasswordActual MotivoIntentoLogin = "PASSWORD_ACTUAL"
+	LoginDesactivado    MotivoIntentoLogin = "DESACTIVADO"
+	LoginBloqueado      MotivoIntentoLogin = "BLOQUEADO"
+	LoginDemora         MotivoIntentoLogin = "DEMORA"
+	LoginLimiteIP       MotivoIntentoLogin = "LIMITE_IP"
+)
+
+// IntentoLogin registra un intento de login. Motivo explica por qué se
+// rechazó; es nil en los exitosos.
+type IntentoLogin struct {
+	ID        uuid.UUID           `json:"id"`
+	Email     string              `json:"email"`
+	UsuarioID *uuid.UUID          `json:"usuario_id,omitempty"`
+	Exitoso   bool                `json:"exitoso"`
+	Motivo    *MotivoIntentoLogin `json:"motivo,omitempty"`
+	IP        string              `json:"ip"`
+	UserAgent string              `json:"user_agent"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+// FiltroIntentosLogin restringe el listado de intentos; los campos nil no filtran.
+type FiltroIntentosLogin struct {
+	Email     *string
+	UsuarioID *uuid.UUID
+	IP        *string
+	Exitoso   *bool
+	Desde     *time.Time
+	Hasta     *time.Time
+}
+
+type IntentoLoginRepository interface {
+	Create(ctx context.Context, i *IntentoLogin) error
+	GetAll(ctx context.Context, filtro FiltroIntentosLogin, offset, limit int) ([]IntentoLogin, int64, error)
+	// CountFallidosPorIP cuenta los intentos con credenciales, código 2FA o
+	// contraseña actual inválidos desde ip posteriores a desde.
+	CountFallidosPorIP(ctx context.Context, ip string, desde time.Time) (int, error)
+	// FallidosSinUsuario cuenta los intentos con credenciales inválidas para
+	// un email que no corresponde a ningún usuario, posteriores a desde, y
+	// devuelve la fecha del último.
+	FallidosSinUsuario(ctx context.Context, email string, desde time.Time) (int, *time.Time, error)
+}
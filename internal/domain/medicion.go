@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Medicion agrupa las medidas corporales y signos vitales tomados en una
+// sesión. Todos los valores son opcionales; el IMC se calcula a partir del
+// peso y la talla.
+type Medicion struct {
+	ID                 uuid.UUID  `json:"id"`
+	HistoriaID         uuid.UUID  `json:"historia_id"`
+	CitaID             *uuid.UUID `json:"cita_id,omitempty"`
+	PaqueteID          *uuid.UUID `json:"paquete_id,omitempty"`
+	Fecha              time.Time  `json:"fecha"`
+	PesoKg             *float64   `json:"peso_kg,omitempty"`
+	TallaCm            *float64   `json:"talla_cm,omitempty"`
+	IMC                *float64   `json:"imc,omitempty"`
+	CinturaCm          *float64   `json:"cintura_cm,omitempty"`
+	CaderaCm           *float64   `json:"cadera_cm,omitempty"`
+	AbdomenCm          *float64   `json:"abdomen_cm,omitempty"`
+	PresionSistolica   *int       `json:"presion_sistolica,omitempty"`
+	PresionDiastolica  *int       `json:"presion_diastolica,omitempty"`
+	FrecuenciaCardiaca *int       `json:"frecuencia_cardiaca,omitempty"`
+	EscalaDolor        *int       `json:"escala_dolor,omitempty"` // 0 a 10
+	Observaciones      string     `json:"observaciones,omitempty"`
+	CreatedBy          uuid.UUID  `json:"created_by"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// Rango de IMC que se acepta como plausible. Un valor fuera de él indica un
+// error de carga en el peso o la talla.
+const (
+	IMCMinimo = 5
+	IMCMaximo = 200
+)
+
+// CalcularIMC devuelve peso / talla² (kg/m²) redondeado a dos decimales.
+func CalcularIMC(pesoKg, tallaCm float64) float64 {
+	m := tallaCm / 100
+	return math.Round(pesoKg/(m*m)*100) / 100
+}
+
+type MetricaMedicion string
+
+const (
+	MetricaPeso               MetricaMedicion = "peso"
+	MetricaTalla              MetricaMedicion = "talla"
+	MetricaIMC                MetricaMedicion = "imc"
+	MetricaCintura            MetricaMedicion = "cintura"
+	MetricaCadera             MetricaMedicion = "cadera"
+	MetricaAbdomen            MetricaMedicion = "abdomen"
+	MetricaPresionSistolica   MetricaMedicion = "presion_sistolica"
+	MetricaPresionDiastolica  MetricaMedicion = "presion_diastolica"
+	MetricaFrecuenciaCardiaca MetricaMedicion = "frecuencia_cardiaca"
+	MetricaDolor              MetricaMedicion = "dolor"
+)
+
+// UnidadesMetrica indica la unidad de cada métrica y sirve como lista de
+// métricas válidas.
+var UnidadesMetrica = map[MetricaMedicion]string{
+	MetricaPeso:               "kg",
+	MetricaTalla:              "cm",
+	MetricaIMC:                "kg/m²",
+	MetricaCintura:            "cm",
+	MetricaCadera:             "cm",
+	MetricaAbdomen:            "cm",
+	MetricaPresionSistolica:   "mmHg",
+	MetricaPresionDiastolica:  "mmHg",
+	MetricaFrecuenciaCardiaca: "lpm",
+	MetricaDolor:              "EVA 0-10",
+}
+
+func (m MetricaMedicion) IsValid() bool {
+	_, ok := UnidadesMetrica[m]
+	return ok
+}
+
+type PuntoSerie struct {
+	MedicionID uuid.UUID  `json:"medicion_id"`
+	Fecha      time.Time  `json:"fecha"`
+	Valor      float64    `json:"valor"`
+	PaqueteID  *uuid.UUID `json:"paquete_id,omitempty"`
+}
+
+type MedicionRepository interface {
+	Create(ctx context.Context, m *Medicion) error
+	GetByHistoriaID(ctx context.Context, historiaID uuid.UUID, paqueteID *uuid.UUID) ([]Medicion, error)
+	GetSerie(ctx context.Context, historiaID uuid.UUID, metrica MetricaMedicion, paqueteID *uuid.UUID) ([]PuntoSerie, error)
+	// UltimaTalla devuelve la talla más reciente registrada, o nil si no hay.
+	UltimaTalla(ctx context.Context, historiaID uuid.UUID) (*float64, error)
+}
@@ -0,0 +1,14 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Notificador entrega mensajes a los usuarios fuera de la API (correo, SMS,
+// registro local).
+type Notificador interface {
+	// EnviarResetPassword envía al usuario el token para restablecer su
+	// contraseña, válido hasta vence.
+	EnviarResetPassword(ctx context.Context, u *Usuario, token string, vence time.Time) error
+}
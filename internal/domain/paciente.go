@@ -7,25 +7,71 @@ import (
 	"github.com/google/uuid"
 )
 
+type EstadoPaciente string
+
+const (
+	PacienteActivo    EstadoPaciente = "ACTIVO"
+	PacienteArchivado EstadoPaciente = "ARCHIVADO"
+)
+
+// MayoriaEdad es la edad a partir de la cual el paciente firma sus propios consentimientos.
+const MayoriaEdad = 18
+
 type Paciente struct {
-	ID              uuid.UUID `json:"id"`
-	Codigo          string    `json:"codigo"`
-	NombreCompleto  string    `json:"nombre_completo"`
-	CI              string    `json:"ci"`
-	FechaNacimiento time.Time `json:"fecha_nacimiento"`
-	Celular         string    `json:"celular"`
-	Direccion       string    `json:"direccion,omitempty"`
-	CreatedBy       uuid.UUID `json:"created_by"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	ID              uuid.UUID      `json:"id"`
+	Codigo          string         `json:"codigo"`
+	NombreCompleto  string         `json:"nombre_completo"`
+	CI              string         `json:"ci"`
+	FechaNacimiento time.Time      `json:"fecha_nacimiento"`
+	Edad            int            `json:"edad"`
+	MenorDeEdad     bool           `json:"menor_de_edad"`
+	Celular         string         `json:"celular"`
+	Direccion       string         `json:"direccion,omitempty"`
+	Estado          EstadoPaciente `json:"estado"`
+	MotivoArchivo   string         `json:"motivo_archivo,omitempty"`
+	FechaArchivo    *time.Time     `json:"fecha_archivo,omitempty"`
+	ArchivadoPor    *uuid.UUID     `json:"archivado_por,omitempty"`
+	CreatedBy       uuid.UUID      `json:"created_by"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+func (p *Paciente) Archivado() bool {
+	return p.Estado == PacienteArchivado
+}
+
+// EdadEn devuelve la edad en años cumplidos a la fecha ref.
+func (p *Paciente) EdadEn(ref time.Time) int {
+	nac := p.FechaNacimiento
+	edad := ref.Year() - nac.Year()
+	if ref.Month() < nac.Month() || (ref.Month() == nac.Month() && ref.Day() < nac.Day()) {
+		edad--
+	}
+	if edad < 0 {
+		return 0
+	}
+	return edad
+}
+
+func (p *Paciente) EsMenorDeEdad(ref time.Time) bool {
+	return p.EdadEn(ref) < MayoriaEdad
+}
+
+// CalcularEdad completa los campos derivados Edad y MenorDeEdad a la fecha actual.
+func (p *Paciente) CalcularEdad() {
+	now := time.Now()
+	p.Edad = p.EdadEn(now)
+	p.MenorDeEdad = p.EsMenorDeEdad(now)
 }
 
 type PacienteRepository interface {
 	Create(ctx context.Context, p *Paciente) error
 	GetByID(ctx context.Context, id uuid.UUID) (*Paciente, error)
 	GetByCI(ctx context.Context, ci string) (*Paciente, error)
-	GetAll(ctx context.Context, offset, limit int) ([]Paciente, int64, error)
-	Search(ctx context.Context, query string, offset, limit int) ([]Paciente, int64, error)
+	GetAll(ctx context.Context, offset, limit int, incluirArchivados bool) ([]Paciente, int64, error)
+	Search(ctx context.Context, query string, offset, limit int, incluirArchivados bool) ([]Paciente, int64, error)
 	Update(ctx context.Context, p *Paciente) error
+	Archivar(ctx context.Context, id uuid.UUID, motivo string, archivadoPor uuid.UUID) error
+	Restaurar(ctx context.Context, id uuid.UUID) error
 	NextCodigo(ctx context.Context) (string, error)
 }
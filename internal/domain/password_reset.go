@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PasswordResetToken es un token de restablecimiento de contraseña emitido
+// por la Administradora. Sirve una sola vez.
+type PasswordResetToken struct {
+	ID        uuid.UUID  `json:"id"`
+	UsuarioID uuid.UUID  `json:"usuario_id"`
+	TokenHash string     `json:"-"`
+	CreadoPor uuid.UUID  `json:"creado_por"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type PasswordResetTokenRepository interface {
+	// Create guarda el token y vence los pendientes del mismo usuario.
+	Create(ctx context.Context, t *PasswordResetToken) error
+	// Consumir marca como usado el token con ese hash si sigue vigente y lo
+	// devuelve; si no existe, venció o ya se usó devuelve sql.ErrNoRows.
+	Consumir(ctx context.Context, tokenHash string) (*PasswordResetToken, error)
+}
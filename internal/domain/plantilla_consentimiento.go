@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VariablesConsentimiento son los marcadores {{variable}} admitidos en el
+// texto de una plantilla de consentimiento.
+var VariablesConsentimiento = []string{
+	"paciente_nombre", "paciente_ci", "paciente_codigo",
+	"tutor", "tutor_nombre", "tutor_ci",
+	"tratamiento", "fecha",
+}
+
+var marcadorConsentimiento = regexp.MustCompile(`\{\{\s*([a-z_]+)\s*\}\}`)
+
+// PlantillaConsentimiento es el texto oficial de un consentimiento. Texto y
+// VersionID corresponden a la versión vigente.
+type PlantillaConsentimiento struct {
+	ID              uuid.UUID  `json:"id"`
+	Nombre          string     `json:"nombre"`
+	TipoTratamiento string     `json:"tipo_tratamiento"`
+	Activa          bool       `json:"activa"`
+	VigenciaDias    *int       `json:"vigencia_dias,omitempty"` // nil: los consentimientos no vencen
+	Requerido       bool       `json:"requerido"`               // las citas de TipoTratamiento exigen un consentimiento vigente
+	VersionActual   int        `json:"version_actual"`
+	VersionID       uuid.UUID  `json:"version_id"`
+	Texto           string     `json:"texto"`
+	CreatedBy       *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// VersionPlantillaConsentimiento es una revisión inmutable del texto.
+type VersionPlantillaConsentimiento struct {
+	ID              uuid.UUID  `json:"id"`
+	PlantillaID     uuid.UUID  `json:"plantilla_id"`
+	PlantillaNombre string     `json:"plantilla_nombre"`
+	Version         int        `json:"version"`
+	Texto           string     `json:"texto"`
+	CreatedBy       *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// ValidarTextoConsentimiento rechaza marcadores desconocidos o mal cerrados.
+func ValidarTextoConsentimiento(texto string) error {
+	if strings.TrimSpace(texto) == "" {
+		return fmt.Errorf("el texto es requerido")
+	}
+	for _, m := range marcadorConsentimiento.FindAllStringSubmatch(texto, -1) {
+		if !variableConsentimientoValida(m[1]) {
+			return fmt.Errorf("variable desconocida {{%s}}; use: %s", m[1], strings.Join(VariablesConsentimiento, ", "))
+		}
+	}
+	resto := marcadorConsentimiento.ReplaceAllString(texto, "")
+	if strings.Contains(resto, "{{") || strings.Contains(resto, "}}") {
+		return fmt.Errorf("hay un marcador {{ }} mal formado")
+	}
+	return nil
+}
+
+func variableConsentimientoValida(v string) bool {
+	for _, valida := range VariablesConsentimiento {
+		if v == valida {
+			return true
+		}
+	}
+	return false
+}
+
+// RenderizarConsentimiento reemplaza los marcadores por sus valores. Las
+// líneas que quedan vacías de forma consecutiva se reducen a una.
+func RenderizarConsentimiento(texto string, valores map[string]string) string {
+	out := marcadorConsentimiento.ReplaceAllStringFunc(texto, func(m string) string {
+		return valores[marcadorConsentimiento.FindStringSubmatch(m)[1]]
+	})
+	lineas := strings.Split(strings.ReplaceAll(out, "\r\n", "\n"), "\n")
+	var res []string
+	for i, l := range lineas {
+		l = strings.TrimRight(l, " \t")
+		if l == "" && i > 0 && len(res) > 0 && res[len(res)-1] == "" {
+			continue
+		}
+		res = append(res, l)
+	}
+	return strings.TrimSpace(strings.Join(res, "\n"))
+}
+
+// HashContenido devuelve el SHA-256 en hexadecimal del texto de un consentimiento.
+func HashContenido(contenido string) string {
+	sum := sha256.Sum256([]byte(contenido))
+	return hex.EncodeToString(sum[:])
+}
+
+type PlantillaConsentimientoRepository interface {
+	// Create inserta la plantilla con su versión 1.
+	Create(ctx context.Context, p *PlantillaConsentimiento) error
+	GetByID(ctx context.Context, id uuid.UUID) (*PlantillaConsentimiento, error)
+	GetAll(ctx context.Context, soloActivas bool) ([]PlantillaConsentimiento, error)
+	// Update guarda los datos de la plantilla; si el texto cambió registra una
+	// nueva versión a nombre de autorID.
+	Update(ctx context.Context, p *PlantillaConsentimiento, autorID uuid.UUID) error
+	GetVersiones(ctx context.Context, plantillaID uuid.UUID) ([]VersionPlantillaConsentimiento, error)
+	GetVersionByID(ctx context.Context, id uuid.UUID) (*VersionPlantillaConsentimiento, error)
+	ExistsByNombre(ctx context.Context, nombre string, excludeID *uuid.UUID) (bool, error)
+}
@@ -0,0 +1,208 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TipoCampo string
+
+const (
+	CampoTexto    TipoCampo = "TEXTO"
+	CampoNumero   TipoCampo = "NUMERO"
+	CampoEntero   TipoCampo = "ENTERO"
+	CampoBooleano TipoCampo = "BOOLEANO"
+	CampoOpcion   TipoCampo = "OPCION"
+	CampoFecha    TipoCampo = "FECHA"
+)
+
+func (t TipoCampo) IsValid() bool {
+	switch t {
+	case CampoTexto, CampoNumero, CampoEntero, CampoBooleano, CampoOpcion, CampoFecha:
+		return true
+	}
+	return false
+}
+
+type CampoPlantilla struct {
+	Clave     string    `json:"clave"`
+	Etiqueta  string    `json:"etiqueta"`
+	Tipo      TipoCampo `json:"tipo"`
+	Requerido bool      `json:"requerido,omitempty"`
+	Opciones  []string  `json:"opciones,omitempty"` // solo OPCION
+	Unidad    string    `json:"unidad,omitempty"`
+	Min       *float64  `json:"min,omitempty"`
+	Max       *float64  `json:"max,omitempty"`
+}
+
+// PlantillaNota define los campos de una nota de evolución estructurada.
+type PlantillaNota struct {
+	ID          uuid.UUID        `json:"id"`
+	Nombre      string           `json:"nombre"`
+	Descripcion string           `json:"descripcion"`
+	TipoNota    string           `json:"tipo_nota"`
+	Campos      []CampoPlantilla `json:"campos"`
+	Activa      bool             `json:"activa"`
+	CreatedBy   *uuid.UUID       `json:"created_by,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// ValidarDefinicion revisa que los campos de la plantilla estén bien formados.
+func (p *PlantillaNota) ValidarDefinicion() error {
+	if len(p.Campos) == 0 {
+		return fmt.Errorf("la plantilla debe tener al menos un campo")
+	}
+	claves := make(map[string]bool)
+	for _, c := range p.Campos {
+		if strings.TrimSpace(c.Clave) == "" || strings.TrimSpace(c.Etiqueta) == "" {
+			return fmt.Errorf("cada campo requiere clave y etiqueta")
+		}
+		if claves[c.Clave] {
+			return fmt.Errorf("la clave %q está repetida", c.Clave)
+		}
+		claves[c.Clave] = true
+		if !c.Tipo.IsValid() {
+			return fmt.Errorf("tipo de campo inválido en %q. Use: TEXTO, NUMERO, ENTERO, BOOLEANO, OPCION o FECHA", c.Clave)
+		}
+		if c.Tipo == CampoOpcion && len(c.Opciones) == 0 {
+			return fmt.Errorf("el campo %q requiere opciones", c.Clave)
+		}
+		if c.Min != nil && c.Max != nil && *c.Min > *c.Max {
+			return fmt.Errorf("el campo %q tiene min mayor que max", c.Clave)
+		}
+	}
+	return nil
+}
+
+// Validar comprueba los datos de una nota contra la plantilla y devuelve solo
+// los campos informados. Claves desconocidas o valores de tipo incorrecto son
+// un error.
+func (p *PlantillaNota) Validar(datos map[string]interface{}) (map[string]interface{}, error) {
+	campos := make(map[string]CampoPlantilla, len(p.Campos))
+	for _, c := range p.Campos {
+		campos[c.Clave] = c
+	}
+	for clave := range datos {
+		if _, ok := campos[clave]; !ok {
+			return nil, fmt.Errorf("el campo %q no existe en la plantilla %s", clave, p.Nombre)
+		}
+	}
+
+	limpios := make(map[string]interface{})
+	for _, c := range p.Campos {
+		v, ok := datos[c.Clave]
+		if !ok || v == nil || v == "" {
+			if c.Requerido {
+				return nil, fmt.Errorf("el campo %s es requerido", c.Etiqueta)
+			}
+			continue
+		}
+		if err := c.validarValor(v); err != nil {
+			return nil, err
+		}
+		if s, ok := v.(string); ok {
+			v = strings.TrimSpace(s)
+		}
+		limpios[c.Clave] = v
+	}
+	return limpios, nil
+}
+
+func (c CampoPlantilla) validarValor(v interface{}) error {
+	switch c.Tipo {
+	case CampoTexto:
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("el campo %s debe ser texto", c.Etiqueta)
+		}
+		if c.Requerido && strings.TrimSpace(s) == "" {
+			return fmt.Errorf("el campo %s es requerido", c.Etiqueta)
+		}
+	case CampoNumero, CampoEntero:
+		n, ok := v.(float64)
+		if !ok {
+			return fmt.Errorf("el campo %s debe ser numérico", c.Etiqueta)
+		}
+		if c.Tipo == CampoEntero && n != math.Trunc(n) {
+			return fmt.Errorf("el campo %s debe ser un número entero", c.Etiqueta)
+		}
+		if c.Min != nil && n < *c.Min {
+			return fmt.Errorf("el campo %s debe ser al menos %s", c.Etiqueta, formatNumero(*c.Min))
+		}
+		if c.Max != nil && n > *c.Max {
+			return fmt.Errorf("el campo %s no puede superar %s", c.Etiqueta, formatNumero(*c.Max))
+		}
+	case CampoBooleano:
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("el campo %s debe ser verdadero o falso", c.Etiqueta)
+		}
+	case CampoOpcion:
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("el campo %s debe ser una de: %s", c.Etiqueta, strings.Join(c.Opciones, ", "))
+		}
+		for _, o := range c.Opciones {
+			if o == s {
+				return nil
+			}
+		}
+		return fmt.Errorf("el campo %s debe ser una de: %s", c.Etiqueta, strings.Join(c.Opciones, ", "))
+	case CampoFecha:
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("el campo %s debe ser una fecha YYYY-MM-DD", c.Etiqueta)
+		}
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return fmt.Errorf("el campo %s debe ser una fecha YYYY-MM-DD", c.Etiqueta)
+		}
+	}
+	return nil
+}
+
+// Renderizar genera el texto legible de una nota a partir de sus datos, una
+// línea por campo informado y en el orden de la plantilla.
+func (p *PlantillaNota) Renderizar(datos map[string]interface{}) string {
+	var lineas []string
+	for _, c := range p.Campos {
+		v, ok := datos[c.Clave]
+		if !ok || v == nil {
+			continue
+		}
+		var valor string
+		switch x := v.(type) {
+		case bool:
+			valor = "No"
+			if x {
+				valor = "Sí"
+			}
+		case float64:
+			valor = formatNumero(x)
+		default:
+			valor = fmt.Sprint(x)
+		}
+		if c.Unidad != "" {
+			valor += " " + c.Unidad
+		}
+		lineas = append(lineas, c.Etiqueta+": "+valor)
+	}
+	return strings.Join(lineas, "\n")
+}
+
+func formatNumero(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+type PlantillaNotaRepository interface {
+	Create(ctx context.Context, p *PlantillaNota) error
+	GetByID(ctx context.Context, id uuid.UUID) (*PlantillaNota, error)
+	GetAll(ctx context.Context, soloActivas bool) ([]PlantillaNota, error)
+	Update(ctx context.Context, p *PlantillaNota) error
+	ExistsByNombre(ctx context.Context, nombre string, excludeID *uuid.UUID) (bool, error)
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type MotivoRevocacionToken string
+
+const (
+	TokenLogout      MotivoRevocacionToken = "LOGOUT"
+	TokenLogoutTodos MotivoRevocacionToken = "LOGOUT_TODOS"
+	TokenReutilizado MotivoRevocacionToken = "REUTILIZADO"
+	TokenCambioPass  MotivoRevocacionToken = "CAMBIO_PASSWORD"
+	TokenReset2FA    MotivoRevocacionToken = "RESET_2FA"
+	TokenDesact2FA   MotivoRevocacionToken = "DESACTIVAR_2FA"
+)
+
+// RefreshToken es un refresh token emitido. ID es el jti del token y
+// FamiliaID agrupa los tokens que descienden de un mismo login.
+type RefreshToken struct {
+	ID               uuid.UUID              `json:"id"`
+	UsuarioID        uuid.UUID              `json:"usuario_id"`
+	FamiliaID        uuid.UUID              `json:"familia_id"`
+	TokenHash        string                 `json:"-"`
+	UserAgent        string                 `json:"user_agent"`
+	IP               string                 `json:"ip"`
+	ExpiresAt        time.Time              `json:"expires_at"`
+	UsedAt           *time.Time             `json:"used_at,omitempty"`
+	ReemplazadoPor   *uuid.UUID             `json:"reemplazado_por,omitempty"`
+	RevokedAt        *time.Time             `json:"revoked_at,omitempty"`
+	MotivoRevocacion *MotivoRevocacionToken `json:"motivo_revocacion,omitempty"`
+	CreatedAt        time.Time              `json:"created_at"`
+}
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, t *RefreshToken) error
+	GetByID(ctx context.Context, id uuid.UUID) (*RefreshToken, error)
+	// Rotar marca anterior como usado y guarda nuevo en una transacción.
+	// Devuelve false si anterior ya estaba usado o revocado.
+	Rotar(ctx context.Context, anteriorID uuid.UUID, nuevo *RefreshToken) (bool, error)
+	RevocarFamilia(ctx context.Context, familiaID uuid.UUID, motivo MotivoRevocacionToken) error
+	RevocarUsuario(ctx context.Context, usuarioID uuid.UUID, motivo MotivoRevocacionToken) error
+}
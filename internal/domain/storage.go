@@ -0,0 +1,14 @@
+package domain
+
+import (
+	"context"
+	"io"
+)
+
+// FileStorage guarda archivos binarios (fotos, adjuntos) bajo una clave. Las
+// claves usan "/" como separador y las genera la aplicación.
+type FileStorage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TipoEventoTimeline string
+
+const (
+	EventoCita           TipoEventoTimeline = "CITA"
+	EventoNota           TipoEventoTimeline = "NOTA"
+	EventoConsentimiento TipoEventoTimeline = "CONSENTIMIENTO"
+	EventoPaquete        TipoEventoTimeline = "PAQUETE"
+)
+
+func (t TipoEventoTimeline) IsValid() bool {
+	switch t {
+	case EventoCita, EventoNota, EventoConsentimiento, EventoPaquete:
+		return true
+	}
+	return false
+}
+
+// EventoTimeline es una entrada de la línea de tiempo clínica del paciente.
+// Tipo agrupa la fuente (cita, nota, ...) y Evento describe lo ocurrido
+// (CREADA, CAMBIO_ESTADO, FIRMADO, ...).
+type EventoTimeline struct {
+	Tipo          TipoEventoTimeline `json:"tipo"`
+	Evento        string             `json:"evento"`
+	Fecha         time.Time          `json:"fecha"`
+	ReferenciaID  uuid.UUID          `json:"referencia_id"`
+	Descripcion   string             `json:"descripcion"`
+	Detalle       json.RawMessage    `json:"detalle,omitempty"`
+	UsuarioID     *uuid.UUID         `json:"usuario_id,omitempty"`
+	UsuarioNombre string             `json:"usuario_nombre,omitempty"`
+}
+
+type TimelineRepository interface {
+	GetByPacienteID(ctx context.Context, pacienteID uuid.UUID, tipos []TipoEventoTimeline, offset, limit int) ([]EventoTimeline, int64, error)
+}
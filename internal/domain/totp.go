@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TOTPUsuario es la inscripción de un usuario en la autenticación en dos
+// pasos. SecretoCifrado está cifrado con AES-GCM; la inscripción queda
+// pendiente (Habilitado false) hasta que el usuario confirma un código.
+type TOTPUsuario struct {
+	UsuarioID      uuid.UUID
+	SecretoCifrado string
+	Habilitado     bool
+	// UltimoPaso es el paso TOTP del último código aceptado.
+	UltimoPaso   int64
+	HabilitadoAt *time.Time
+	CreatedAt    time.Time
+}
+
+type TOTPRepository interface {
+	GetByUsuarioID(ctx context.Context, usuarioID uuid.UUID) (*TOTPUsuario, error)
+	// GuardarPendiente crea o reemplaza una inscripción no habilitada.
+	GuardarPendiente(ctx context.Context, usuarioID uuid.UUID, secretoCifrado string) error
+	// Habilitar confirma la inscripción con el paso del código verificado y
+	// reemplaza los códigos de recuperación.
+	Habilitar(ctx context.Context, usuarioID uuid.UUID, paso int64, hashesRecuperacion []string) error
+	// RegistrarPaso guarda paso como el último usado si es posterior al
+	// anterior; devuelve false si el código ya se había usado.
+	RegistrarPaso(ctx context.Context, usuarioID uuid.UUID, paso int64) (bool, error)
+	ReemplazarCodigos(ctx context.Context, usuarioID uuid.UUID, hashesRecuperacion []string) error
+	// ConsumirCodigo marca usado el código de recuperación con ese hash;
+	// devuelve false si no existe o ya se usó.
+	ConsumirCodigo(ctx context.Context, usuarioID uuid.UUID, hash string) (bool, error)
+	CodigosRestantes(ctx context.Context, usuarioID uuid.UUID) (int, error)
+	// Delete quita la inscripción y los códigos de recuperación.
+	Delete(ctx context.Context, usuarioID uuid.UUID) error
+}
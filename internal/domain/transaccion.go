@@ -0,0 +1,10 @@
+package domain
+
+import "context"
+
+// Transactor agrupa escrituras de varios repositorios: los repositorios que
+// reciben el contexto de fn escriben en una misma transacción, que se confirma
+// solo si fn no devuelve error.
+type Transactor interface {
+	EnTransaccion(ctx context.Context, fn func(ctx context.Context) error) error
+}
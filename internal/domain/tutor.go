@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tutor es el padre, madre o apoderado legal que firma en nombre de un paciente menor de edad.
+type Tutor struct {
+	ID             uuid.UUID `json:"id"`
+	PacienteID     uuid.UUID `json:"paciente_id"`
+	NombreCompleto string    `json:"nombre_completo"`
+	CI             string    `json:"ci"`
+	Parentesco     string    `json:"parentesco"`
+	Celular        string    `json:"celular,omitempty"`
+	CreatedBy      uuid.UUID `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type TutorRepository interface {
+	Create(ctx context.Context, t *Tutor) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Tutor, error)
+	GetByPacienteID(ctx context.Context, pacienteID uuid.UUID) ([]Tutor, error)
+}
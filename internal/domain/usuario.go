@@ -15,8 +15,17 @@ type Usuario struct {
 	RolID          uuid.UUID `json:"rol_id"`
 	Rol            *Rol      `json:"rol,omitempty"`
 	Activo         bool      `json:"activo"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	TokenVersion   int       `json:"-"` // se incrementa para invalidar los access tokens emitidos
+	// DebeCambiarPassword obliga a cambiar la contraseña antes de usar la API.
+	DebeCambiarPassword bool       `json:"debe_cambiar_password"`
+	PasswordChangedAt   *time.Time `json:"password_changed_at,omitempty"`
+	// IntentosFallidos cuenta los logins fallidos consecutivos; al llegar al
+	// máximo la cuenta queda bloqueada hasta BloqueadoHasta.
+	IntentosFallidos int        `json:"intentos_fallidos"`
+	UltimoFalloAt    *time.Time `json:"ultimo_fallo_at,omitempty"`
+	BloqueadoHasta   *time.Time `json:"bloqueado_hasta,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
 }
 
 type UsuarioRepository interface {
@@ -24,6 +33,22 @@ type UsuarioRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*Usuario, error)
 	GetByEmail(ctx context.Context, email string) (*Usuario, error)
 	GetAll(ctx context.Context, offset, limit int) ([]Usuario, int64, error)
-	Update(ctx context.Context, u *Usuario) error
+	// Update guarda los datos editables sin tocar token_version desde el
+	// struct; con revocarTokens la incrementa en la misma sentencia. En ambos
+	// casos deja en u la versión vigente.
+	Update(ctx context.Context, u *Usuario, revocarTokens bool) error
+	// Delete desactiva al usuario e invalida sus access tokens.
 	Delete(ctx context.Context, id uuid.UUID) error
+	IncrementTokenVersion(ctx context.Context, id uuid.UUID) error
+	// GetTokenVersion devuelve la versión de tokens vigente y si el usuario está activo.
+	GetTokenVersion(ctx context.Context, id uuid.UUID) (int, bool, error)
+	// RegistrarLoginFallido suma un fallo y, si llega a maxIntentos, bloquea la
+	// cuenta durante bloqueo y reinicia la cuenta de fallos. Devuelve los
+	// fallos acumulados y el fin del bloqueo, si lo hay.
+	RegistrarLoginFallido(ctx context.Context, id uuid.UUID, maxIntentos int, bloqueo time.Duration) (int, *time.Time, error)
+	// Desbloquear reinicia los fallos y quita el bloqueo; lo usa también el login exitoso.
+	Desbloquear(ctx context.Context, id uuid.UUID) error
+	// UpdatePassword reemplaza el hash, quita DebeCambiarPassword e invalida
+	// los access tokens emitidos.
+	UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error
 }
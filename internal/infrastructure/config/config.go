@@ -4,13 +4,25 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// DefaultJWTSecret es el JWT_SECRET de desarrollo. En producción el servidor
+// se niega a iniciar si lo usa.
+const DefaultJWTSecret = "cambiar-este-secret-en-produccion"
+
 type Config struct {
-	DB     DBConfig
-	Server ServerConfig
-	JWT    JWTConfig
-	Admin  AdminConfig
+	DB       DBConfig
+	Server   ServerConfig
+	JWT      JWTConfig
+	Admin    AdminConfig
+	Storage  StorageConfig
+	Consent  ConsentConfig
+	Login    LoginConfig
+	Password PasswordConfig
+	Notifier NotifierConfig
+	TOTP     TOTPConfig
 }
 
 type DBConfig struct {
@@ -29,12 +41,97 @@ func (c DBConfig) DSN() string {
 
 type ServerConfig struct {
 	Port string
+	// Env es "production" en producción; habilita las validaciones de Validate.
+	Env string
+	// Timezone es la zona horaria IANA de la clínica, con la que se muestran
+	// las fechas en los documentos (por ejemplo, {{fecha}} en los
+	// consentimientos).
+	Timezone string
 }
 
+// JWTConfig configura la firma de los tokens. Algorithm es "HS256" (firma con
+// Secret), "RS256" o "EdDSA" (firman con la clave de PrivateKeyFile).
+// PublicKeyFiles y PreviousSecrets, separados por coma, son claves anteriores
+// que solo verifican, para rotar la clave de firma sin cerrar las sesiones.
 type JWTConfig struct {
+	Algorithm            string
 	Secret               string
+	PrivateKeyFile       string
+	PublicKeyFiles       string
+	PreviousSecrets      string
 	ExpirationHours      int
 	RefreshExpirationHrs int
+	// SessionCacheSeconds es cuánto se cachea la versión de tokens de cada
+	// usuario. Con varias instancias, es la demora máxima de una revocación.
+	SessionCacheSeconds int
+}
+
+// StorageConfig selecciona dónde se guardan los archivos (fotos, adjuntos).
+// Driver "local" usa LocalPath; "s3" usa cualquier servicio compatible con S3.
+type StorageConfig struct {
+	Driver      string
+	LocalPath   string
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3PathStyle bool
+}
+
+// ConsentConfig agrupa la configuración de los consentimientos informados.
+type ConsentConfig struct {
+	// SealSecret es la clave HMAC del sello de los consentimientos. Es
+	// obligatoria en producción; en desarrollo, si está vacía se usa JWT_SECRET.
+	SealSecret string
+	// MissingPolicy indica qué hacer al agendar una cita de un tratamiento que
+	// exige consentimiento vigente y el paciente no lo tiene: "warn" (por
+	// defecto) la crea con una advertencia, "block" la rechaza.
+	MissingPolicy string
+}
+
+// LoginConfig limita los intentos de login. Tras cada fallo de una cuenta la
+// espera antes del siguiente intento se duplica, desde DelayBaseSeconds hasta
+// DelayMaxSeconds; con MaxAttempts fallos seguidos la cuenta se bloquea
+// LockoutMinutes. Una IP con MaxFailuresPerIP fallos en IPWindowMinutes queda
+// rechazada para cualquier cuenta.
+type LoginConfig struct {
+	MaxAttempts      int
+	LockoutMinutes   int
+	DelayBaseSeconds int
+	DelayMaxSeconds  int
+	MaxFailuresPerIP int
+	IPWindowMinutes  int
+}
+
+// PasswordConfig define la política de contraseñas y la vigencia de los
+// tokens de restablecimiento.
+type PasswordConfig struct {
+	MinLength       int
+	RequireUpper    bool
+	RequireLower    bool
+	RequireDigit    bool
+	RequireSymbol   bool
+	ResetTTLMinutes int
+}
+
+// NotifierConfig selecciona cómo se entregan los mensajes a los usuarios.
+// Driver "log" los escribe en el log del servidor. ResetURL, si se indica, es
+// la página del frontend que recibe el token de restablecimiento como ?token=.
+type NotifierConfig struct {
+	Driver   string
+	ResetURL string
+}
+
+// TOTPConfig configura la autenticación en dos pasos.
+type TOTPConfig struct {
+	// Issuer es el nombre que muestran las apps de autenticación.
+	Issuer string
+	// RequiredRoles son los roles, separados por coma, que deben activarla.
+	RequiredRoles string
+	// EncryptionKey cifra los secretos TOTP en la base. Es obligatoria en
+	// producción; en desarrollo, si está vacía se usa JWT_SECRET.
+	EncryptionKey string
 }
 
 type AdminConfig struct {
@@ -53,18 +150,95 @@ func Load() *Config {
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
 		},
 		Server: ServerConfig{
-			Port: getEnv("SERVER_PORT", "8080"),
+			Port:     getEnv("SERVER_PORT", "8080"),
+			Env:      getEnv("APP_ENV", "development"),
+			Timezone: getEnv("CLINIC_TIMEZONE", "America/La_Paz"),
 		},
 		JWT: JWTConfig{
-			Secret:               getEnv("JWT_SECRET", "cambiar-este-secret-en-produccion"),
+			Algorithm:            getEnv("JWT_ALGORITHM", "HS256"),
+			Secret:               getEnv("JWT_SECRET", DefaultJWTSecret),
+			PrivateKeyFile:       getEnv("JWT_PRIVATE_KEY_FILE", ""),
+			PublicKeyFiles:       getEnv("JWT_PUBLIC_KEY_FILES", ""),
+			PreviousSecrets:      getEnv("JWT_PREVIOUS_SECRETS", ""),
 			ExpirationHours:      getEnvInt("JWT_EXPIRATION_HOURS", 8),
 			RefreshExpirationHrs: getEnvInt("JWT_REFRESH_EXPIRATION_HOURS", 72),
+			SessionCacheSeconds:  getEnvInt("JWT_SESSION_CACHE_SECONDS", 30),
 		},
 		Admin: AdminConfig{
 			Email:    getEnv("ADMIN_EMAIL", "admin@centrocaribel.com"),
 			Password: getEnv("ADMIN_PASSWORD", "Admin123!"),
 		},
+		Storage: StorageConfig{
+			Driver:      getEnv("STORAGE_DRIVER", "local"),
+			LocalPath:   getEnv("STORAGE_LOCAL_PATH", "uploads"),
+			S3Endpoint:  getEnv("S3_ENDPOINT", "https://s3.amazonaws.com"),
+			S3Region:    getEnv("S3_REGION", "us-east-1"),
+			S3Bucket:    getEnv("S3_BUCKET", ""),
+			S3AccessKey: getEnv("S3_ACCESS_KEY", ""),
+			S3SecretKey: getEnv("S3_SECRET_KEY", ""),
+			S3PathStyle: getEnv("S3_PATH_STYLE", "true") == "true",
+		},
+		Consent: ConsentConfig{
+			SealSecret:    getEnv("CONSENT_SEAL_SECRET", ""),
+			MissingPolicy: getEnv("CONSENT_MISSING_POLICY", "warn"),
+		},
+		Login: LoginConfig{
+			MaxAttempts:      getEnvInt("LOGIN_MAX_ATTEMPTS", 5),
+			LockoutMinutes:   getEnvInt("LOGIN_LOCKOUT_MINUTES", 15),
+			DelayBaseSeconds: getEnvInt("LOGIN_DELAY_BASE_SECONDS", 1),
+			DelayMaxSeconds:  getEnvInt("LOGIN_DELAY_MAX_SECONDS", 30),
+			MaxFailuresPerIP: getEnvInt("LOGIN_MAX_FAILURES_PER_IP", 20),
+			IPWindowMinutes:  getEnvInt("LOGIN_IP_WINDOW_MINUTES", 15),
+		},
+		Password: PasswordConfig{
+			MinLength:       getEnvInt("PASSWORD_MIN_LENGTH", 8),
+			RequireUpper:    getEnv("PASSWORD_REQUIRE_UPPER", "true") == "true",
+			RequireLower:    getEnv("PASSWORD_REQUIRE_LOWER", "true") == "true",
+			RequireDigit:    getEnv("PASSWORD_REQUIRE_DIGIT", "true") == "true",
+			RequireSymbol:   getEnv("PASSWORD_REQUIRE_SYMBOL", "false") == "true",
+			ResetTTLMinutes: getEnvInt("PASSWORD_RESET_TTL_MINUTES", 60),
+		},
+		Notifier: NotifierConfig{
+			Driver:   getEnv("NOTIFIER_DRIVER", "log"),
+			ResetURL: getEnv("PASSWORD_RESET_URL", ""),
+		},
+		TOTP: TOTPConfig{
+			Issuer:        getEnv("TOTP_ISSUER", "Centro Caribel"),
+			RequiredRoles: getEnv("TOTP_REQUIRED_ROLES", "Administradora"),
+			EncryptionKey: getEnv("TOTP_ENCRYPTION_KEY", ""),
+		},
+	}
+}
+
+// Validate rechaza en producción las configuraciones inseguras. Ahí las claves
+// del sello de consentimientos y del cifrado TOTP son obligatorias: si usaran
+// JWT_SECRET, rotarlo invalidaría los sellos guardados y dejaría ilegibles los
+// secretos TOTP.
+func (c *Config) Validate() error {
+	if _, err := time.LoadLocation(c.Server.Timezone); err != nil {
+		return fmt.Errorf("CLINIC_TIMEZONE inválida: %q", c.Server.Timezone)
+	}
+	if c.Server.Env != "production" {
+		return nil
+	}
+
+	switch {
+	case c.JWT.Algorithm == "HS256" && c.JWT.Secret == DefaultJWTSecret:
+		return fmt.Errorf("JWT_SECRET tiene el valor por defecto; configure un secreto propio")
+	case c.Consent.SealSecret == "" || c.Consent.SealSecret == DefaultJWTSecret:
+		return fmt.Errorf("CONSENT_SEAL_SECRET es requerido en producción")
+	case c.TOTP.EncryptionKey == "" || c.TOTP.EncryptionKey == DefaultJWTSecret:
+		return fmt.Errorf("TOTP_ENCRYPTION_KEY es requerido en producción")
+	}
+	if c.JWT.Algorithm == "HS256" && len(c.JWT.Secret) < 32 {
+		return fmt.Errorf("JWT_SECRET debe tener al menos 32 caracteres")
+	}
+	for _, s := range strings.Split(c.JWT.PreviousSecrets, ",") {
+		if strings.TrimSpace(s) == DefaultJWTSecret {
+			return fmt.Errorf("JWT_PREVIOUS_SECRETS no puede incluir el secreto por defecto")
+		}
 	}
+	return nil
 }
 
 func getEnv(key, fallback string) string {
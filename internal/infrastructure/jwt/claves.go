@@ -0,0 +1,164 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/tunek/centro-caribel/internal/application/auth"
+)
+
+// Clave es una clave de firma o de verificación de tokens. Kid viaja en la
+// cabecera "kid" de cada token: en las claves RSA y Ed25519 es el thumbprint
+// RFC 7638; en las HMAC se deriva del secreto con kidHMAC.
+type Clave struct {
+	Kid    string
+	metodo jwt.SigningMethod
+	// privada es nil en las claves que solo verifican.
+	privada interface{}
+	publica interface{}
+}
+
+// NewClaveHMAC crea una clave HS256. Sirve para firmar y verificar, y nunca
+// se publica en el JWKS.
+func NewClaveHMAC(secret string) *Clave {
+	k := []byte(secret)
+	return &Clave{
+		Kid:     kidHMAC(k),
+		metodo:  jwt.SigningMethodHS256,
+		privada: k,
+		publica: k,
+	}
+}
+
+// CargarClavePrivada lee una clave privada PEM (RSA en PKCS#1 o PKCS#8,
+// Ed25519 en PKCS#8) para firmar con RS256 o EdDSA.
+func CargarClavePrivada(path string) (*Clave, error) {
+	bloque, err := leerPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var privada crypto.PrivateKey
+	if k, err := x509.ParsePKCS1PrivateKey(bloque.Bytes); err == nil {
+		privada = k
+	} else if privada, err = x509.ParsePKCS8PrivateKey(bloque.Bytes); err != nil {
+		return nil, fmt.Errorf("%s: clave privada inválida: %w", path, err)
+	}
+
+	switch k := privada.(type) {
+	case *rsa.PrivateKey:
+		c, err := clavePublica(&k.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		c.privada = k
+		return c, nil
+	case ed25519.PrivateKey:
+		c, _ := clavePublica(k.Public())
+		c.privada = k
+		return c, nil
+	default:
+		return nil, fmt.Errorf("%s: tipo de clave no soportado (use RSA o Ed25519)", path)
+	}
+}
+
+// CargarClavePublica lee una clave pública PEM (PKIX) que solo verifica; se
+// usa para seguir aceptando los tokens firmados con una clave ya rotada.
+func CargarClavePublica(path string) (*Clave, error) {
+	bloque, err := leerPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	publica, err := x509.ParsePKIXPublicKey(bloque.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: clave pública inválida: %w", path, err)
+	}
+	c, err := clavePublica(publica)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+func clavePublica(publica crypto.PublicKey) (*Clave, error) {
+	switch k := publica.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("la clave RSA debe tener al menos 2048 bits")
+		}
+		n, e := b64(k.N.Bytes()), b64(big.NewInt(int64(k.E)).Bytes())
+		return &Clave{
+			Kid:     thumbprint(`{"e":"` + e + `","kty":"RSA","n":"` + n + `"}`),
+			metodo:  jwt.SigningMethodRS256,
+			publica: k,
+		}, nil
+	case ed25519.PublicKey:
+		return &Clave{
+			Kid:     thumbprint(`{"crv":"Ed25519","kty":"OKP","x":"` + b64(k) + `"}`),
+			metodo:  jwt.SigningMethodEdDSA,
+			publica: k,
+		}, nil
+	default:
+		return nil, fmt.Errorf("tipo de clave no soportado (use RSA o Ed25519)")
+	}
+}
+
+// jwk devuelve la clave en formato JWK, o false si no se publica (HMAC).
+func (c *Clave) jwk() (auth.JWK, bool) {
+	j := auth.JWK{Use: "sig", Alg: c.metodo.Alg(), Kid: c.Kid}
+	switch k := c.publica.(type) {
+	case *rsa.PublicKey:
+		j.Kty = "RSA"
+		j.N = b64(k.N.Bytes())
+		j.E = b64(big.NewInt(int64(k.E)).Bytes())
+	case ed25519.PublicKey:
+		j.Kty = "OKP"
+		j.Crv = "Ed25519"
+		j.X = b64(k)
+	default:
+		return auth.JWK{}, false
+	}
+	return j, true
+}
+
+func leerPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	bloque, _ := pem.Decode(data)
+	if bloque == nil {
+		return nil, fmt.Errorf("%s: no contiene un bloque PEM", path)
+	}
+	return bloque, nil
+}
+
+// kidHMAC deriva el kid de un secreto HMAC como HMAC-SHA256(secreto, "kid")
+// truncado a 16 bytes. El thumbprint RFC 7638 sería un SHA-256 sin clave del
+// secreto, que se publica en cada token.
+func kidHMAC(secreto []byte) string {
+	mac := hmac.New(sha256.New, secreto)
+	mac.Write([]byte("kid"))
+	return b64(mac.Sum(nil)[:16])
+}
+
+// thumbprint es el SHA-256 en base64url del JWK canónico (RFC 7638): solo
+// los miembros requeridos, en orden alfabético y sin espacios.
+func thumbprint(canonico string) string {
+	sum := sha256.Sum256([]byte(canonico))
+	return b64(sum[:])
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
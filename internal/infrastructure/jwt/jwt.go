@@ -2,57 +2,116 @@ package jwt
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/tunek/centro-caribel/internal/application/auth"
+	"github.com/tunek/centro-caribel/internal/infrastructure/config"
 )
 
+// Service firma los tokens con una clave y los verifica con cualquiera de
+// las claves registradas, elegida por el "kid" del token. Las claves de
+// verificación adicionales permiten rotar la de firma sin invalidar los
+// tokens ya emitidos.
 type Service struct {
-	secret          []byte
+	firma           *Clave
+	claves          map[string]*Clave
 	expHours        int
 	refreshExpHours int
 }
 
-func NewService(secret string, expHours, refreshExpHours int) *Service {
+func NewService(firma *Clave, verificacion []*Clave, expHours, refreshExpHours int) *Service {
+	claves := map[string]*Clave{firma.Kid: firma}
+	for _, c := range verificacion {
+		claves[c.Kid] = c
+	}
 	return &Service{
-		secret:          []byte(secret),
+		firma:           firma,
+		claves:          claves,
 		expHours:        expHours,
 		refreshExpHours: refreshExpHours,
 	}
 }
 
-func (s *Service) GenerateToken(userID, rolNombre string) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id":    userID,
-		"rol_nombre": rolNombre,
-		"exp":        time.Now().Add(time.Duration(s.expHours) * time.Hour).Unix(),
-		"iat":        time.Now().Unix(),
-		"type":       "access",
+// New crea el servicio con las claves indicadas en la configuración.
+func New(cfg config.JWTConfig) (*Service, error) {
+	var firma *Clave
+	switch cfg.Algorithm {
+	case "", "HS256":
+		firma = NewClaveHMAC(cfg.Secret)
+	case "RS256", "EdDSA":
+		if cfg.PrivateKeyFile == "" {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE es requerido para JWT_ALGORITHM=%s", cfg.Algorithm)
+		}
+		var err error
+		if firma, err = CargarClavePrivada(cfg.PrivateKeyFile); err != nil {
+			return nil, err
+		}
+		if firma.metodo.Alg() != cfg.Algorithm {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE es una clave %s y JWT_ALGORITHM es %s", firma.metodo.Alg(), cfg.Algorithm)
+		}
+	default:
+		return nil, fmt.Errorf("JWT_ALGORITHM desconocido: %s (use HS256, RS256 o EdDSA)", cfg.Algorithm)
+	}
+
+	var verificacion []*Clave
+	for _, path := range lista(cfg.PublicKeyFiles) {
+		c, err := CargarClavePublica(path)
+		if err != nil {
+			return nil, err
+		}
+		verificacion = append(verificacion, c)
+	}
+	for _, secret := range lista(cfg.PreviousSecrets) {
+		verificacion = append(verificacion, NewClaveHMAC(secret))
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.secret)
+	return NewService(firma, verificacion, cfg.ExpirationHours, cfg.RefreshExpirationHrs), nil
 }
 
-func (s *Service) GenerateRefreshToken(userID string) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Duration(s.refreshExpHours) * time.Hour).Unix(),
-		"iat":     time.Now().Unix(),
-		"type":    "refresh",
+// PublicKeys devuelve las claves públicas de firma y verificación para el
+// JWKS. Las claves HMAC no se publican.
+func (s *Service) PublicKeys() []auth.JWK {
+	keys := []auth.JWK{}
+	if j, ok := s.firma.jwk(); ok {
+		keys = append(keys, j)
+	}
+	for kid, c := range s.claves {
+		if kid == s.firma.Kid {
+			continue
+		}
+		if j, ok := c.jwk(); ok {
+			keys = append(keys, j)
+		}
 	}
+	return keys
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.secret)
+func (s *Service) firmar(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(s.firma.metodo, claims)
+	token.Header["kid"] = s.firma.Kid
+	return token.SignedString(s.firma.privada)
 }
 
-func (s *Service) ValidateToken(tokenStr string) (*auth.Claims, error) {
+// parse verifica la firma con la clave del "kid" y comprueba el tipo de
+// token. La firma debe usar el algoritmo de esa clave, para que no se pueda
+// presentar una clave pública como secreto HMAC.
+func (s *Service) parse(tokenStr, tipo string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		clave := s.claves[kid]
+		if kid == "" {
+			// Tokens emitidos antes de usar "kid": solo la clave de firma.
+			clave = s.firma
+		}
+		if clave == nil {
+			return nil, fmt.Errorf("clave desconocida: %s", kid)
+		}
+		if token.Method.Alg() != clave.metodo.Alg() {
 			return nil, fmt.Errorf("método de firma inesperado: %v", token.Header["alg"])
 		}
-		return s.secret, nil
+		return clave.publica, nil
 	})
 
 	if err != nil {
@@ -65,38 +124,115 @@ func (s *Service) ValidateToken(tokenStr string) (*auth.Claims, error) {
 	}
 
 	tokenType, _ := claims["type"].(string)
-	if tokenType != "access" {
+	if tokenType != tipo {
 		return nil, fmt.Errorf("tipo de token inválido")
 	}
+	return claims, nil
+}
+
+// lista separa una lista de valores separados por coma, sin vacíos.
+func lista(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// preAuthExp es la vigencia del token entre la contraseña y el código TOTP.
+const preAuthExp = 5 * time.Minute
 
+// GenerateToken firma un access token. TokenVersion va en el claim "ver": si
+// cambia, el token deja de aceptarse. CambioPassword ("cpw") e Inscribir2FA
+// ("i2f") marcan los tokens que solo sirven para completar esas acciones.
+func (s *Service) GenerateToken(c auth.Claims) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id":    c.UserID,
+		"rol_nombre": c.RolNombre,
+		"ver":        c.TokenVersion,
+		"exp":        time.Now().Add(time.Duration(s.expHours) * time.Hour).Unix(),
+		"iat":        time.Now().Unix(),
+		"type":       "access",
+	}
+	if c.CambioPassword {
+		claims["cpw"] = true
+	}
+	if c.Inscribir2FA {
+		claims["i2f"] = true
+	}
+
+	return s.firmar(claims)
+}
+
+// GenerateRefreshToken firma un refresh token con jti tokenID y devuelve
+// también su vencimiento, que se guarda junto al token.
+func (s *Service) GenerateRefreshToken(userID, tokenID string) (string, time.Time, error) {
+	exp := time.Now().Add(time.Duration(s.refreshExpHours) * time.Hour)
+	claims := jwt.MapClaims{
+		"user_id": userID,
+		"jti":     tokenID,
+		"exp":     exp.Unix(),
+		"iat":     time.Now().Unix(),
+		"type":    "refresh",
+	}
+
+	signed, err := s.firmar(claims)
+	return signed, exp, err
+}
+
+func (s *Service) ValidateToken(tokenStr string) (*auth.Claims, error) {
+	claims, err := s.parse(tokenStr, "access")
+	if err != nil {
+		return nil, err
+	}
+
+	// Los tokens emitidos antes de existir "ver" equivalen a la versión 0.
+	version, _ := claims["ver"].(float64)
+	cambioPassword, _ := claims["cpw"].(bool)
+	inscribir2FA, _ := claims["i2f"].(bool)
 	return &auth.Claims{
-		UserID:    claims["user_id"].(string),
-		RolNombre: claims["rol_nombre"].(string),
+		UserID:         claims["user_id"].(string),
+		RolNombre:      claims["rol_nombre"].(string),
+		TokenVersion:   int(version),
+		CambioPassword: cambioPassword,
+		Inscribir2FA:   inscribir2FA,
 	}, nil
 }
 
-func (s *Service) ValidateRefreshToken(tokenStr string) (string, error) {
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("método de firma inesperado: %v", token.Header["alg"])
-		}
-		return s.secret, nil
-	})
+// GeneratePreAuthToken firma el token que acredita que el usuario ya
+// presentó la contraseña y falta el código TOTP. Vence a los 5 minutos.
+func (s *Service) GeneratePreAuthToken(userID string, version int) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id": userID,
+		"ver":     version,
+		"exp":     time.Now().Add(preAuthExp).Unix(),
+		"iat":     time.Now().Unix(),
+		"type":    "preauth",
+	}
+
+	return s.firmar(claims)
+}
 
+func (s *Service) ValidatePreAuthToken(tokenStr string) (*auth.Claims, error) {
+	claims, err := s.parse(tokenStr, "preauth")
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok || !token.Valid {
-		return "", fmt.Errorf("token inválido")
-	}
+	userID, _ := claims["user_id"].(string)
+	version, _ := claims["ver"].(float64)
+	return &auth.Claims{UserID: userID, TokenVersion: int(version)}, nil
+}
 
-	tokenType, _ := claims["type"].(string)
-	if tokenType != "refresh" {
-		return "", fmt.Errorf("tipo de token inválido")
+func (s *Service) ValidateRefreshToken(tokenStr string) (*auth.RefreshClaims, error) {
+	claims, err := s.parse(tokenStr, "refresh")
+	if err != nil {
+		return nil, err
 	}
 
 	userID, _ := claims["user_id"].(string)
-	return userID, nil
+	tokenID, _ := claims["jti"].(string)
+	return &auth.RefreshClaims{UserID: userID, TokenID: tokenID}, nil
 }
@@ -0,0 +1,31 @@
+package notificacion
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/tunek/centro-caribel/internal/domain"
+)
+
+// Log escribe los mensajes en el log del servidor en lugar de enviarlos. Sirve
+// para desarrollo o para instalaciones donde la Administradora entrega el
+// enlace en persona.
+type Log struct {
+	resetURL string
+}
+
+func NewLog(resetURL string) *Log {
+	return &Log{resetURL: resetURL}
+}
+
+func (l *Log) EnviarResetPassword(ctx context.Context, u *domain.Usuario, token string, vence time.Time) error {
+	destino := "token " + token
+	if l.resetURL != "" {
+		destino = l.resetURL + "?token=" + url.QueryEscape(token)
+	}
+	log.Printf("Restablecimiento de contraseña para %s (válido hasta %s): %s",
+		u.Email, vence.Format(time.RFC3339), destino)
+	return nil
+}
@@ -0,0 +1,18 @@
+package notificacion
+
+import (
+	"fmt"
+
+	"github.com/tunek/centro-caribel/internal/domain"
+	"github.com/tunek/centro-caribel/internal/infrastructure/config"
+)
+
+// New crea el notificador indicado en la configuración.
+func New(cfg config.NotifierConfig) (domain.Notificador, error) {
+	switch cfg.Driver {
+	case "", "log":
+		return NewLog(cfg.ResetURL), nil
+	default:
+		return nil, fmt.Errorf("NOTIFIER_DRIVER desconocido: %s", cfg.Driver)
+	}
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/tunek/centro-caribel/internal/domain"
+)
+
+type AdjuntoRepository struct {
+	db *sql.DB
+}
+
+func NewAdjuntoRepository(db *sql.DB) *AdjuntoRepository {
+	return &AdjuntoRepository{db: db}
+}
+
+const adjuntoColumns = `a.id, a.paciente_id, a.nota_id, a.descripcion, a.visibilidad, a.nombre_archivo,
+	a.content_type, a.tamano_bytes, a.sha256, a.storage_key, a.subido_por,
+	COALESCE(u.nombre_completo, ''), a.created_at`
+const adjuntoFrom = ` FROM adjuntos a LEFT JOIN usuarios u ON u.id = a.subido_por`
+
+func scanAdjunto(row interface{ Scan(dest ...any) error }) (*domain.Adjunto, error) {
+	var a domain.Adjunto
+	err := row.Scan(&a.ID, &a.PacienteID, &a.NotaID, &a.Descripcion, &a.Visibilidad, &a.NombreArchivo,
+		&a.ContentType, &a.TamanoBytes, &a.SHA256, &a.StorageKey, &a.SubidoPor,
+		&a.SubidoPorNombre, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *AdjuntoRepository) Create(ctx context.Context, a *domain.Adjunto) error {
+	return conexion(ctx, r.db).QueryRowContext(ctx,
+		`INSERT INTO adjuntos (id, paciente_id, nota_id, descripcion, visibilidad, nombre_archivo,
+		 content_type, tamano_bytes, sha256, storage_key, subido_por)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		 RETURNING created_at`,
+		a.ID, a.PacienteID, a.NotaID, a.Descripcion, a.Visibilidad, a.NombreArchivo,
+		a.ContentType, a.TamanoBytes, a.SHA256, a.StorageKey, a.SubidoPor).
+		Scan(&a.CreatedAt)
+}
+
+func (r *AdjuntoRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Adjunto, error) {
+	return scanAdjunto(r.db.QueryRowContext(ctx, `SELECT `+adjuntoColumns+adjuntoFrom+` WHERE a.id = $1`, id))
+}
+
+func (r *AdjuntoRepository) GetByPacienteID(ctx context.Context, pacienteID uuid.UUID, notaID *uuid.UUID, visibilidades []domain.VisibilidadAdjunto) ([]domain.Adjunto, error) {
+	permitidas := make([]string, len(visibilidades))
+	for i, v := range visibilidades {
+		permitidas[i] = string(v)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+adjuntoColumns+adjuntoFrom+`
+		 WHERE a.paciente_id = $1
+		   AND ($2::uuid IS NULL OR a.nota_id = $2)
+		   AND a.visibilidad = ANY($3)
+		 ORDER BY a.created_at DESC`, pacienteID, notaID, pq.Array(permitidas))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var adjuntos []domain.Adjunto
+	for rows.Next() {
+		a, err := scanAdjunto(rows)
+		if err != nil {
+			return nil, err
+		}
+		adjuntos = append(adjuntos, *a)
+	}
+	return adjuntos, rows.Err()
+}
+
+func (r *AdjuntoRepository) GetByChecksum(ctx context.Context, pacienteID uuid.UUID, sha256 string) (*domain.Adjunto, error) {
+	return scanAdjunto(r.db.QueryRowContext(ctx,
+		`SELECT `+adjuntoColumns+adjuntoFrom+` WHERE a.paciente_id = $1 AND a.sha256 = $2`, pacienteID, sha256))
+}
+
+func (r *AdjuntoRepository) CountByStorageKey(ctx context.Context, storageKey string) (int, error) {
+	var n int
+	err := conexion(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM adjuntos WHERE storage_key = $1`, storageKey).Scan(&n)
+	return n, err
+}
+
+func (r *AdjuntoRepository) BloquearArchivo(ctx context.Context, storageKey string) error {
+	_, err := conexion(ctx, r.db).ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, storageKey)
+	return err
+}
+
+func (r *AdjuntoRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	res, err := conexion(ctx, r.db).ExecContext(ctx, `DELETE FROM adjuntos WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
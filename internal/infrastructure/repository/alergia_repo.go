@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+)
+
+type AlergiaRepository struct {
+	db *sql.DB
+}
+
+func NewAlergiaRepository(db *sql.DB) *AlergiaRepository {
+	return &AlergiaRepository{db: db}
+}
+
+func (r *AlergiaRepository) Create(ctx context.Context, a *domain.Alergia) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO historia_alergias (id, historia_id, sustancia, reaccion, severidad, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		a.ID, a.HistoriaID, a.Sustancia, a.Reaccion, a.Severidad, a.CreatedBy)
+	return err
+}
+
+func (r *AlergiaRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Alergia, error) {
+	var a domain.Alergia
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, historia_id, sustancia, reaccion, severidad, created_by, created_at, updated_at
+		 FROM historia_alergias WHERE id = $1`, id).
+		Scan(&a.ID, &a.HistoriaID, &a.Sustancia, &a.Reaccion, &a.Severidad, &a.CreatedBy, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *AlergiaRepository) GetByHistoriaID(ctx context.Context, historiaID uuid.UUID) ([]domain.Alergia, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, historia_id, sustancia, reaccion, severidad, created_by, created_at, updated_at
+		 FROM historia_alergias WHERE historia_id = $1
+		 ORDER BY CASE severidad WHEN 'SEVERA' THEN 1 WHEN 'MODERADA' THEN 2 ELSE 3 END, sustancia`, historiaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var alergias []domain.Alergia
+	for rows.Next() {
+		var a domain.Alergia
+		if err := rows.Scan(&a.ID, &a.HistoriaID, &a.Sustancia, &a.Reaccion, &a.Severidad, &a.CreatedBy, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		alergias = append(alergias, a)
+	}
+	return alergias, nil
+}
+
+func (r *AlergiaRepository) Update(ctx context.Context, a *domain.Alergia) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE historia_alergias SET sustancia = $1, reaccion = $2, severidad = $3 WHERE id = $4`,
+		a.Sustancia, a.Reaccion, a.Severidad, a.ID)
+	return err
+}
+
+func (r *AlergiaRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM historia_alergias WHERE id = $1", id)
+	return err
+}
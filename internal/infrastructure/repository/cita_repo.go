@@ -137,16 +137,36 @@ func (r *CitaRepository) GetByFecha(ctx context.Context, fecha time.Time) ([]dom
 	return citas, nil
 }
 
-func (r *CitaRepository) UpdateEstado(ctx context.Context, id uuid.UUID, estado domain.EstadoCita) error {
-	_, err := r.db.ExecContext(ctx, "UPDATE citas SET estado = $1 WHERE id = $2", estado, id)
-	return err
+func (r *CitaRepository) UpdateEstado(ctx context.Context, id uuid.UUID, estado domain.EstadoCita, cambiadoPor uuid.UUID) error {
+	return r.cambiarEstado(ctx, id, estado, cambiadoPor,
+		"UPDATE citas SET estado = $1 WHERE id = $2", estado, id)
 }
 
-func (r *CitaRepository) Reagendar(ctx context.Context, id uuid.UUID, fecha time.Time, hora string, turno domain.TurnoCita) error {
-	_, err := r.db.ExecContext(ctx,
+func (r *CitaRepository) Reagendar(ctx context.Context, id uuid.UUID, fecha time.Time, hora string, turno domain.TurnoCita, cambiadoPor uuid.UUID) error {
+	return r.cambiarEstado(ctx, id, domain.EstadoReagendada, cambiadoPor,
 		"UPDATE citas SET estado = $1, fecha = $2, hora = $3, turno = $4 WHERE id = $5",
 		domain.EstadoReagendada, fecha, hora, turno, id)
-	return err
+}
+
+// cambiarEstado ejecuta la actualización y registra el cambio en el historial
+// dentro de la misma transacción, o de la que ya lleve ctx.
+func (r *CitaRepository) cambiarEstado(ctx context.Context, id uuid.UUID, nuevo domain.EstadoCita, cambiadoPor uuid.UUID, query string, args ...interface{}) error {
+	return enTransaccion(ctx, r.db, func(ctx context.Context, tx ejecutor) error {
+		var anterior domain.EstadoCita
+		if err := tx.QueryRowContext(ctx, "SELECT estado FROM citas WHERE id = $1 FOR UPDATE", id).Scan(&anterior); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO citas_estados_historial (id, cita_id, estado_anterior, estado_nuevo, cambiado_por)
+			 VALUES ($1, $2, $3, $4, $5)`,
+			uuid.New(), id, anterior, nuevo, cambiadoPor)
+		return err
+	})
 }
 
 func (r *CitaRepository) ExistsByFechaHora(ctx context.Context, fecha time.Time, hora string, excludeID *uuid.UUID) (bool, error) {
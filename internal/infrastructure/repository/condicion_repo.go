@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+)
+
+type CondicionRepository struct {
+	db *sql.DB
+}
+
+func NewCondicionRepository(db *sql.DB) *CondicionRepository {
+	return &CondicionRepository{db: db}
+}
+
+func (r *CondicionRepository) Create(ctx context.Context, c *domain.Condicion) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO historia_condiciones (id, historia_id, tipo, codigo, descripcion, parentesco, observaciones, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		c.ID, c.HistoriaID, c.Tipo, c.Codigo, c.Descripcion, c.Parentesco, c.Observaciones, c.CreatedBy)
+	return err
+}
+
+func (r *CondicionRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Condicion, error) {
+	var c domain.Condicion
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, historia_id, tipo, codigo, descripcion, parentesco, observaciones, created_by, created_at, updated_at
+		 FROM historia_condiciones WHERE id = $1`, id).
+		Scan(&c.ID, &c.HistoriaID, &c.Tipo, &c.Codigo, &c.Descripcion, &c.Parentesco, &c.Observaciones, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *CondicionRepository) GetByHistoriaID(ctx context.Context, historiaID uuid.UUID) ([]domain.Condicion, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, historia_id, tipo, codigo, descripcion, parentesco, observaciones, created_by, created_at, updated_at
+		 FROM historia_condiciones WHERE historia_id = $1 ORDER BY tipo DESC, created_at`, historiaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var condiciones []domain.Condicion
+	for rows.Next() {
+		var c domain.Condicion
+		if err := rows.Scan(&c.ID, &c.HistoriaID, &c.Tipo, &c.Codigo, &c.Descripcion, &c.Parentesco, &c.Observaciones, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		condiciones = append(condiciones, c)
+	}
+	return condiciones, nil
+}
+
+func (r *CondicionRepository) Update(ctx context.Context, c *domain.Condicion) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE historia_condiciones SET tipo = $1, codigo = $2, descripcion = $3, parentesco = $4, observaciones = $5
+		 WHERE id = $6`,
+		c.Tipo, c.Codigo, c.Descripcion, c.Parentesco, c.Observaciones, c.ID)
+	return err
+}
+
+func (r *CondicionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM historia_condiciones WHERE id = $1", id)
+	return err
+}
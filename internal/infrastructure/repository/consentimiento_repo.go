@@ -5,6 +5,7 @@ import (
 	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/tunek/centro-caribel/internal/domain"
 )
 
@@ -16,30 +17,52 @@ func NewConsentimientoRepository(db *sql.DB) *ConsentimientoRepository {
 	return &ConsentimientoRepository{db: db}
 }
 
+const consentimientoColumns = `c.id, c.paciente_id, c.fecha_firma, c.firma_digital, COALESCE(c.firma_tipo, ''), c.autoriza_fotos, c.contenido,
+	c.contenido_sha256, COALESCE(c.sello, ''), c.sello_version, c.plantilla_version_id, COALESCE(pc.nombre, ''), pv.version,
+	c.tratamiento, c.fecha_vencimiento,
+	c.tutor_id, COALESCE(t.nombre_completo, ''), c.registrado_por, c.created_at`
+const consentimientoFrom = `consentimientos c LEFT JOIN tutores t ON c.tutor_id = t.id
+	LEFT JOIN plantillas_consentimiento_versiones pv ON pv.id = c.plantilla_version_id
+	LEFT JOIN plantillas_consentimiento pc ON pc.id = pv.plantilla_id`
+
+func scanConsentimiento(row interface{ Scan(dest ...any) error }) (*domain.Consentimiento, error) {
+	var c domain.Consentimiento
+	err := row.Scan(&c.ID, &c.PacienteID, &c.FechaFirma, &c.FirmaDigital, &c.FirmaTipo, &c.AutorizaFotos, &c.Contenido,
+		&c.ContenidoSHA256, &c.Sello, &c.SelloVersion, &c.PlantillaVersionID, &c.PlantillaNombre, &c.PlantillaVersion,
+		&c.Tratamiento, &c.FechaVencimiento,
+		&c.TutorID, &c.TutorNombre, &c.RegistradoPor, &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
 func (r *ConsentimientoRepository) Create(ctx context.Context, c *domain.Consentimiento) error {
-	_, err := r.db.ExecContext(ctx,
-		`INSERT INTO consentimientos (id, paciente_id, fecha_firma, firma_digital, autoriza_fotos, contenido, registrado_por)
-		 VALUES ($1, $2, NOW(), $3, $4, $5, $6)`,
-		c.ID, c.PacienteID, c.FirmaDigital, c.AutorizaFotos, c.Contenido, c.RegistradoPor)
-	return err
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO consentimientos (id, paciente_id, fecha_firma, firma_digital, firma_tipo, autoriza_fotos, contenido,
+		 contenido_sha256, sello, sello_version, plantilla_version_id, tratamiento, fecha_vencimiento, tutor_id, registrado_por)
+		 VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		 RETURNING created_at`,
+		c.ID, c.PacienteID, c.FechaFirma, c.FirmaDigital, c.FirmaTipo, c.AutorizaFotos, c.Contenido,
+		c.ContenidoSHA256, c.Sello, c.SelloVersion, c.PlantillaVersionID, c.Tratamiento, c.FechaVencimiento, c.TutorID, c.RegistradoPor).
+		Scan(&c.CreatedAt)
 }
 
 func (r *ConsentimientoRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Consentimiento, error) {
-	var c domain.Consentimiento
-	err := r.db.QueryRowContext(ctx,
-		`SELECT id, paciente_id, fecha_firma, firma_digital, autoriza_fotos, contenido, registrado_por, created_at
-		 FROM consentimientos WHERE id = $1`, id).
-		Scan(&c.ID, &c.PacienteID, &c.FechaFirma, &c.FirmaDigital, &c.AutorizaFotos, &c.Contenido, &c.RegistradoPor, &c.CreatedAt)
+	c, err := scanConsentimiento(r.db.QueryRowContext(ctx,
+		`SELECT `+consentimientoColumns+` FROM `+consentimientoFrom+` WHERE c.id = $1`, id))
 	if err != nil {
 		return nil, err
 	}
-	return &c, nil
+	if err := r.cargarRevocaciones(ctx, []*domain.Consentimiento{c}); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
 func (r *ConsentimientoRepository) GetByPacienteID(ctx context.Context, pacienteID uuid.UUID) ([]domain.Consentimiento, error) {
 	rows, err := r.db.QueryContext(ctx,
-		`SELECT id, paciente_id, fecha_firma, firma_digital, autoriza_fotos, contenido, registrado_por, created_at
-		 FROM consentimientos WHERE paciente_id = $1 ORDER BY created_at DESC`, pacienteID)
+		`SELECT `+consentimientoColumns+` FROM `+consentimientoFrom+` WHERE c.paciente_id = $1 ORDER BY c.fecha_firma DESC, c.created_at DESC`, pacienteID)
 	if err != nil {
 		return nil, err
 	}
@@ -47,11 +70,66 @@ func (r *ConsentimientoRepository) GetByPacienteID(ctx context.Context, paciente
 
 	var list []domain.Consentimiento
 	for rows.Next() {
-		var c domain.Consentimiento
-		if err := rows.Scan(&c.ID, &c.PacienteID, &c.FechaFirma, &c.FirmaDigital, &c.AutorizaFotos, &c.Contenido, &c.RegistradoPor, &c.CreatedAt); err != nil {
+		c, err := scanConsentimiento(rows)
+		if err != nil {
 			return nil, err
 		}
-		list = append(list, c)
+		list = append(list, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ptrs := make([]*domain.Consentimiento, len(list))
+	for i := range list {
+		ptrs[i] = &list[i]
+	}
+	if err := r.cargarRevocaciones(ctx, ptrs); err != nil {
+		return nil, err
 	}
 	return list, nil
 }
+
+func (r *ConsentimientoRepository) Revocar(ctx context.Context, rev *domain.RevocacionConsentimiento) error {
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO consentimiento_revocaciones (id, consentimiento_id, alcance, fecha_revocacion, motivo, registrado_por)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING created_at`,
+		rev.ID, rev.ConsentimientoID, rev.Alcance, rev.FechaRevocacion, rev.Motivo, rev.RegistradoPor).
+		Scan(&rev.CreatedAt)
+}
+
+// cargarRevocaciones completa las revocaciones de los consentimientos dados.
+func (r *ConsentimientoRepository) cargarRevocaciones(ctx context.Context, consentimientos []*domain.Consentimiento) error {
+	if len(consentimientos) == 0 {
+		return nil
+	}
+	ids := make([]string, len(consentimientos))
+	porID := make(map[uuid.UUID]*domain.Consentimiento, len(consentimientos))
+	for i, c := range consentimientos {
+		ids[i] = c.ID.String()
+		porID[c.ID] = c
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT r.id, r.consentimiento_id, r.alcance, r.fecha_revocacion, r.motivo, r.registrado_por,
+		 COALESCE(u.nombre_completo, ''), r.created_at
+		 FROM consentimiento_revocaciones r LEFT JOIN usuarios u ON u.id = r.registrado_por
+		 WHERE r.consentimiento_id = ANY($1::uuid[])
+		 ORDER BY r.fecha_revocacion`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rev domain.RevocacionConsentimiento
+		if err := rows.Scan(&rev.ID, &rev.ConsentimientoID, &rev.Alcance, &rev.FechaRevocacion, &rev.Motivo,
+			&rev.RegistradoPor, &rev.RegistradoPorNombre, &rev.CreatedAt); err != nil {
+			return err
+		}
+		c := porID[rev.ConsentimientoID]
+		c.Revocaciones = append(c.Revocaciones, rev)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+)
+
+type FotoRepository struct {
+	db *sql.DB
+}
+
+func NewFotoRepository(db *sql.DB) *FotoRepository {
+	return &FotoRepository{db: db}
+}
+
+const fotoColumns = `f.id, f.paciente_id, f.consentimiento_id, f.cita_id, f.paquete_id, f.zona, f.momento, f.descripcion,
+	f.storage_key, f.nombre_archivo, f.content_type, f.tamano_bytes, f.tomada_en, f.tomada_por,
+	COALESCE(u.nombre_completo, ''), f.created_at`
+const fotoFrom = ` FROM fotos f LEFT JOIN usuarios u ON u.id = f.tomada_por`
+
+func scanFoto(row interface{ Scan(dest ...any) error }) (*domain.Foto, error) {
+	var f domain.Foto
+	err := row.Scan(&f.ID, &f.PacienteID, &f.ConsentimientoID, &f.CitaID, &f.PaqueteID, &f.Zona, &f.Momento, &f.Descripcion,
+		&f.StorageKey, &f.NombreArchivo, &f.ContentType, &f.TamanoBytes, &f.TomadaEn, &f.TomadaPor,
+		&f.TomadaPorNombre, &f.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r *FotoRepository) Create(ctx context.Context, f *domain.Foto) error {
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO fotos (id, paciente_id, consentimiento_id, cita_id, paquete_id, zona, momento, descripcion,
+		 storage_key, nombre_archivo, content_type, tamano_bytes, tomada_en, tomada_por)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		 RETURNING created_at`,
+		f.ID, f.PacienteID, f.ConsentimientoID, f.CitaID, f.PaqueteID, f.Zona, f.Momento, f.Descripcion,
+		f.StorageKey, f.NombreArchivo, f.ContentType, f.TamanoBytes, f.TomadaEn, f.TomadaPor).
+		Scan(&f.CreatedAt)
+}
+
+func (r *FotoRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Foto, error) {
+	return scanFoto(r.db.QueryRowContext(ctx, `SELECT `+fotoColumns+fotoFrom+` WHERE f.id = $1`, id))
+}
+
+func (r *FotoRepository) GetByPacienteID(ctx context.Context, pacienteID uuid.UUID, zona string, paqueteID *uuid.UUID) ([]domain.Foto, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+fotoColumns+fotoFrom+`
+		 WHERE f.paciente_id = $1
+		   AND ($2 = '' OR lower(f.zona) = lower($2))
+		   AND ($3::uuid IS NULL OR f.paquete_id = $3)
+		 ORDER BY f.tomada_en`, pacienteID, zona, paqueteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fotos []domain.Foto
+	for rows.Next() {
+		f, err := scanFoto(rows)
+		if err != nil {
+			return nil, err
+		}
+		fotos = append(fotos, *f)
+	}
+	return fotos, rows.Err()
+}
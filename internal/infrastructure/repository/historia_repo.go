@@ -18,23 +18,38 @@ func NewHistoriaClinicaRepository(db *sql.DB) *HistoriaClinicaRepository {
 }
 
 func (r *HistoriaClinicaRepository) Create(ctx context.Context, h *domain.HistoriaClinica) error {
-	_, err := r.db.ExecContext(ctx,
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
 		`INSERT INTO historias_clinicas (id, paciente_id, numero_historia, estado,
 		 antecedentes_personales, antecedentes_familiares, alergias, medicamentos_actuales)
 		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
 		h.ID, h.PacienteID, h.NumeroHistoria, h.Estado,
-		h.AntecedentesPersonales, h.AntecedentesFamiliares, h.Alergias, h.MedicamentosActuales)
-	return err
+		h.AntecedentesPersonales, h.AntecedentesFamiliares, h.Alergias, h.MedicamentosActuales); err != nil {
+		return err
+	}
+
+	if err := insertVersion(ctx, tx, h, 1, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (r *HistoriaClinicaRepository) GetByPacienteID(ctx context.Context, pacienteID uuid.UUID) (*domain.HistoriaClinica, error) {
 	var h domain.HistoriaClinica
 	err := r.db.QueryRowContext(ctx,
-		`SELECT id, paciente_id, numero_historia, estado,
+		`SELECT id, paciente_id, numero_historia, estado, COALESCE(motivo_estado, ''),
+		 estado_cambiado_por, estado_cambiado_at,
 		 antecedentes_personales, antecedentes_familiares, alergias, medicamentos_actuales,
 		 created_at, updated_at
 		 FROM historias_clinicas WHERE paciente_id = $1`, pacienteID).
-		Scan(&h.ID, &h.PacienteID, &h.NumeroHistoria, &h.Estado,
+		Scan(&h.ID, &h.PacienteID, &h.NumeroHistoria, &h.Estado, &h.MotivoEstado,
+			&h.EstadoCambiadoPor, &h.EstadoCambiadoAt,
 			&h.AntecedentesPersonales, &h.AntecedentesFamiliares, &h.Alergias, &h.MedicamentosActuales,
 			&h.CreatedAt, &h.UpdatedAt)
 	if err != nil {
@@ -43,16 +58,93 @@ func (r *HistoriaClinicaRepository) GetByPacienteID(ctx context.Context, pacient
 	return &h, nil
 }
 
-func (r *HistoriaClinicaRepository) UpdateAntecedentes(ctx context.Context, h *domain.HistoriaClinica) error {
-	_, err := r.db.ExecContext(ctx,
+// UpdateAntecedentes guarda los nuevos valores y registra la versión
+// correspondiente en la misma transacción.
+func (r *HistoriaClinicaRepository) UpdateAntecedentes(ctx context.Context, h *domain.HistoriaClinica, autorID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
 		`UPDATE historias_clinicas SET
 		 antecedentes_personales = $1, antecedentes_familiares = $2,
 		 alergias = $3, medicamentos_actuales = $4
 		 WHERE id = $5`,
-		h.AntecedentesPersonales, h.AntecedentesFamiliares, h.Alergias, h.MedicamentosActuales, h.ID)
+		h.AntecedentesPersonales, h.AntecedentesFamiliares, h.Alergias, h.MedicamentosActuales, h.ID); err != nil {
+		return err
+	}
+
+	// El UPDATE anterior bloquea la fila de la historia, por lo que el cálculo
+	// del siguiente número de versión no compite con otra transacción.
+	var version int
+	if err := tx.QueryRowContext(ctx,
+		`SELECT COALESCE(MAX(version), 0) + 1 FROM historia_antecedentes_versiones WHERE historia_id = $1`,
+		h.ID).Scan(&version); err != nil {
+		return err
+	}
+
+	if err := insertVersion(ctx, tx, h, version, &autorID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertVersion(ctx context.Context, tx *sql.Tx, h *domain.HistoriaClinica, version int, autorID *uuid.UUID) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO historia_antecedentes_versiones (id, historia_id, version,
+		 antecedentes_personales, antecedentes_familiares, alergias, medicamentos_actuales, autor_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		uuid.New(), h.ID, version,
+		h.AntecedentesPersonales, h.AntecedentesFamiliares, h.Alergias, h.MedicamentosActuales, autorID)
 	return err
 }
 
+const versionColumns = `v.id, v.historia_id, v.version,
+	v.antecedentes_personales, v.antecedentes_familiares, v.alergias, v.medicamentos_actuales,
+	v.autor_id, COALESCE(u.nombre_completo, ''), v.created_at`
+
+func scanVersion(row interface{ Scan(dest ...any) error }) (*domain.VersionAntecedentes, error) {
+	var v domain.VersionAntecedentes
+	err := row.Scan(&v.ID, &v.HistoriaID, &v.Version,
+		&v.AntecedentesPersonales, &v.AntecedentesFamiliares, &v.Alergias, &v.MedicamentosActuales,
+		&v.AutorID, &v.AutorNombre, &v.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *HistoriaClinicaRepository) GetVersiones(ctx context.Context, historiaID uuid.UUID) ([]domain.VersionAntecedentes, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+versionColumns+`
+		 FROM historia_antecedentes_versiones v LEFT JOIN usuarios u ON u.id = v.autor_id
+		 WHERE v.historia_id = $1 ORDER BY v.version DESC`, historiaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versiones []domain.VersionAntecedentes
+	for rows.Next() {
+		v, err := scanVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versiones = append(versiones, *v)
+	}
+	return versiones, rows.Err()
+}
+
+func (r *HistoriaClinicaRepository) GetVersion(ctx context.Context, historiaID uuid.UUID, version int) (*domain.VersionAntecedentes, error) {
+	return scanVersion(r.db.QueryRowContext(ctx,
+		`SELECT `+versionColumns+`
+		 FROM historia_antecedentes_versiones v LEFT JOIN usuarios u ON u.id = v.autor_id
+		 WHERE v.historia_id = $1 AND v.version = $2`, historiaID, version))
+}
+
 func (r *HistoriaClinicaRepository) NextNumero(ctx context.Context) (string, error) {
 	var seq int
 	err := r.db.QueryRowContext(ctx, "SELECT nextval('historias_numero_seq')").Scan(&seq)
@@ -61,3 +153,18 @@ func (r *HistoriaClinicaRepository) NextNumero(ctx context.Context) (string, err
 	}
 	return fmt.Sprintf("HC-%05d", seq), nil
 }
+
+func (r *HistoriaClinicaRepository) CambiarEstado(ctx context.Context, id uuid.UUID, estado domain.EstadoHistoria, motivo string, cambiadoPor uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE historias_clinicas SET estado = $1, motivo_estado = $2,
+		 estado_cambiado_por = $3, estado_cambiado_at = NOW()
+		 WHERE id = $4`, estado, motivo, cambiadoPor, id)
+	if err != nil {
+		return err
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
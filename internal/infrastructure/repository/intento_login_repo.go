@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/tunek/centro-caribel/internal/domain"
+)
+
+type IntentoLoginRepository struct {
+	db *sql.DB
+}
+
+func NewIntentoLoginRepository(db *sql.DB) *IntentoLoginRepository {
+	return &IntentoLoginRepository{db: db}
+}
+
+func (r *IntentoLoginRepository) Create(ctx context.Context, i *domain.IntentoLogin) error {
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO intentos_login (email, usuario_id, exitoso, motivo, ip, user_agent)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id, created_at`,
+		i.Email, i.UsuarioID, i.Exitoso, i.Motivo, i.IP, i.UserAgent).Scan(&i.ID, &i.CreatedAt)
+}
+
+func (r *IntentoLoginRepository) GetAll(ctx context.Context, filtro domain.FiltroIntentosLogin, offset, limit int) ([]domain.IntentoLogin, int64, error) {
+	where := "WHERE 1=1"
+	args := []interface{}{}
+	argIdx := 1
+
+	if filtro.Email != nil {
+		where += fmt.Sprintf(" AND email = $%d", argIdx)
+		args = append(args, *filtro.Email)
+		argIdx++
+	}
+	if filtro.UsuarioID != nil {
+		where += fmt.Sprintf(" AND usuario_id = $%d", argIdx)
+		args = append(args, *filtro.UsuarioID)
+		argIdx++
+	}
+	if filtro.IP != nil {
+		where += fmt.Sprintf(" AND ip = $%d", argIdx)
+		args = append(args, *filtro.IP)
+		argIdx++
+	}
+	if filtro.Exitoso != nil {
+		where += fmt.Sprintf(" AND exitoso = $%d", argIdx)
+		args = append(args, *filtro.Exitoso)
+		argIdx++
+	}
+	if filtro.Desde != nil {
+		where += fmt.Sprintf(" AND created_at >= $%d", argIdx)
+		args = append(args, *filtro.Desde)
+		argIdx++
+	}
+	if filtro.Hasta != nil {
+		where += fmt.Sprintf(" AND created_at < $%d", argIdx)
+		args = append(args, *filtro.Hasta)
+		argIdx++
+	}
+
+	var total int64
+	countArgs := make([]interface{}, len(args))
+	copy(countArgs, args)
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM intentos_login "+where, countArgs...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(
+		`SELECT id, email, usuario_id, exitoso, motivo, ip, user_agent, created_at
+		 FROM intentos_login %s ORDER BY created_at DESC LIMIT $%d OFFSET $%d`,
+		where, argIdx, argIdx+1)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var intentos []domain.IntentoLogin
+	for rows.Next() {
+		var i domain.IntentoLogin
+		if err := rows.Scan(&i.ID, &i.Email, &i.UsuarioID, &i.Exitoso, &i.Motivo, &i.IP, &i.UserAgent, &i.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		intentos = append(intentos, i)
+	}
+	return intentos, total, nil
+}
+
+func (r *IntentoLoginRepository) CountFallidosPorIP(ctx context.Context, ip string, desde time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM intentos_login
+		 WHERE ip = $1 AND motivo IN ('CREDENCIALES', 'CODIGO_2FA', 'PASSWORD_ACTUAL') AND created_at > $2`, ip, desde).Scan(&n)
+	return n, err
+}
+
+func (r *IntentoLoginRepository) FallidosSinUsuario(ctx context.Context, email string, desde time.Time) (int, *time.Time, error) {
+	var n int
+	var ultimo *time.Time
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*), MAX(created_at) FROM intentos_login
+		 WHERE email = $1 AND usuario_id IS NULL AND motivo = 'CREDENCIALES' AND created_at > $2`,
+		email, desde).Scan(&n, &ultimo)
+	return n, ultimo, err
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+)
+
+type MedicamentoRepository struct {
+	db *sql.DB
+}
+
+func NewMedicamentoRepository(db *sql.DB) *MedicamentoRepository {
+	return &MedicamentoRepository{db: db}
+}
+
+const medicamentoColumns = `id, historia_id, nombre, dosis, frecuencia, fecha_inicio, fecha_fin, indicacion, created_by, created_at, updated_at`
+
+func scanMedicamento(row interface{ Scan(dest ...any) error }) (domain.Medicamento, error) {
+	var m domain.Medicamento
+	err := row.Scan(&m.ID, &m.HistoriaID, &m.Nombre, &m.Dosis, &m.Frecuencia, &m.FechaInicio, &m.FechaFin, &m.Indicacion, &m.CreatedBy, &m.CreatedAt, &m.UpdatedAt)
+	if err == nil {
+		m.Activo = m.ActivoEn(time.Now())
+	}
+	return m, err
+}
+
+func (r *MedicamentoRepository) Create(ctx context.Context, m *domain.Medicamento) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO historia_medicamentos (id, historia_id, nombre, dosis, frecuencia, fecha_inicio, fecha_fin, indicacion, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		m.ID, m.HistoriaID, m.Nombre, m.Dosis, m.Frecuencia, m.FechaInicio, m.FechaFin, m.Indicacion, m.CreatedBy)
+	return err
+}
+
+func (r *MedicamentoRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Medicamento, error) {
+	m, err := scanMedicamento(r.db.QueryRowContext(ctx,
+		`SELECT `+medicamentoColumns+` FROM historia_medicamentos WHERE id = $1`, id))
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *MedicamentoRepository) GetByHistoriaID(ctx context.Context, historiaID uuid.UUID) ([]domain.Medicamento, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+medicamentoColumns+` FROM historia_medicamentos WHERE historia_id = $1
+		 ORDER BY fecha_inicio DESC, nombre`, historiaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var medicamentos []domain.Medicamento
+	for rows.Next() {
+		m, err := scanMedicamento(rows)
+		if err != nil {
+			return nil, err
+		}
+		medicamentos = append(medicamentos, m)
+	}
+	return medicamentos, nil
+}
+
+func (r *MedicamentoRepository) Update(ctx context.Context, m *domain.Medicamento) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE historia_medicamentos SET nombre = $1, dosis = $2, frecuencia = $3,
+		 fecha_inicio = $4, fecha_fin = $5, indicacion = $6 WHERE id = $7`,
+		m.Nombre, m.Dosis, m.Frecuencia, m.FechaInicio, m.FechaFin, m.Indicacion, m.ID)
+	return err
+}
+
+func (r *MedicamentoRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM historia_medicamentos WHERE id = $1", id)
+	return err
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+)
+
+type MedicionRepository struct {
+	db *sql.DB
+}
+
+func NewMedicionRepository(db *sql.DB) *MedicionRepository {
+	return &MedicionRepository{db: db}
+}
+
+// columnasMetrica traduce cada métrica a su columna. Solo se interpolan en SQL
+// valores de este mapa.
+var columnasMetrica = map[domain.MetricaMedicion]string{
+	domain.MetricaPeso:               "peso_kg",
+	domain.MetricaTalla:              "talla_cm",
+	domain.MetricaIMC:                "imc",
+	domain.MetricaCintura:            "cintura_cm",
+	domain.MetricaCadera:             "cadera_cm",
+	domain.MetricaAbdomen:            "abdomen_cm",
+	domain.MetricaPresionSistolica:   "presion_sistolica",
+	domain.MetricaPresionDiastolica:  "presion_diastolica",
+	domain.MetricaFrecuenciaCardiaca: "frecuencia_cardiaca",
+	domain.MetricaDolor:              "escala_dolor",
+}
+
+const medicionColumns = `id, historia_id, cita_id, paquete_id, fecha,
+	peso_kg, talla_cm, imc, cintura_cm, cadera_cm, abdomen_cm,
+	presion_sistolica, presion_diastolica, frecuencia_cardiaca, escala_dolor,
+	observaciones, created_by, created_at`
+
+func scanMedicion(row interface{ Scan(dest ...any) error }) (*domain.Medicion, error) {
+	var m domain.Medicion
+	err := row.Scan(&m.ID, &m.HistoriaID, &m.CitaID, &m.PaqueteID, &m.Fecha,
+		&m.PesoKg, &m.TallaCm, &m.IMC, &m.CinturaCm, &m.CaderaCm, &m.AbdomenCm,
+		&m.PresionSistolica, &m.PresionDiastolica, &m.FrecuenciaCardiaca, &m.EscalaDolor,
+		&m.Observaciones, &m.CreatedBy, &m.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *MedicionRepository) Create(ctx context.Context, m *domain.Medicion) error {
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO mediciones (id, historia_id, cita_id, paquete_id, fecha,
+		 peso_kg, talla_cm, imc, cintura_cm, cadera_cm, abdomen_cm,
+		 presion_sistolica, presion_diastolica, frecuencia_cardiaca, escala_dolor,
+		 observaciones, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		 RETURNING created_at`,
+		m.ID, m.HistoriaID, m.CitaID, m.PaqueteID, m.Fecha,
+		m.PesoKg, m.TallaCm, m.IMC, m.CinturaCm, m.CaderaCm, m.AbdomenCm,
+		m.PresionSistolica, m.PresionDiastolica, m.FrecuenciaCardiaca, m.EscalaDolor,
+		m.Observaciones, m.CreatedBy).Scan(&m.CreatedAt)
+}
+
+func (r *MedicionRepository) GetByHistoriaID(ctx context.Context, historiaID uuid.UUID, paqueteID *uuid.UUID) ([]domain.Medicion, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+medicionColumns+` FROM mediciones
+		 WHERE historia_id = $1 AND ($2::uuid IS NULL OR paquete_id = $2)
+		 ORDER BY fecha DESC`, historiaID, paqueteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mediciones []domain.Medicion
+	for rows.Next() {
+		m, err := scanMedicion(rows)
+		if err != nil {
+			return nil, err
+		}
+		mediciones = append(mediciones, *m)
+	}
+	return mediciones, rows.Err()
+}
+
+func (r *MedicionRepository) GetSerie(ctx context.Context, historiaID uuid.UUID, metrica domain.MetricaMedicion, paqueteID *uuid.UUID) ([]domain.PuntoSerie, error) {
+	col, ok := columnasMetrica[metrica]
+	if !ok {
+		return nil, fmt.Errorf("métrica desconocida: %s", metrica)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, fecha, `+col+`::float8, paquete_id FROM mediciones
+		 WHERE historia_id = $1 AND `+col+` IS NOT NULL AND ($2::uuid IS NULL OR paquete_id = $2)
+		 ORDER BY fecha`, historiaID, paqueteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var puntos []domain.PuntoSerie
+	for rows.Next() {
+		var p domain.PuntoSerie
+		if err := rows.Scan(&p.MedicionID, &p.Fecha, &p.Valor, &p.PaqueteID); err != nil {
+			return nil, err
+		}
+		puntos = append(puntos, p)
+	}
+	return puntos, rows.Err()
+}
+
+func (r *MedicionRepository) UltimaTalla(ctx context.Context, historiaID uuid.UUID) (*float64, error) {
+	var talla float64
+	err := r.db.QueryRowContext(ctx,
+		`SELECT talla_cm::float8 FROM mediciones
+		 WHERE historia_id = $1 AND talla_cm IS NOT NULL
+		 ORDER BY fecha DESC LIMIT 1`, historiaID).Scan(&talla)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &talla, nil
+}
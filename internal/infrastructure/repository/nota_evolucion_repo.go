@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 
 	"github.com/google/uuid"
 	"github.com/tunek/centro-caribel/internal/domain"
@@ -16,19 +17,63 @@ func NewNotaEvolucionRepository(db *sql.DB) *NotaEvolucionRepository {
 	return &NotaEvolucionRepository{db: db}
 }
 
+// El número de sesión cuenta las citas atendidas del paquete hasta la cita de
+// la nota, incluida esta aunque todavía no esté marcada como ATENDIDA.
+const notaColumns = `n.id, n.historia_id, n.tipo, n.contenido, n.estado, n.firmado_por, n.firmado_at,
+	n.nota_original_id, n.cita_id, n.paquete_id,
+	CASE WHEN c.id IS NOT NULL AND p.id IS NOT NULL THEN
+		(SELECT COUNT(*) FROM citas c2
+		 WHERE c2.paquete_id = n.paquete_id
+		   AND (c2.estado = 'ATENDIDA' OR c2.id = c.id)
+		   AND (c2.fecha, c2.hora) <= (c.fecha, c.hora))::int
+	END,
+	p.total_sesiones,
+	n.plantilla_id, n.datos,
+	n.created_by, n.created_at, n.updated_at`
+
+const notaFrom = ` FROM notas_evolucion n
+	LEFT JOIN citas c ON c.id = n.cita_id
+	LEFT JOIN paquetes_tratamiento p ON p.id = n.paquete_id`
+
+func scanNota(row interface{ Scan(dest ...any) error }) (*domain.NotaEvolucion, error) {
+	var n domain.NotaEvolucion
+	var numero, total sql.NullInt64
+	err := row.Scan(&n.ID, &n.HistoriaID, &n.Tipo, &n.Contenido, &n.Estado, &n.FirmadoPor, &n.FirmadoAt,
+		&n.NotaOriginalID, &n.CitaID, &n.PaqueteID, &numero, &total,
+		&n.PlantillaID, &n.Datos,
+		&n.CreatedBy, &n.CreatedAt, &n.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if numero.Valid && total.Valid {
+		num, tot := int(numero.Int64), int(total.Int64)
+		n.NumeroSesion = &num
+		n.TotalSesiones = &tot
+		n.Sesion = n.ResumenSesion()
+	}
+	return &n, nil
+}
+
 func (r *NotaEvolucionRepository) Create(ctx context.Context, n *domain.NotaEvolucion) error {
-	_, err := r.db.ExecContext(ctx,
-		`INSERT INTO notas_evolucion (id, historia_id, tipo, contenido, created_by)
-		 VALUES ($1, $2, $3, $4, $5)`,
-		n.ID, n.HistoriaID, n.Tipo, n.Contenido, n.CreatedBy)
-	return err
+	return conexion(ctx, r.db).QueryRowContext(ctx,
+		`INSERT INTO notas_evolucion (id, historia_id, tipo, contenido, estado, firmado_por, firmado_at,
+		 nota_original_id, cita_id, paquete_id, plantilla_id, datos, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		 RETURNING created_at, updated_at`,
+		n.ID, n.HistoriaID, n.Tipo, n.Contenido, n.Estado, n.FirmadoPor, n.FirmadoAt,
+		n.NotaOriginalID, n.CitaID, n.PaqueteID, n.PlantillaID, nullJSON(n.Datos), n.CreatedBy).Scan(&n.CreatedAt, &n.UpdatedAt)
+}
+
+func (r *NotaEvolucionRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.NotaEvolucion, error) {
+	return scanNota(r.db.QueryRowContext(ctx,
+		`SELECT `+notaColumns+notaFrom+` WHERE n.id = $1`, id))
 }
 
 func (r *NotaEvolucionRepository) GetByHistoriaID(ctx context.Context, historiaID uuid.UUID) ([]domain.NotaEvolucion, error) {
 	rows, err := r.db.QueryContext(ctx,
-		`SELECT id, historia_id, tipo, contenido, created_by, created_at
-		 FROM notas_evolucion WHERE historia_id = $1
-		 ORDER BY created_at DESC`, historiaID)
+		`SELECT `+notaColumns+notaFrom+`
+		 WHERE n.historia_id = $1
+		 ORDER BY n.created_at DESC`, historiaID)
 	if err != nil {
 		return nil, err
 	}
@@ -36,11 +81,60 @@ func (r *NotaEvolucionRepository) GetByHistoriaID(ctx context.Context, historiaI
 
 	var notas []domain.NotaEvolucion
 	for rows.Next() {
-		var n domain.NotaEvolucion
-		if err := rows.Scan(&n.ID, &n.HistoriaID, &n.Tipo, &n.Contenido, &n.CreatedBy, &n.CreatedAt); err != nil {
+		n, err := scanNota(rows)
+		if err != nil {
 			return nil, err
 		}
-		notas = append(notas, n)
+		notas = append(notas, *n)
+	}
+	return notas, rows.Err()
+}
+
+func (r *NotaEvolucionRepository) Update(ctx context.Context, n *domain.NotaEvolucion) error {
+	return r.db.QueryRowContext(ctx,
+		`UPDATE notas_evolucion SET tipo = $1, contenido = $2, datos = $3
+		 WHERE id = $4 AND estado = 'BORRADOR'
+		 RETURNING updated_at`,
+		n.Tipo, n.Contenido, nullJSON(n.Datos), n.ID).Scan(&n.UpdatedAt)
+}
+
+func (r *NotaEvolucionRepository) Firmar(ctx context.Context, id, firmadoPor uuid.UUID) error {
+	var updated uuid.UUID
+	return r.db.QueryRowContext(ctx,
+		`UPDATE notas_evolucion SET estado = 'FIRMADA', firmado_por = $1, firmado_at = NOW()
+		 WHERE id = $2 AND estado = 'BORRADOR'
+		 RETURNING id`,
+		firmadoPor, id).Scan(&updated)
+}
+
+func (r *NotaEvolucionRepository) Descartar(ctx context.Context, id, descartadaPor uuid.UUID, motivo string) error {
+	return enTransaccion(ctx, r.db, func(ctx context.Context, tx ejecutor) error {
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO notas_descartadas (id, historia_id, tipo, contenido, datos, plantilla_id, cita_id, paquete_id,
+			 created_by, created_at, descartada_por, motivo)
+			 SELECT id, historia_id, tipo, contenido, datos, plantilla_id, cita_id, paquete_id,
+			        created_by, created_at, $2, NULLIF(TRIM($3), '')
+			 FROM notas_evolucion WHERE id = $1 AND estado = 'BORRADOR'`, id, descartadaPor, motivo)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return sql.ErrNoRows
+		}
+
+		// Los adjuntos se conservan en el legajo del paciente, sin la nota.
+		if _, err := tx.ExecContext(ctx, `UPDATE adjuntos SET nota_id = NULL WHERE nota_id = $1`, id); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM notas_evolucion WHERE id = $1 AND estado = 'BORRADOR'`, id)
+		return err
+	})
+}
+
+// nullJSON guarda NULL en lugar de un documento vacío.
+func nullJSON(b json.RawMessage) interface{} {
+	if len(b) == 0 {
+		return nil
 	}
-	return notas, nil
+	return []byte(b)
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/tunek/centro-caribel/internal/domain"
@@ -17,20 +18,28 @@ func NewPacienteRepository(db *sql.DB) *PacienteRepository {
 	return &PacienteRepository{db: db}
 }
 
+const pacienteColumns = `id, codigo, nombre_completo, ci, fecha_nacimiento, celular, direccion, estado, COALESCE(motivo_archivo, ''), fecha_archivo, archivado_por, created_by, created_at, updated_at`
+
+func scanPaciente(row interface{ Scan(dest ...any) error }) (domain.Paciente, error) {
+	var p domain.Paciente
+	err := row.Scan(&p.ID, &p.Codigo, &p.NombreCompleto, &p.CI, &p.FechaNacimiento, &p.Celular, &p.Direccion, &p.Estado, &p.MotivoArchivo, &p.FechaArchivo, &p.ArchivadoPor, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt)
+	if err == nil {
+		p.CalcularEdad()
+	}
+	return p, err
+}
+
 func (r *PacienteRepository) Create(ctx context.Context, p *domain.Paciente) error {
 	_, err := r.db.ExecContext(ctx,
-		`INSERT INTO pacientes (id, codigo, nombre_completo, ci, fecha_nacimiento, celular, direccion, created_by)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
-		p.ID, p.Codigo, p.NombreCompleto, p.CI, p.FechaNacimiento, p.Celular, p.Direccion, p.CreatedBy)
+		`INSERT INTO pacientes (id, codigo, nombre_completo, ci, fecha_nacimiento, celular, direccion, estado, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		p.ID, p.Codigo, p.NombreCompleto, p.CI, p.FechaNacimiento, p.Celular, p.Direccion, p.Estado, p.CreatedBy)
 	return err
 }
 
 func (r *PacienteRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Paciente, error) {
-	var p domain.Paciente
-	err := r.db.QueryRowContext(ctx,
-		`SELECT id, codigo, nombre_completo, ci, fecha_nacimiento, celular, direccion, created_by, created_at, updated_at
-		 FROM pacientes WHERE id = $1`, id).
-		Scan(&p.ID, &p.Codigo, &p.NombreCompleto, &p.CI, &p.FechaNacimiento, &p.Celular, &p.Direccion, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt)
+	p, err := scanPaciente(r.db.QueryRowContext(ctx,
+		`SELECT `+pacienteColumns+` FROM pacientes WHERE id = $1`, id))
 	if err != nil {
 		return nil, err
 	}
@@ -38,27 +47,28 @@ func (r *PacienteRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain
 }
 
 func (r *PacienteRepository) GetByCI(ctx context.Context, ci string) (*domain.Paciente, error) {
-	var p domain.Paciente
-	err := r.db.QueryRowContext(ctx,
-		`SELECT id, codigo, nombre_completo, ci, fecha_nacimiento, celular, direccion, created_by, created_at, updated_at
-		 FROM pacientes WHERE ci = $1`, ci).
-		Scan(&p.ID, &p.Codigo, &p.NombreCompleto, &p.CI, &p.FechaNacimiento, &p.Celular, &p.Direccion, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt)
+	p, err := scanPaciente(r.db.QueryRowContext(ctx,
+		`SELECT `+pacienteColumns+` FROM pacientes WHERE ci = $1`, ci))
 	if err != nil {
 		return nil, err
 	}
 	return &p, nil
 }
 
-func (r *PacienteRepository) GetAll(ctx context.Context, offset, limit int) ([]domain.Paciente, int64, error) {
+func (r *PacienteRepository) GetAll(ctx context.Context, offset, limit int, incluirArchivados bool) ([]domain.Paciente, int64, error) {
+	where := "WHERE estado = 'ACTIVO'"
+	if incluirArchivados {
+		where = ""
+	}
+
 	var total int64
-	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM pacientes").Scan(&total)
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM pacientes "+where).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
 
 	rows, err := r.db.QueryContext(ctx,
-		`SELECT id, codigo, nombre_completo, ci, fecha_nacimiento, celular, direccion, created_by, created_at, updated_at
-		 FROM pacientes ORDER BY created_at DESC LIMIT $1 OFFSET $2`, limit, offset)
+		`SELECT `+pacienteColumns+` FROM pacientes `+where+` ORDER BY created_at DESC LIMIT $1 OFFSET $2`, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -66,8 +76,8 @@ func (r *PacienteRepository) GetAll(ctx context.Context, offset, limit int) ([]d
 
 	var pacientes []domain.Paciente
 	for rows.Next() {
-		var p domain.Paciente
-		if err := rows.Scan(&p.ID, &p.Codigo, &p.NombreCompleto, &p.CI, &p.FechaNacimiento, &p.Celular, &p.Direccion, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		p, err := scanPaciente(rows)
+		if err != nil {
 			return nil, 0, err
 		}
 		pacientes = append(pacientes, p)
@@ -83,20 +93,49 @@ func (r *PacienteRepository) Update(ctx context.Context, p *domain.Paciente) err
 	return err
 }
 
-func (r *PacienteRepository) Search(ctx context.Context, query string, offset, limit int) ([]domain.Paciente, int64, error) {
-	like := "%" + query + "%"
+// pacienteSearchWhere combina similitud de trigramas sobre el nombre (independiente
+// del orden de las palabras) con coincidencias parciales en CI, código y celular.
+// $1 es la consulta normalizada, $2 el patrón LIKE de contención.
+const pacienteSearchWhere = `(f_unaccent(lower(nombre_completo)) % f_unaccent($1)
+	OR f_unaccent($1) <% f_unaccent(lower(nombre_completo))
+	OR ci ILIKE $2 OR codigo ILIKE $2 OR celular LIKE $2)`
+
+// pacienteSearchRank ordena por relevancia: coincidencia exacta de CI o código,
+// luego prefijo ($3), luego la mejor similitud de nombre.
+const pacienteSearchRank = `GREATEST(
+	similarity(f_unaccent(lower(nombre_completo)), f_unaccent($1)),
+	word_similarity(f_unaccent($1), f_unaccent(lower(nombre_completo))),
+	CASE
+		WHEN lower(ci) = $1 OR lower(codigo) = $1 THEN 1.0
+		WHEN ci ILIKE $3 OR codigo ILIKE $3 OR celular LIKE $3 THEN 0.9
+		WHEN ci ILIKE $2 OR codigo ILIKE $2 OR celular LIKE $2 THEN 0.7
+		ELSE 0
+	END)`
+
+func (r *PacienteRepository) Search(ctx context.Context, query string, offset, limit int, incluirArchivados bool) ([]domain.Paciente, int64, error) {
+	q := strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	if q == "" {
+		return r.GetAll(ctx, offset, limit, incluirArchivados)
+	}
+	contiene := "%" + escapeLike(q) + "%"
+	prefijo := escapeLike(q) + "%"
+
+	where := "WHERE " + pacienteSearchWhere
+	if !incluirArchivados {
+		where += ` AND estado = 'ACTIVO'`
+	}
 
 	var total int64
 	err := r.db.QueryRowContext(ctx,
-		`SELECT COUNT(*) FROM pacientes WHERE unaccent(lower(nombre_completo)) LIKE unaccent(lower($1)) OR ci LIKE $2`, like, like).Scan(&total)
+		`SELECT COUNT(*) FROM pacientes `+where, q, contiene).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
 
 	rows, err := r.db.QueryContext(ctx,
-		`SELECT id, codigo, nombre_completo, ci, fecha_nacimiento, celular, direccion, created_by, created_at, updated_at
-		 FROM pacientes WHERE unaccent(lower(nombre_completo)) LIKE unaccent(lower($1)) OR ci LIKE $2
-		 ORDER BY nombre_completo ASC LIMIT $3 OFFSET $4`, like, like, limit, offset)
+		`SELECT `+pacienteColumns+` FROM pacientes `+where+`
+		 ORDER BY `+pacienteSearchRank+` DESC, nombre_completo ASC LIMIT $4 OFFSET $5`,
+		q, contiene, prefijo, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -104,8 +143,8 @@ func (r *PacienteRepository) Search(ctx context.Context, query string, offset, l
 
 	var pacientes []domain.Paciente
 	for rows.Next() {
-		var p domain.Paciente
-		if err := rows.Scan(&p.ID, &p.Codigo, &p.NombreCompleto, &p.CI, &p.FechaNacimiento, &p.Celular, &p.Direccion, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		p, err := scanPaciente(rows)
+		if err != nil {
 			return nil, 0, err
 		}
 		pacientes = append(pacientes, p)
@@ -113,6 +152,25 @@ func (r *PacienteRepository) Search(ctx context.Context, query string, offset, l
 	return pacientes, total, nil
 }
 
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func (r *PacienteRepository) Archivar(ctx context.Context, id uuid.UUID, motivo string, archivadoPor uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE pacientes SET estado = 'ARCHIVADO', motivo_archivo = $1, fecha_archivo = NOW(), archivado_por = $2
+		 WHERE id = $3`,
+		motivo, archivadoPor, id)
+	return err
+}
+
+func (r *PacienteRepository) Restaurar(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE pacientes SET estado = 'ACTIVO', motivo_archivo = NULL, fecha_archivo = NULL, archivado_por = NULL
+		 WHERE id = $1`, id)
+	return err
+}
+
 func (r *PacienteRepository) NextCodigo(ctx context.Context) (string, error) {
 	var seq int
 	err := r.db.QueryRowContext(ctx, "SELECT nextval('pacientes_codigo_seq')").Scan(&seq)
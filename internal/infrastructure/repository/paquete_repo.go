@@ -26,7 +26,7 @@ func (r *PaqueteRepository) Create(ctx context.Context, p *domain.PaqueteTratami
 
 func (r *PaqueteRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.PaqueteTratamiento, error) {
 	var p domain.PaqueteTratamiento
-	err := r.db.QueryRowContext(ctx,
+	err := conexion(ctx, r.db).QueryRowContext(ctx,
 		`SELECT id, paciente_id, tipo_tratamiento, total_sesiones, sesiones_completadas, estado, notas, created_by, created_at, updated_at
 		 FROM paquetes_tratamiento WHERE id = $1`, id).
 		Scan(&p.ID, &p.PacienteID, &p.TipoTratamiento, &p.TotalSesiones, &p.SesionesCompletadas, &p.Estado, &p.Notas, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt)
@@ -77,12 +77,12 @@ func (r *PaqueteRepository) GetActivosByPaciente(ctx context.Context, pacienteID
 }
 
 func (r *PaqueteRepository) IncrementSesiones(ctx context.Context, id uuid.UUID) error {
-	_, err := r.db.ExecContext(ctx,
+	_, err := conexion(ctx, r.db).ExecContext(ctx,
 		`UPDATE paquetes_tratamiento SET sesiones_completadas = sesiones_completadas + 1 WHERE id = $1`, id)
 	return err
 }
 
 func (r *PaqueteRepository) UpdateEstado(ctx context.Context, id uuid.UUID, estado domain.EstadoPaquete) error {
-	_, err := r.db.ExecContext(ctx, "UPDATE paquetes_tratamiento SET estado = $1 WHERE id = $2", estado, id)
+	_, err := conexion(ctx, r.db).ExecContext(ctx, "UPDATE paquetes_tratamiento SET estado = $1 WHERE id = $2", estado, id)
 	return err
 }
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/tunek/centro-caribel/internal/domain"
+)
+
+type PasswordResetTokenRepository struct {
+	db *sql.DB
+}
+
+func NewPasswordResetTokenRepository(db *sql.DB) *PasswordResetTokenRepository {
+	return &PasswordResetTokenRepository{db: db}
+}
+
+func (r *PasswordResetTokenRepository) Create(ctx context.Context, t *domain.PasswordResetToken) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Solo el último token emitido queda vigente.
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE password_reset_tokens SET expires_at = NOW()
+		 WHERE usuario_id = $1 AND used_at IS NULL AND expires_at > NOW()`, t.UsuarioID); err != nil {
+		return err
+	}
+
+	if err := tx.QueryRowContext(ctx,
+		`INSERT INTO password_reset_tokens (usuario_id, token_hash, creado_por, expires_at)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING id, created_at`,
+		t.UsuarioID, t.TokenHash, t.CreadoPor, t.ExpiresAt).Scan(&t.ID, &t.CreatedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PasswordResetTokenRepository) Consumir(ctx context.Context, tokenHash string) (*domain.PasswordResetToken, error) {
+	var t domain.PasswordResetToken
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE password_reset_tokens SET used_at = NOW()
+		 WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		 RETURNING id, usuario_id, token_hash, creado_por, expires_at, used_at, created_at`, tokenHash).
+		Scan(&t.ID, &t.UsuarioID, &t.TokenHash, &t.CreadoPor, &t.ExpiresAt, &t.UsedAt, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
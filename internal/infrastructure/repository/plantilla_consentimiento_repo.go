@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+)
+
+type PlantillaConsentimientoRepository struct {
+	db *sql.DB
+}
+
+func NewPlantillaConsentimientoRepository(db *sql.DB) *PlantillaConsentimientoRepository {
+	return &PlantillaConsentimientoRepository{db: db}
+}
+
+const plantillaConsentimientoColumns = `p.id, p.nombre, p.tipo_tratamiento, p.activa, p.vigencia_dias, p.requerido, p.version_actual, v.id, v.texto,
+	p.created_by, p.created_at, p.updated_at`
+const plantillaConsentimientoFrom = ` FROM plantillas_consentimiento p
+	JOIN plantillas_consentimiento_versiones v ON v.plantilla_id = p.id AND v.version = p.version_actual`
+
+func scanPlantillaConsentimiento(row interface{ Scan(dest ...any) error }) (*domain.PlantillaConsentimiento, error) {
+	var p domain.PlantillaConsentimiento
+	err := row.Scan(&p.ID, &p.Nombre, &p.TipoTratamiento, &p.Activa, &p.VigenciaDias, &p.Requerido, &p.VersionActual, &p.VersionID, &p.Texto,
+		&p.CreatedBy, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+const versionConsentimientoColumns = `v.id, v.plantilla_id, p.nombre, v.version, v.texto, v.created_by, v.created_at`
+
+func scanVersionConsentimiento(row interface{ Scan(dest ...any) error }) (*domain.VersionPlantillaConsentimiento, error) {
+	var v domain.VersionPlantillaConsentimiento
+	err := row.Scan(&v.ID, &v.PlantillaID, &v.PlantillaNombre, &v.Version, &v.Texto, &v.CreatedBy, &v.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *PlantillaConsentimientoRepository) Create(ctx context.Context, p *domain.PlantillaConsentimiento) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	p.VersionActual = 1
+	if err := tx.QueryRowContext(ctx,
+		`INSERT INTO plantillas_consentimiento (id, nombre, tipo_tratamiento, activa, vigencia_dias, requerido, version_actual, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 RETURNING created_at, updated_at`,
+		p.ID, p.Nombre, p.TipoTratamiento, p.Activa, p.VigenciaDias, p.Requerido, p.VersionActual, p.CreatedBy).
+		Scan(&p.CreatedAt, &p.UpdatedAt); err != nil {
+		return err
+	}
+
+	p.VersionID = uuid.New()
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO plantillas_consentimiento_versiones (id, plantilla_id, version, texto, created_by)
+		 VALUES ($1, $2, $3, $4, $5)`,
+		p.VersionID, p.ID, p.VersionActual, p.Texto, p.CreatedBy); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PlantillaConsentimientoRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.PlantillaConsentimiento, error) {
+	return scanPlantillaConsentimiento(r.db.QueryRowContext(ctx,
+		`SELECT `+plantillaConsentimientoColumns+plantillaConsentimientoFrom+` WHERE p.id = $1`, id))
+}
+
+func (r *PlantillaConsentimientoRepository) GetAll(ctx context.Context, soloActivas bool) ([]domain.PlantillaConsentimiento, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+plantillaConsentimientoColumns+plantillaConsentimientoFrom+`
+		 WHERE p.activa OR NOT $1
+		 ORDER BY p.nombre`, soloActivas)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plantillas []domain.PlantillaConsentimiento
+	for rows.Next() {
+		p, err := scanPlantillaConsentimiento(rows)
+		if err != nil {
+			return nil, err
+		}
+		plantillas = append(plantillas, *p)
+	}
+	return plantillas, rows.Err()
+}
+
+func (r *PlantillaConsentimientoRepository) Update(ctx context.Context, p *domain.PlantillaConsentimiento, autorID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// FOR UPDATE serializa las ediciones concurrentes de la misma plantilla.
+	var textoActual string
+	var version int
+	if err := tx.QueryRowContext(ctx,
+		`SELECT v.texto, p.version_actual`+plantillaConsentimientoFrom+` WHERE p.id = $1 FOR UPDATE OF p`, p.ID).
+		Scan(&textoActual, &version); err != nil {
+		return err
+	}
+
+	if textoActual != p.Texto {
+		version++
+		p.VersionID = uuid.New()
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO plantillas_consentimiento_versiones (id, plantilla_id, version, texto, created_by)
+			 VALUES ($1, $2, $3, $4, $5)`,
+			p.VersionID, p.ID, version, p.Texto, autorID); err != nil {
+			return err
+		}
+	}
+	p.VersionActual = version
+
+	if err := tx.QueryRowContext(ctx,
+		`UPDATE plantillas_consentimiento SET nombre = $1, tipo_tratamiento = $2, activa = $3, vigencia_dias = $4,
+		 requerido = $5, version_actual = $6
+		 WHERE id = $7
+		 RETURNING updated_at`,
+		p.Nombre, p.TipoTratamiento, p.Activa, p.VigenciaDias, p.Requerido, p.VersionActual, p.ID).Scan(&p.UpdatedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PlantillaConsentimientoRepository) GetVersiones(ctx context.Context, plantillaID uuid.UUID) ([]domain.VersionPlantillaConsentimiento, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+versionConsentimientoColumns+`
+		 FROM plantillas_consentimiento_versiones v JOIN plantillas_consentimiento p ON p.id = v.plantilla_id
+		 WHERE v.plantilla_id = $1
+		 ORDER BY v.version DESC`, plantillaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versiones []domain.VersionPlantillaConsentimiento
+	for rows.Next() {
+		v, err := scanVersionConsentimiento(rows)
+		if err != nil {
+			return nil, err
+		}
+		versiones = append(versiones, *v)
+	}
+	return versiones, rows.Err()
+}
+
+func (r *PlantillaConsentimientoRepository) GetVersionByID(ctx context.Context, id uuid.UUID) (*domain.VersionPlantillaConsentimiento, error) {
+	return scanVersionConsentimiento(r.db.QueryRowContext(ctx,
+		`SELECT `+versionConsentimientoColumns+`
+		 FROM plantillas_consentimiento_versiones v JOIN plantillas_consentimiento p ON p.id = v.plantilla_id
+		 WHERE v.id = $1`, id))
+}
+
+func (r *PlantillaConsentimientoRepository) ExistsByNombre(ctx context.Context, nombre string, excludeID *uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM plantillas_consentimiento WHERE lower(nombre) = lower($1) AND ($2::uuid IS NULL OR id != $2))`,
+		nombre, excludeID).Scan(&exists)
+	return exists, err
+}
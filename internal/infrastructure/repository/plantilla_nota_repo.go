@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+)
+
+type PlantillaNotaRepository struct {
+	db *sql.DB
+}
+
+func NewPlantillaNotaRepository(db *sql.DB) *PlantillaNotaRepository {
+	return &PlantillaNotaRepository{db: db}
+}
+
+const plantillaNotaColumns = `id, nombre, descripcion, tipo_nota, campos, activa, created_by, created_at, updated_at`
+
+func scanPlantillaNota(row interface{ Scan(dest ...any) error }) (*domain.PlantillaNota, error) {
+	var p domain.PlantillaNota
+	var campos []byte
+	err := row.Scan(&p.ID, &p.Nombre, &p.Descripcion, &p.TipoNota, &campos, &p.Activa, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(campos, &p.Campos); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *PlantillaNotaRepository) Create(ctx context.Context, p *domain.PlantillaNota) error {
+	campos, err := json.Marshal(p.Campos)
+	if err != nil {
+		return err
+	}
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO plantillas_nota (id, nombre, descripcion, tipo_nota, campos, activa, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING created_at, updated_at`,
+		p.ID, p.Nombre, p.Descripcion, p.TipoNota, campos, p.Activa, p.CreatedBy).
+		Scan(&p.CreatedAt, &p.UpdatedAt)
+}
+
+func (r *PlantillaNotaRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.PlantillaNota, error) {
+	return scanPlantillaNota(r.db.QueryRowContext(ctx,
+		`SELECT `+plantillaNotaColumns+` FROM plantillas_nota WHERE id = $1`, id))
+}
+
+func (r *PlantillaNotaRepository) GetAll(ctx context.Context, soloActivas bool) ([]domain.PlantillaNota, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+plantillaNotaColumns+` FROM plantillas_nota
+		 WHERE activa OR NOT $1
+		 ORDER BY nombre`, soloActivas)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plantillas []domain.PlantillaNota
+	for rows.Next() {
+		p, err := scanPlantillaNota(rows)
+		if err != nil {
+			return nil, err
+		}
+		plantillas = append(plantillas, *p)
+	}
+	return plantillas, rows.Err()
+}
+
+func (r *PlantillaNotaRepository) Update(ctx context.Context, p *domain.PlantillaNota) error {
+	campos, err := json.Marshal(p.Campos)
+	if err != nil {
+		return err
+	}
+	return r.db.QueryRowContext(ctx,
+		`UPDATE plantillas_nota SET nombre = $1, descripcion = $2, tipo_nota = $3, campos = $4, activa = $5
+		 WHERE id = $6
+		 RETURNING updated_at`,
+		p.Nombre, p.Descripcion, p.TipoNota, campos, p.Activa, p.ID).Scan(&p.UpdatedAt)
+}
+
+func (r *PlantillaNotaRepository) ExistsByNombre(ctx context.Context, nombre string, excludeID *uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM plantillas_nota WHERE lower(nombre) = lower($1) AND ($2::uuid IS NULL OR id != $2))`,
+		nombre, excludeID).Scan(&exists)
+	return exists, err
+}
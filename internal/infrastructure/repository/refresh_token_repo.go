@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+)
+
+type RefreshTokenRepository struct {
+	db *sql.DB
+}
+
+func NewRefreshTokenRepository(db *sql.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+const insertRefreshToken = `INSERT INTO refresh_tokens (id, usuario_id, familia_id, token_hash, user_agent, ip, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING created_at`
+
+func (r *RefreshTokenRepository) Create(ctx context.Context, t *domain.RefreshToken) error {
+	return r.db.QueryRowContext(ctx, insertRefreshToken,
+		t.ID, t.UsuarioID, t.FamiliaID, t.TokenHash, t.UserAgent, t.IP, t.ExpiresAt).Scan(&t.CreatedAt)
+}
+
+func (r *RefreshTokenRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.RefreshToken, error) {
+	var t domain.RefreshToken
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, usuario_id, familia_id, token_hash, user_agent, ip, expires_at, used_at, reemplazado_por,
+		 revoked_at, motivo_revocacion, created_at
+		 FROM refresh_tokens WHERE id = $1`, id).
+		Scan(&t.ID, &t.UsuarioID, &t.FamiliaID, &t.TokenHash, &t.UserAgent, &t.IP, &t.ExpiresAt, &t.UsedAt,
+			&t.ReemplazadoPor, &t.RevokedAt, &t.MotivoRevocacion, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *RefreshTokenRepository) Rotar(ctx context.Context, anteriorID uuid.UUID, nuevo *domain.RefreshToken) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, insertRefreshToken,
+		nuevo.ID, nuevo.UsuarioID, nuevo.FamiliaID, nuevo.TokenHash, nuevo.UserAgent, nuevo.IP, nuevo.ExpiresAt).
+		Scan(&nuevo.CreatedAt); err != nil {
+		return false, err
+	}
+
+	// La condición hace que, entre dos refresh simultáneos con el mismo token,
+	// solo uno lo consuma.
+	res, err := tx.ExecContext(ctx,
+		`UPDATE refresh_tokens SET used_at = NOW(), reemplazado_por = $1
+		 WHERE id = $2 AND used_at IS NULL AND revoked_at IS NULL`, nuevo.ID, anteriorID)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (r *RefreshTokenRepository) RevocarFamilia(ctx context.Context, familiaID uuid.UUID, motivo domain.MotivoRevocacionToken) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = NOW(), motivo_revocacion = $1
+		 WHERE familia_id = $2 AND revoked_at IS NULL`, motivo, familiaID)
+	return err
+}
+
+func (r *RefreshTokenRepository) RevocarUsuario(ctx context.Context, usuarioID uuid.UUID, motivo domain.MotivoRevocacionToken) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = NOW(), motivo_revocacion = $1
+		 WHERE usuario_id = $2 AND revoked_at IS NULL`, motivo, usuarioID)
+	return err
+}
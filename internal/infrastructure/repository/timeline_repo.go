@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/tunek/centro-caribel/internal/domain"
+)
+
+type TimelineRepository struct {
+	db *sql.DB
+}
+
+func NewTimelineRepository(db *sql.DB) *TimelineRepository {
+	return &TimelineRepository{db: db}
+}
+
+// timelineEventos une todas las fuentes de actividad clínica de un paciente ($1)
+// en una sola relación con columnas homogéneas.
+const timelineEventos = `WITH eventos AS (
+	SELECT 'CITA' AS tipo, 'CREADA' AS evento, c.created_at AS fecha, c.id AS referencia_id,
+		'Cita agendada: ' || c.tipo_tratamiento AS descripcion,
+		json_build_object('fecha', c.fecha, 'hora', TO_CHAR(c.hora, 'HH24:MI'), 'turno', c.turno,
+			'estado', c.estado, 'paquete_id', c.paquete_id) AS detalle,
+		c.created_by AS usuario_id
+	FROM citas c WHERE c.paciente_id = $1
+
+	UNION ALL
+	SELECT 'CITA', 'CAMBIO_ESTADO', h.created_at, h.cita_id,
+		'Cita ' || c.tipo_tratamiento || ': ' || COALESCE(h.estado_anterior::text || ' -> ', '') || h.estado_nuevo::text,
+		json_build_object('estado_anterior', h.estado_anterior, 'estado_nuevo', h.estado_nuevo,
+			'fecha', c.fecha, 'hora', TO_CHAR(c.hora, 'HH24:MI')),
+		h.cambiado_por
+	FROM citas_estados_historial h JOIN citas c ON c.id = h.cita_id
+	WHERE c.paciente_id = $1
+
+	UNION ALL
+	SELECT 'NOTA', CASE WHEN n.nota_original_id IS NULL THEN 'FIRMADA' ELSE 'ADENDA' END, n.firmado_at, n.id,
+		CASE WHEN n.nota_original_id IS NULL THEN 'Nota de evolución (' || n.tipo || ')'
+			ELSE 'Adenda a nota de evolución (' || n.tipo || ')' END,
+		json_build_object('tipo', n.tipo, 'contenido', n.contenido, 'nota_original_id', n.nota_original_id),
+		n.firmado_por
+	FROM notas_evolucion n JOIN historias_clinicas hc ON hc.id = n.historia_id
+	WHERE hc.paciente_id = $1 AND n.estado = 'FIRMADA'
+
+	UNION ALL
+	SELECT 'CONSENTIMIENTO', 'FIRMADO', co.fecha_firma, co.id,
+		CASE WHEN co.autoriza_fotos THEN 'Consentimiento firmado (autoriza fotos)' ELSE 'Consentimiento firmado' END,
+		json_build_object('autoriza_fotos', co.autoriza_fotos),
+		co.registrado_por
+	FROM consentimientos co WHERE co.paciente_id = $1
+
+	UNION ALL
+	SELECT 'PAQUETE', 'CREADO', p.created_at, p.id,
+		'Paquete de tratamiento: ' || p.tipo_tratamiento || ' (' || p.total_sesiones || ' sesiones)',
+		json_build_object('tipo_tratamiento', p.tipo_tratamiento, 'total_sesiones', p.total_sesiones),
+		p.created_by
+	FROM paquetes_tratamiento p WHERE p.paciente_id = $1
+
+	UNION ALL
+	SELECT 'PAQUETE', p.estado, p.updated_at, p.id,
+		'Paquete ' || lower(p.estado) || ': ' || p.tipo_tratamiento,
+		json_build_object('tipo_tratamiento', p.tipo_tratamiento, 'total_sesiones', p.total_sesiones,
+			'sesiones_completadas', p.sesiones_completadas),
+		NULL
+	FROM paquetes_tratamiento p WHERE p.paciente_id = $1 AND p.estado <> 'ACTIVO'
+)`
+
+func (r *TimelineRepository) GetByPacienteID(ctx context.Context, pacienteID uuid.UUID, tipos []domain.TipoEventoTimeline, offset, limit int) ([]domain.EventoTimeline, int64, error) {
+	var filtro []string
+	for _, t := range tipos {
+		filtro = append(filtro, string(t))
+	}
+	where := ` WHERE ($2::text[] IS NULL OR e.tipo = ANY($2::text[]))`
+
+	var total int64
+	err := r.db.QueryRowContext(ctx,
+		timelineEventos+` SELECT COUNT(*) FROM eventos e`+where, pacienteID, pq.Array(filtro)).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		timelineEventos+` SELECT e.tipo, e.evento, e.fecha, e.referencia_id, e.descripcion, e.detalle, e.usuario_id, COALESCE(u.nombre_completo, '')
+		 FROM eventos e LEFT JOIN usuarios u ON u.id = e.usuario_id`+where+`
+		 ORDER BY e.fecha DESC LIMIT $3 OFFSET $4`,
+		pacienteID, pq.Array(filtro), limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var eventos []domain.EventoTimeline
+	for rows.Next() {
+		var e domain.EventoTimeline
+		var detalle []byte
+		if err := rows.Scan(&e.Tipo, &e.Evento, &e.Fecha, &e.ReferenciaID, &e.Descripcion, &detalle, &e.UsuarioID, &e.UsuarioNombre); err != nil {
+			return nil, 0, err
+		}
+		e.Detalle = detalle
+		eventos = append(eventos, e)
+	}
+	return eventos, total, nil
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+)
+
+type TOTPRepository struct {
+	db *sql.DB
+}
+
+func NewTOTPRepository(db *sql.DB) *TOTPRepository {
+	return &TOTPRepository{db: db}
+}
+
+func (r *TOTPRepository) GetByUsuarioID(ctx context.Context, usuarioID uuid.UUID) (*domain.TOTPUsuario, error) {
+	var t domain.TOTPUsuario
+	err := r.db.QueryRowContext(ctx,
+		`SELECT usuario_id, secreto_cifrado, habilitado, ultimo_paso, habilitado_at, created_at
+		 FROM usuarios_totp WHERE usuario_id = $1`, usuarioID).
+		Scan(&t.UsuarioID, &t.SecretoCifrado, &t.Habilitado, &t.UltimoPaso, &t.HabilitadoAt, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *TOTPRepository) GuardarPendiente(ctx context.Context, usuarioID uuid.UUID, secretoCifrado string) error {
+	// La condición evita pisar una inscripción ya habilitada.
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO usuarios_totp (usuario_id, secreto_cifrado)
+		 VALUES ($1, $2)
+		 ON CONFLICT (usuario_id) DO UPDATE
+		     SET secreto_cifrado = EXCLUDED.secreto_cifrado, ultimo_paso = 0, created_at = NOW()
+		     WHERE usuarios_totp.habilitado = false`, usuarioID, secretoCifrado)
+	return err
+}
+
+func (r *TOTPRepository) Habilitar(ctx context.Context, usuarioID uuid.UUID, paso int64, hashesRecuperacion []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE usuarios_totp SET habilitado = true, ultimo_paso = $1, habilitado_at = NOW()
+		 WHERE usuario_id = $2`, paso, usuarioID); err != nil {
+		return err
+	}
+	if err := reemplazarCodigos(ctx, tx, usuarioID, hashesRecuperacion); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *TOTPRepository) RegistrarPaso(ctx context.Context, usuarioID uuid.UUID, paso int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE usuarios_totp SET ultimo_paso = $1 WHERE usuario_id = $2 AND ultimo_paso < $1", paso, usuarioID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *TOTPRepository) ReemplazarCodigos(ctx context.Context, usuarioID uuid.UUID, hashesRecuperacion []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := reemplazarCodigos(ctx, tx, usuarioID, hashesRecuperacion); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func reemplazarCodigos(ctx context.Context, tx *sql.Tx, usuarioID uuid.UUID, hashes []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM totp_codigos_recuperacion WHERE usuario_id = $1", usuarioID); err != nil {
+		return err
+	}
+	for _, h := range hashes {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO totp_codigos_recuperacion (usuario_id, codigo_hash) VALUES ($1, $2)", usuarioID, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *TOTPRepository) ConsumirCodigo(ctx context.Context, usuarioID uuid.UUID, hash string) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE totp_codigos_recuperacion SET used_at = NOW()
+		 WHERE usuario_id = $1 AND codigo_hash = $2 AND used_at IS NULL`, usuarioID, hash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *TOTPRepository) CodigosRestantes(ctx context.Context, usuarioID uuid.UUID) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM totp_codigos_recuperacion WHERE usuario_id = $1 AND used_at IS NULL", usuarioID).Scan(&n)
+	return n, err
+}
+
+func (r *TOTPRepository) Delete(ctx context.Context, usuarioID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM totp_codigos_recuperacion WHERE usuario_id = $1", usuarioID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM usuarios_totp WHERE usuario_id = $1", usuarioID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// txKey identifica en el contexto la transacción abierta por Transactor.
+type txKey struct{}
+
+// ejecutor es lo que comparten *sql.DB y *sql.Tx.
+type ejecutor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type Transactor struct {
+	db *sql.DB
+}
+
+func NewTransactor(db *sql.DB) *Transactor {
+	return &Transactor{db: db}
+}
+
+// EnTransaccion ejecuta fn con un contexto que lleva la transacción. Si ctx ya
+// tiene una, fn se suma a ella.
+func (t *Transactor) EnTransaccion(ctx context.Context, fn func(ctx context.Context) error) error {
+	return enTransaccion(ctx, t.db, func(ctx context.Context, _ ejecutor) error {
+		return fn(ctx)
+	})
+}
+
+// enTransaccion ejecuta fn en la transacción de ctx o, si no hay, en una nueva
+// que se confirma cuando fn termina sin error.
+func enTransaccion(ctx context.Context, db *sql.DB, fn func(ctx context.Context, q ejecutor) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx, tx)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx), tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// conexion devuelve la transacción abierta en ctx o, si no hay, db.
+func conexion(ctx context.Context, db *sql.DB) ejecutor {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+)
+
+type TutorRepository struct {
+	db *sql.DB
+}
+
+func NewTutorRepository(db *sql.DB) *TutorRepository {
+	return &TutorRepository{db: db}
+}
+
+func (r *TutorRepository) Create(ctx context.Context, t *domain.Tutor) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO tutores (id, paciente_id, nombre_completo, ci, parentesco, celular, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		t.ID, t.PacienteID, t.NombreCompleto, t.CI, t.Parentesco, t.Celular, t.CreatedBy)
+	return err
+}
+
+func (r *TutorRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Tutor, error) {
+	var t domain.Tutor
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, paciente_id, nombre_completo, ci, parentesco, COALESCE(celular, ''), created_by, created_at, updated_at
+		 FROM tutores WHERE id = $1`, id).
+		Scan(&t.ID, &t.PacienteID, &t.NombreCompleto, &t.CI, &t.Parentesco, &t.Celular, &t.CreatedBy, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *TutorRepository) GetByPacienteID(ctx context.Context, pacienteID uuid.UUID) ([]domain.Tutor, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, paciente_id, nombre_completo, ci, parentesco, COALESCE(celular, ''), created_by, created_at, updated_at
+		 FROM tutores WHERE paciente_id = $1 ORDER BY created_at`, pacienteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tutores []domain.Tutor
+	for rows.Next() {
+		var t domain.Tutor
+		if err := rows.Scan(&t.ID, &t.PacienteID, &t.NombreCompleto, &t.CI, &t.Parentesco, &t.Celular, &t.CreatedBy, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		tutores = append(tutores, t)
+	}
+	return tutores, nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/tunek/centro-caribel/internal/domain"
@@ -18,9 +19,9 @@ func NewUsuarioRepository(db *sql.DB) *UsuarioRepository {
 
 func (r *UsuarioRepository) Create(ctx context.Context, u *domain.Usuario) error {
 	_, err := r.db.ExecContext(ctx,
-		`INSERT INTO usuarios (id, nombre_completo, email, password_hash, rol_id, activo)
-		 VALUES ($1, $2, $3, $4, $5, $6)`,
-		u.ID, u.NombreCompleto, u.Email, u.PasswordHash, u.RolID, u.Activo)
+		`INSERT INTO usuarios (id, nombre_completo, email, password_hash, rol_id, activo, debe_cambiar_password)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		u.ID, u.NombreCompleto, u.Email, u.PasswordHash, u.RolID, u.Activo, u.DebeCambiarPassword)
 	return err
 }
 
@@ -28,11 +29,15 @@ func (r *UsuarioRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.
 	var u domain.Usuario
 	var rol domain.Rol
 	err := r.db.QueryRowContext(ctx,
-		`SELECT u.id, u.nombre_completo, u.email, u.password_hash, u.rol_id, u.activo, u.created_at, u.updated_at,
+		`SELECT u.id, u.nombre_completo, u.email, u.password_hash, u.rol_id, u.activo, u.token_version,
+		        u.debe_cambiar_password, u.password_changed_at,
+		        u.intentos_fallidos, u.ultimo_fallo_at, u.bloqueado_hasta, u.created_at, u.updated_at,
 		        r.id, r.nombre, r.descripcion, r.permisos, r.activo
 		 FROM usuarios u JOIN roles r ON u.rol_id = r.id
 		 WHERE u.id = $1`, id).
-		Scan(&u.ID, &u.NombreCompleto, &u.Email, &u.PasswordHash, &u.RolID, &u.Activo, &u.CreatedAt, &u.UpdatedAt,
+		Scan(&u.ID, &u.NombreCompleto, &u.Email, &u.PasswordHash, &u.RolID, &u.Activo, &u.TokenVersion,
+			&u.DebeCambiarPassword, &u.PasswordChangedAt,
+			&u.IntentosFallidos, &u.UltimoFalloAt, &u.BloqueadoHasta, &u.CreatedAt, &u.UpdatedAt,
 			&rol.ID, &rol.Nombre, &rol.Descripcion, &rol.Permisos, &rol.Activo)
 	if err != nil {
 		return nil, err
@@ -44,9 +49,13 @@ func (r *UsuarioRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.
 func (r *UsuarioRepository) GetByEmail(ctx context.Context, email string) (*domain.Usuario, error) {
 	var u domain.Usuario
 	err := r.db.QueryRowContext(ctx,
-		`SELECT id, nombre_completo, email, password_hash, rol_id, activo, created_at, updated_at
+		`SELECT id, nombre_completo, email, password_hash, rol_id, activo, token_version,
+		        debe_cambiar_password, password_changed_at,
+		        intentos_fallidos, ultimo_fallo_at, bloqueado_hasta, created_at, updated_at
 		 FROM usuarios WHERE email = $1`, email).
-		Scan(&u.ID, &u.NombreCompleto, &u.Email, &u.PasswordHash, &u.RolID, &u.Activo, &u.CreatedAt, &u.UpdatedAt)
+		Scan(&u.ID, &u.NombreCompleto, &u.Email, &u.PasswordHash, &u.RolID, &u.Activo, &u.TokenVersion,
+			&u.DebeCambiarPassword, &u.PasswordChangedAt,
+			&u.IntentosFallidos, &u.UltimoFalloAt, &u.BloqueadoHasta, &u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -61,7 +70,8 @@ func (r *UsuarioRepository) GetAll(ctx context.Context, offset, limit int) ([]do
 	}
 
 	rows, err := r.db.QueryContext(ctx,
-		`SELECT u.id, u.nombre_completo, u.email, u.rol_id, u.activo, u.created_at, u.updated_at,
+		`SELECT u.id, u.nombre_completo, u.email, u.rol_id, u.activo, u.debe_cambiar_password, u.password_changed_at,
+		        u.intentos_fallidos, u.ultimo_fallo_at, u.bloqueado_hasta, u.created_at, u.updated_at,
 		        r.nombre
 		 FROM usuarios u JOIN roles r ON u.rol_id = r.id
 		 ORDER BY u.created_at DESC LIMIT $1 OFFSET $2`, limit, offset)
@@ -74,7 +84,8 @@ func (r *UsuarioRepository) GetAll(ctx context.Context, offset, limit int) ([]do
 	for rows.Next() {
 		var u domain.Usuario
 		var rolNombre string
-		if err := rows.Scan(&u.ID, &u.NombreCompleto, &u.Email, &u.RolID, &u.Activo, &u.CreatedAt, &u.UpdatedAt, &rolNombre); err != nil {
+		if err := rows.Scan(&u.ID, &u.NombreCompleto, &u.Email, &u.RolID, &u.Activo, &u.DebeCambiarPassword, &u.PasswordChangedAt,
+			&u.IntentosFallidos, &u.UltimoFalloAt, &u.BloqueadoHasta, &u.CreatedAt, &u.UpdatedAt, &rolNombre); err != nil {
 			return nil, 0, err
 		}
 		u.Rol = &domain.Rol{Nombre: rolNombre}
@@ -83,15 +94,59 @@ func (r *UsuarioRepository) GetAll(ctx context.Context, offset, limit int) ([]do
 	return usuarios, total, nil
 }
 
-func (r *UsuarioRepository) Update(ctx context.Context, u *domain.Usuario) error {
+func (r *UsuarioRepository) Update(ctx context.Context, u *domain.Usuario, revocarTokens bool) error {
+	return r.db.QueryRowContext(ctx,
+		`UPDATE usuarios SET nombre_completo = $1, email = $2, rol_id = $3, activo = $4,
+		        token_version = token_version + CASE WHEN $5 THEN 1 ELSE 0 END
+		 WHERE id = $6
+		 RETURNING token_version`,
+		u.NombreCompleto, u.Email, u.RolID, u.Activo, revocarTokens, u.ID).Scan(&u.TokenVersion)
+}
+
+func (r *UsuarioRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE usuarios SET activo = false, token_version = token_version + 1 WHERE id = $1", id)
+	return err
+}
+
+func (r *UsuarioRepository) IncrementTokenVersion(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE usuarios SET token_version = token_version + 1 WHERE id = $1", id)
+	return err
+}
+
+func (r *UsuarioRepository) GetTokenVersion(ctx context.Context, id uuid.UUID) (int, bool, error) {
+	var version int
+	var activo bool
+	err := r.db.QueryRowContext(ctx, "SELECT token_version, activo FROM usuarios WHERE id = $1", id).Scan(&version, &activo)
+	return version, activo, err
+}
+
+func (r *UsuarioRepository) RegistrarLoginFallido(ctx context.Context, id uuid.UUID, maxIntentos int, bloqueo time.Duration) (int, *time.Time, error) {
+	// Un solo UPDATE para que dos fallos simultáneos no se pisen la cuenta.
+	var intentos int
+	var bloqueadoHasta *time.Time
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE usuarios SET
+		     bloqueado_hasta = CASE WHEN intentos_fallidos + 1 >= $1
+		                            THEN NOW() + $2 * INTERVAL '1 second' ELSE bloqueado_hasta END,
+		     intentos_fallidos = CASE WHEN intentos_fallidos + 1 >= $1 THEN 0 ELSE intentos_fallidos + 1 END,
+		     ultimo_fallo_at = NOW()
+		 WHERE id = $3
+		 RETURNING intentos_fallidos, bloqueado_hasta`,
+		maxIntentos, int(bloqueo.Seconds()), id).
+		Scan(&intentos, &bloqueadoHasta)
+	return intentos, bloqueadoHasta, err
+}
+
+func (r *UsuarioRepository) Desbloquear(ctx context.Context, id uuid.UUID) error {
 	_, err := r.db.ExecContext(ctx,
-		`UPDATE usuarios SET nombre_completo = $1, email = $2, rol_id = $3, activo = $4
-		 WHERE id = $5`,
-		u.NombreCompleto, u.Email, u.RolID, u.Activo, u.ID)
+		"UPDATE usuarios SET intentos_fallidos = 0, ultimo_fallo_at = NULL, bloqueado_hasta = NULL WHERE id = $1", id)
 	return err
 }
 
-func (r *UsuarioRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	_, err := r.db.ExecContext(ctx, "UPDATE usuarios SET activo = false WHERE id = $1", id)
+func (r *UsuarioRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE usuarios SET password_hash = $1, debe_cambiar_password = false, password_changed_at = NOW(),
+		     token_version = token_version + 1
+		 WHERE id = $2`, passwordHash, id)
 	return err
 }
@@ -0,0 +1,282 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3 guarda los archivos en un bucket compatible con S3 (AWS, MinIO, etc.).
+// Las peticiones se firman con AWS Signature Version 4.
+type S3 struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+	client    *http.Client
+	// descargas no tiene un plazo total: el objeto se lee al ritmo en que el
+	// cliente HTTP de la aplicación lo recibe, y lo corta el contexto.
+	descargas *http.Client
+}
+
+func NewS3(endpoint, region, bucket, accessKey, secretKey string, pathStyle bool) (*S3, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("S3_ENDPOINT inválido: %q", endpoint)
+	}
+	return &S3{
+		endpoint:  u,
+		region:    region,
+		bucket:    bucket,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		pathStyle: pathStyle,
+		client:    &http.Client{Timeout: 60 * time.Second},
+		descargas: &http.Client{Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			ResponseHeaderTimeout: 60 * time.Second,
+		}},
+	}, nil
+}
+
+// Put lee el archivo completo para calcular el hash del contenido que exige
+// la firma; los archivos que maneja la aplicación tienen un tamaño acotado.
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	req, err := s.request(ctx, http.MethodPut, key, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	_, err = s.do(req)
+	return err
+}
+
+// Get consulta el tamaño del objeto y devuelve un lector que lo descarga a
+// medida que se lee, sin cargarlo en memoria; ver objetoS3.
+func (s *S3) Get(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	req, err := s.request(ctx, http.MethodHead, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.abrir(s.client, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.ContentLength < 0 {
+		return nil, fmt.Errorf("s3 HEAD %s: falta Content-Length", req.URL.Path)
+	}
+	return &objetoS3{s: s, ctx: ctx, key: key, size: resp.ContentLength}, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	req, err := s.request(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	_, err = s.do(req)
+	return err
+}
+
+func (s *S3) do(req *http.Request) ([]byte, error) {
+	resp, err := s.abrir(s.client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// abrir envía la petición y devuelve la respuesta sin leer el cuerpo, o un
+// error con el mensaje de S3 si no fue exitosa.
+func (s *S3) abrir(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return nil, fmt.Errorf("s3 %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+// request arma una petición firmada para el objeto key.
+func (s *S3) request(ctx context.Context, method, key string, body []byte) (*http.Request, error) {
+	if err := validarClave(key); err != nil {
+		return nil, err
+	}
+
+	u := *s.endpoint
+	if s.pathStyle {
+		u.Path = "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	u.RawPath = uriEncode(u.Path, false)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	s.firmar(req, body, time.Now().UTC())
+	return req, nil
+}
+
+func (s *S3) firmar(req *http.Request, body []byte, ahora time.Time) {
+	amzDate := ahora.Format("20060102T150405Z")
+	fecha := ahora.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, false),
+		"", // sin query string
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := fecha + "/" + s.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	kDate := hmacSHA256([]byte("AWS4"+s.secretKey), fecha)
+	kRegion := hmacSHA256(kDate, s.region)
+	kService := hmacSHA256(kRegion, "s3")
+	kSigning := hmacSHA256(kService, "aws4_request")
+	firma := hex.EncodeToString(hmacSHA256(kSigning, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, firma))
+}
+
+// uriEncode codifica según las reglas de SigV4: solo se conservan los
+// caracteres no reservados (y "/" en rutas si encodeSlash es false).
+func uriEncode(v string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// objetoS3 lee un objeto de S3 por rangos. Seek no hace peticiones: la lectura
+// siguiente pide el objeto desde la nueva posición, así que http.ServeContent
+// puede averiguar el tamaño y atender Range sin descargar de más.
+type objetoS3 struct {
+	s    *S3
+	ctx  context.Context
+	key  string
+	size int64
+	pos  int64
+	body io.ReadCloser
+}
+
+func (o *objetoS3) Read(p []byte) (int, error) {
+	if o.pos >= o.size {
+		return 0, io.EOF
+	}
+	if o.body == nil {
+		req, err := o.s.request(o.ctx, http.MethodGet, o.key, nil)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", o.pos))
+		resp, err := o.s.abrir(o.s.descargas, req)
+		if err != nil {
+			return 0, err
+		}
+		o.body = resp.Body
+	}
+	n, err := o.body.Read(p)
+	o.pos += int64(n)
+	if err == io.EOF && o.pos < o.size {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (o *objetoS3) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = o.pos + offset
+	case io.SeekEnd:
+		pos = o.size + offset
+	default:
+		return 0, fmt.Errorf("s3: whence inválido: %d", whence)
+	}
+	if pos < 0 {
+		return 0, fmt.Errorf("s3: posición negativa")
+	}
+	if pos != o.pos && o.body != nil {
+		o.body.Close()
+		o.body = nil
+	}
+	o.pos = pos
+	return pos, nil
+}
+
+func (o *objetoS3) Close() error {
+	if o.body == nil {
+		return nil
+	}
+	err := o.body.Close()
+	o.body = nil
+	return err
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tunek/centro-caribel/internal/domain"
+	"github.com/tunek/centro-caribel/internal/infrastructure/config"
+)
+
+// New crea el almacenamiento indicado en la configuración.
+func New(cfg config.StorageConfig) (domain.FileStorage, error) {
+	switch cfg.Driver {
+	case "", "local":
+		return NewLocal(cfg.LocalPath)
+	case "s3":
+		if cfg.S3Bucket == "" || cfg.S3AccessKey == "" || cfg.S3SecretKey == "" {
+			return nil, fmt.Errorf("S3_BUCKET, S3_ACCESS_KEY y S3_SECRET_KEY son requeridos para STORAGE_DRIVER=s3")
+		}
+		return NewS3(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey, cfg.S3PathStyle)
+	default:
+		return nil, fmt.Errorf("STORAGE_DRIVER desconocido: %s", cfg.Driver)
+	}
+}
+
+// validarClave rechaza claves vacías, absolutas o con segmentos "..".
+func validarClave(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return fmt.Errorf("clave de almacenamiento inválida: %q", key)
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return fmt.Errorf("clave de almacenamiento inválida: %q", key)
+		}
+	}
+	return nil
+}
@@ -24,9 +24,17 @@ func (r *LoginRequest) Validate() error {
 }
 
 type LoginResponse struct {
-	Token        string `json:"token"`
-	RefreshToken string `json:"refresh_token"`
-	ExpiresIn    int    `json:"expires_in"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
+	// DebeCambiarPassword y DebeInscribir2FA indican que el token solo sirve
+	// para completar esa acción (POST /auth/cambiar-password, /auth/2fa/...).
+	DebeCambiarPassword bool `json:"debe_cambiar_password"`
+	DebeInscribir2FA    bool `json:"debe_inscribir_2fa"`
+	// Si Requiere2FA es true no hay tokens de sesión: PreAuthToken se envía
+	// con el código a POST /auth/2fa/verificar antes de 5 minutos.
+	Requiere2FA  bool   `json:"requiere_2fa"`
+	PreAuthToken string `json:"pre_auth_token,omitempty"`
 }
 
 type RefreshRequest struct {
@@ -39,3 +47,63 @@ func (r *RefreshRequest) Validate() error {
 	}
 	return nil
 }
+
+type CambiarPasswordRequest struct {
+	PasswordActual string `json:"password_actual"`
+	PasswordNueva  string `json:"password_nueva"`
+}
+
+func (r *CambiarPasswordRequest) Validate() error {
+	if err := validator.RequiredString(r.PasswordActual, "password_actual"); err != nil {
+		return err
+	}
+	if err := validator.RequiredString(r.PasswordNueva, "password_nueva"); err != nil {
+		return err
+	}
+	return nil
+}
+
+type RestablecerPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (r *RestablecerPasswordRequest) Validate() error {
+	if err := validator.RequiredString(r.Token, "token"); err != nil {
+		return err
+	}
+	if err := validator.RequiredString(r.Password, "password"); err != nil {
+		return err
+	}
+	return nil
+}
+
+type Verificar2FARequest struct {
+	PreAuthToken string `json:"pre_auth_token"`
+	Codigo       string `json:"codigo"`
+}
+
+func (r *Verificar2FARequest) Validate() error {
+	if err := validator.RequiredString(r.PreAuthToken, "pre_auth_token"); err != nil {
+		return err
+	}
+	if err := validator.RequiredString(r.Codigo, "codigo"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Codigo2FARequest lleva un código de la app de autenticación o, donde se
+// acepte, uno de recuperación.
+type Codigo2FARequest struct {
+	Codigo string `json:"codigo"`
+}
+
+func (r *Codigo2FARequest) Validate() error {
+	return validator.RequiredString(r.Codigo, "codigo")
+}
+
+type Activar2FAResponse struct {
+	CodigosRecuperacion []string      `json:"codigos_recuperacion"`
+	Sesion              LoginResponse `json:"sesion"`
+}
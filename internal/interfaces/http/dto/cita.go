@@ -37,10 +37,19 @@ func (r *CreateCitaRequest) Validate() error {
 }
 
 type UpdateEstadoCitaRequest struct {
-	Estado domain.EstadoCita `json:"estado"`
-	Fecha  string            `json:"fecha,omitempty"`
-	Hora   string            `json:"hora,omitempty"`
-	Turno  domain.TurnoCita  `json:"turno,omitempty"`
+	Estado domain.EstadoCita    `json:"estado"`
+	Fecha  string               `json:"fecha,omitempty"`
+	Hora   string               `json:"hora,omitempty"`
+	Turno  domain.TurnoCita     `json:"turno,omitempty"`
+	Nota   *NotaAtencionRequest `json:"nota,omitempty"` // solo con estado ATENDIDA
+}
+
+type NotaAtencionRequest struct {
+	Tipo        string                 `json:"tipo"`
+	Contenido   string                 `json:"contenido"`
+	PlantillaID *uuid.UUID             `json:"plantilla_id,omitempty"`
+	Datos       map[string]interface{} `json:"datos,omitempty"`
+	Borrador    bool                   `json:"borrador"` // deja la nota sin firmar
 }
 
 func (r *UpdateEstadoCitaRequest) Validate() error {
@@ -61,5 +70,22 @@ func (r *UpdateEstadoCitaRequest) Validate() error {
 			return apperrors.NewBadRequest("El turno debe ser 'AM' o 'PM'")
 		}
 	}
+	if r.Nota != nil {
+		if r.Estado != domain.EstadoAtendida {
+			return apperrors.NewBadRequest("Solo se puede registrar una nota al marcar la cita como ATENDIDA")
+		}
+		if r.Nota.PlantillaID != nil {
+			if len(r.Nota.Datos) == 0 {
+				return apperrors.NewBadRequest("El campo nota.datos es requerido al usar una plantilla")
+			}
+		} else {
+			if r.Nota.Tipo == "" {
+				r.Nota.Tipo = "TRATAMIENTO"
+			}
+			if err := validator.RequiredString(r.Nota.Contenido, "nota.contenido"); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
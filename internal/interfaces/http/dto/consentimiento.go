@@ -1,18 +1,68 @@
 package dto
 
 import (
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
 	"github.com/tunek/centro-caribel/pkg/validator"
 )
 
 type CreateConsentimientoRequest struct {
-	FirmaDigital  string `json:"firma_digital"` // base64 encoded
-	AutorizaFotos bool   `json:"autoriza_fotos"`
-	Contenido     string `json:"contenido"`
+	FirmaDigital  string     `json:"firma_digital"` // PNG o SVG en base64 o data URL
+	AutorizaFotos bool       `json:"autoriza_fotos"`
+	PlantillaID   uuid.UUID  `json:"plantilla_id"`
+	Tratamiento   string     `json:"tratamiento,omitempty"` // por defecto, el de la plantilla
+	TutorID       *uuid.UUID `json:"tutor_id,omitempty"`    // requerido si el paciente es menor de edad
 }
 
 func (r *CreateConsentimientoRequest) Validate() error {
-	if err := validator.RequiredString(r.Contenido, "contenido"); err != nil {
+	if err := validator.RequiredString(r.FirmaDigital, "firma_digital"); err != nil {
 		return err
 	}
+	if r.PlantillaID == uuid.Nil {
+		return apperrors.NewBadRequest("El campo plantilla_id es requerido")
+	}
 	return nil
 }
+
+type PrevisualizarConsentimientoRequest struct {
+	PlantillaID uuid.UUID  `json:"plantilla_id"`
+	Tratamiento string     `json:"tratamiento,omitempty"`
+	TutorID     *uuid.UUID `json:"tutor_id,omitempty"`
+}
+
+func (r *PrevisualizarConsentimientoRequest) Validate() error {
+	if r.PlantillaID == uuid.Nil {
+		return apperrors.NewBadRequest("El campo plantilla_id es requerido")
+	}
+	return nil
+}
+
+type RevocarConsentimientoRequest struct {
+	Alcance domain.AlcanceRevocacion `json:"alcance"` // TOTAL o FOTOS
+	Motivo  string                   `json:"motivo"`
+	Fecha   string                   `json:"fecha,omitempty"` // YYYY-MM-DD o RFC 3339; por defecto, ahora
+}
+
+func (r *RevocarConsentimientoRequest) Validate() error {
+	if !r.Alcance.IsValid() {
+		return apperrors.NewBadRequest("Alcance inválido. Use: TOTAL o FOTOS")
+	}
+	return validator.RequiredString(r.Motivo, "motivo")
+}
+
+type PlantillaConsentimientoRequest struct {
+	Nombre          string `json:"nombre"`
+	TipoTratamiento string `json:"tipo_tratamiento"`
+	Texto           string `json:"texto"`
+	VigenciaDias    *int   `json:"vigencia_dias,omitempty"` // sin valor: los consentimientos no vencen
+	Requerido       bool   `json:"requerido"`               // las citas del tratamiento exigen consentimiento vigente
+	Activa          *bool  `json:"activa,omitempty"`        // solo al actualizar; por defecto true
+}
+
+func (r *PlantillaConsentimientoRequest) Validate() error {
+	if err := validator.RequiredString(r.Nombre, "nombre"); err != nil {
+		return err
+	}
+	return validator.RequiredString(r.Texto, "texto")
+}
@@ -1,6 +1,9 @@
 package dto
 
 import (
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/domain"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
 	"github.com/tunek/centro-caribel/pkg/validator"
 )
 
@@ -15,12 +18,39 @@ func (r *UpdateAntecedentesRequest) Validate() error {
 	return nil
 }
 
+type CambiarEstadoHistoriaRequest struct {
+	Motivo string `json:"motivo"`
+}
+
+func (r *CambiarEstadoHistoriaRequest) Validate() error {
+	return validator.RequiredString(r.Motivo, "motivo")
+}
+
+// DescartarNotaRequest lleva el motivo, obligatorio cuando quien descarta no
+// es el autor del borrador.
+type DescartarNotaRequest struct {
+	Motivo string `json:"motivo"`
+}
+
 type CreateNotaRequest struct {
-	Tipo      string `json:"tipo"`
-	Contenido string `json:"contenido"`
+	Tipo        string                 `json:"tipo"`
+	Contenido   string                 `json:"contenido"`
+	CitaID      *uuid.UUID             `json:"cita_id,omitempty"`
+	PaqueteID   *uuid.UUID             `json:"paquete_id,omitempty"`
+	PlantillaID *uuid.UUID             `json:"plantilla_id,omitempty"`
+	Datos       map[string]interface{} `json:"datos,omitempty"` // campos de la plantilla
+	Borrador    bool                   `json:"borrador"`        // deja la nota sin firmar
 }
 
+// Validate exige tipo y contenido salvo en notas con plantilla, donde el
+// contenido se genera a partir de datos.
 func (r *CreateNotaRequest) Validate() error {
+	if r.PlantillaID != nil {
+		if len(r.Datos) == 0 {
+			return apperrors.NewBadRequest("El campo datos es requerido al usar una plantilla")
+		}
+		return nil
+	}
 	if err := validator.RequiredString(r.Tipo, "tipo"); err != nil {
 		return err
 	}
@@ -29,3 +59,121 @@ func (r *CreateNotaRequest) Validate() error {
 	}
 	return nil
 }
+
+type UpdateNotaRequest struct {
+	Tipo      string                 `json:"tipo"`
+	Contenido string                 `json:"contenido"`
+	Datos     map[string]interface{} `json:"datos,omitempty"` // solo notas con plantilla
+}
+
+func (r *UpdateNotaRequest) Validate() error {
+	if r.Contenido == "" && len(r.Datos) == 0 {
+		return apperrors.NewBadRequest("Debe enviar contenido o datos")
+	}
+	return nil
+}
+
+type CreateAdendaRequest struct {
+	Contenido string `json:"contenido"`
+}
+
+func (r *CreateAdendaRequest) Validate() error {
+	return validator.RequiredString(r.Contenido, "contenido")
+}
+
+type AlergiaRequest struct {
+	Sustancia string `json:"sustancia"`
+	Reaccion  string `json:"reaccion"`
+	Severidad string `json:"severidad"` // LEVE, MODERADA, SEVERA
+}
+
+func (r *AlergiaRequest) Validate() error {
+	if err := validator.RequiredString(r.Sustancia, "sustancia"); err != nil {
+		return err
+	}
+	if err := validator.RequiredString(r.Severidad, "severidad"); err != nil {
+		return err
+	}
+	return nil
+}
+
+type MedicamentoRequest struct {
+	Nombre      string `json:"nombre"`
+	Dosis       string `json:"dosis"`
+	Frecuencia  string `json:"frecuencia"`
+	FechaInicio string `json:"fecha_inicio"` // formato: 2006-01-02
+	FechaFin    string `json:"fecha_fin"`    // opcional
+	Indicacion  string `json:"indicacion"`
+}
+
+func (r *MedicamentoRequest) Validate() error {
+	if err := validator.RequiredString(r.Nombre, "nombre"); err != nil {
+		return err
+	}
+	if err := validator.RequiredString(r.Dosis, "dosis"); err != nil {
+		return err
+	}
+	if err := validator.RequiredString(r.Frecuencia, "frecuencia"); err != nil {
+		return err
+	}
+	if err := validator.RequiredString(r.FechaInicio, "fecha_inicio"); err != nil {
+		return err
+	}
+	return nil
+}
+
+type CondicionRequest struct {
+	Tipo          string `json:"tipo"` // PERSONAL, FAMILIAR
+	Codigo        string `json:"codigo"`
+	Descripcion   string `json:"descripcion"`
+	Parentesco    string `json:"parentesco"`
+	Observaciones string `json:"observaciones"`
+}
+
+func (r *CondicionRequest) Validate() error {
+	if err := validator.RequiredString(r.Tipo, "tipo"); err != nil {
+		return err
+	}
+	if err := validator.RequiredString(r.Descripcion, "descripcion"); err != nil {
+		return err
+	}
+	return nil
+}
+
+type PlantillaNotaRequest struct {
+	Nombre      string                  `json:"nombre"`
+	Descripcion string                  `json:"descripcion"`
+	TipoNota    string                  `json:"tipo_nota"`
+	Campos      []domain.CampoPlantilla `json:"campos"`
+	Activa      *bool                   `json:"activa,omitempty"` // solo al actualizar; por defecto true
+}
+
+func (r *PlantillaNotaRequest) Validate() error {
+	if err := validator.RequiredString(r.Nombre, "nombre"); err != nil {
+		return err
+	}
+	if len(r.Campos) == 0 {
+		return apperrors.NewBadRequest("El campo campos es requerido")
+	}
+	return nil
+}
+
+type CreateMedicionRequest struct {
+	Fecha              string     `json:"fecha,omitempty"` // YYYY-MM-DD o RFC 3339; por defecto ahora
+	CitaID             *uuid.UUID `json:"cita_id,omitempty"`
+	PaqueteID          *uuid.UUID `json:"paquete_id,omitempty"`
+	PesoKg             *float64   `json:"peso_kg,omitempty"`
+	TallaCm            *float64   `json:"talla_cm,omitempty"`
+	CinturaCm          *float64   `json:"cintura_cm,omitempty"`
+	CaderaCm           *float64   `json:"cadera_cm,omitempty"`
+	AbdomenCm          *float64   `json:"abdomen_cm,omitempty"`
+	PresionSistolica   *int       `json:"presion_sistolica,omitempty"`
+	PresionDiastolica  *int       `json:"presion_diastolica,omitempty"`
+	FrecuenciaCardiaca *int       `json:"frecuencia_cardiaca,omitempty"`
+	EscalaDolor        *int       `json:"escala_dolor,omitempty"`
+	Observaciones      string     `json:"observaciones"`
+}
+
+func (r *CreateMedicionRequest) Validate() error {
+	return nil
+}
@@ -27,3 +27,31 @@ func (r *CreatePacienteRequest) Validate() error {
 	}
 	return nil
 }
+
+type ArchivarPacienteRequest struct {
+	Motivo string `json:"motivo"`
+}
+
+func (r *ArchivarPacienteRequest) Validate() error {
+	return validator.RequiredString(r.Motivo, "motivo")
+}
+
+type CreateTutorRequest struct {
+	NombreCompleto string `json:"nombre_completo"`
+	CI             string `json:"ci"`
+	Parentesco     string `json:"parentesco"`
+	Celular        string `json:"celular"`
+}
+
+func (r *CreateTutorRequest) Validate() error {
+	if err := validator.RequiredString(r.NombreCompleto, "nombre_completo"); err != nil {
+		return err
+	}
+	if err := validator.RequiredString(r.CI, "ci"); err != nil {
+		return err
+	}
+	if err := validator.RequiredString(r.Parentesco, "parentesco"); err != nil {
+		return err
+	}
+	return nil
+}
@@ -22,7 +22,8 @@ func (r *CreateUsuarioRequest) Validate() error {
 	if err := validator.ValidEmail(r.Email); err != nil {
 		return err
 	}
-	if err := validator.MinLength(r.Password, "password", 8); err != nil {
+	// La política de contraseñas se aplica en el servicio.
+	if err := validator.RequiredString(r.Password, "password"); err != nil {
 		return err
 	}
 	if r.RolID == uuid.Nil {
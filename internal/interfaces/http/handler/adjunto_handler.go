@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"mime"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/application/adjunto"
+	"github.com/tunek/centro-caribel/internal/domain"
+	"github.com/tunek/centro-caribel/internal/interfaces/http/middleware"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+	"github.com/tunek/centro-caribel/pkg/response"
+)
+
+type AdjuntoHandler struct {
+	service *adjunto.Service
+}
+
+func NewAdjuntoHandler(service *adjunto.Service) *AdjuntoHandler {
+	return &AdjuntoHandler{service: service}
+}
+
+func (h *AdjuntoHandler) GetByPaciente(w http.ResponseWriter, r *http.Request) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+
+	var notaID *uuid.UUID
+	if v := r.URL.Query().Get("nota_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			response.Error(w, apperrors.NewBadRequest("nota_id inválido"))
+			return
+		}
+		notaID = &id
+	}
+
+	adjuntos, err := h.service.GetByPaciente(r.Context(), pacienteID, notaID, middleware.GetRolNombre(r.Context()))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, adjuntos)
+}
+
+func (h *AdjuntoHandler) Subir(w http.ResponseWriter, r *http.Request) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, adjunto.TamanoMaximo+1<<20)
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		response.Error(w, apperrors.NewBadRequest("El archivo excede el tamaño máximo o el formulario es inválido"))
+		return
+	}
+
+	file, header, err := r.FormFile("archivo")
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("El campo 'archivo' es requerido"))
+		return
+	}
+	defer file.Close()
+
+	datos := adjunto.DatosAdjunto{
+		Descripcion: r.FormValue("descripcion"),
+		Visibilidad: domain.VisibilidadAdjunto(r.FormValue("visibilidad")),
+	}
+	if datos.NotaID, err = parseFormUUID(r, "nota_id"); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	a, err := h.service.Subir(r.Context(), pacienteID, file, header.Size, header.Filename, datos, userID, middleware.GetRolNombre(r.Context()))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, a)
+}
+
+func (h *AdjuntoHandler) Descargar(w http.ResponseWriter, r *http.Request) {
+	pacienteID, adjuntoID, err := parseAdjuntoPath(r)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	a, contenido, err := h.service.Abrir(r.Context(), pacienteID, adjuntoID, middleware.GetRolNombre(r.Context()))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	defer contenido.Close()
+
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.NombreArchivo}))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("ETag", `"`+a.SHA256+`"`)
+	http.ServeContent(w, r, a.NombreArchivo, a.CreatedAt, contenido)
+}
+
+func (h *AdjuntoHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	pacienteID, adjuntoID, err := parseAdjuntoPath(r)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), pacienteID, adjuntoID, middleware.GetRolNombre(r.Context())); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "Adjunto eliminado"})
+}
+
+func parseAdjuntoPath(r *http.Request) (uuid.UUID, uuid.UUID, error) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, apperrors.NewBadRequest("ID de paciente inválido")
+	}
+	adjuntoID, err := uuid.Parse(r.PathValue("adjuntoId"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, apperrors.NewBadRequest("ID de adjunto inválido")
+	}
+	return pacienteID, adjuntoID, nil
+}
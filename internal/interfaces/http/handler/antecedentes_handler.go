@@ -0,0 +1,337 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/application/historia"
+	"github.com/tunek/centro-caribel/internal/domain"
+	"github.com/tunek/centro-caribel/internal/interfaces/http/dto"
+	"github.com/tunek/centro-caribel/internal/interfaces/http/middleware"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+	"github.com/tunek/centro-caribel/pkg/response"
+	"github.com/tunek/centro-caribel/pkg/validator"
+)
+
+// parseItemPath obtiene el ID del paciente y el del registro de antecedente.
+func parseItemPath(r *http.Request) (uuid.UUID, uuid.UUID, error) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, apperrors.NewBadRequest("ID de paciente inválido")
+	}
+	itemID, err := uuid.Parse(r.PathValue("itemId"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, apperrors.NewBadRequest("ID de registro inválido")
+	}
+	return pacienteID, itemID, nil
+}
+
+// Alergias
+
+func (h *HistoriaHandler) GetAlergias(w http.ResponseWriter, r *http.Request) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+
+	alergias, err := h.service.GetAlergias(r.Context(), pacienteID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, alergias)
+}
+
+func (h *HistoriaHandler) CreateAlergia(w http.ResponseWriter, r *http.Request) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+
+	var req dto.AlergiaRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	alergia, err := h.service.CreateAlergia(r.Context(), pacienteID, req.Sustancia, req.Reaccion,
+		domain.SeveridadAlergia(req.Severidad), userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, alergia)
+}
+
+func (h *HistoriaHandler) UpdateAlergia(w http.ResponseWriter, r *http.Request) {
+	pacienteID, id, err := parseItemPath(r)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	var req dto.AlergiaRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	alergia, err := h.service.UpdateAlergia(r.Context(), pacienteID, id, req.Sustancia, req.Reaccion,
+		domain.SeveridadAlergia(req.Severidad), userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, alergia)
+}
+
+func (h *HistoriaHandler) DeleteAlergia(w http.ResponseWriter, r *http.Request) {
+	pacienteID, id, err := parseItemPath(r)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	if err := h.service.DeleteAlergia(r.Context(), pacienteID, id, userID); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "Alergia eliminada"})
+}
+
+// Medicamentos
+
+func (h *HistoriaHandler) GetMedicamentos(w http.ResponseWriter, r *http.Request) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+
+	soloActivos := r.URL.Query().Get("activos") == "true"
+
+	medicamentos, err := h.service.GetMedicamentos(r.Context(), pacienteID, soloActivos)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, medicamentos)
+}
+
+func (h *HistoriaHandler) CreateMedicamento(w http.ResponseWriter, r *http.Request) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+
+	var req dto.MedicamentoRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	medicamento, err := h.service.CreateMedicamento(r.Context(), pacienteID, medicamentoInput(req), userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, medicamento)
+}
+
+func (h *HistoriaHandler) UpdateMedicamento(w http.ResponseWriter, r *http.Request) {
+	pacienteID, id, err := parseItemPath(r)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	var req dto.MedicamentoRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	medicamento, err := h.service.UpdateMedicamento(r.Context(), pacienteID, id, medicamentoInput(req), userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, medicamento)
+}
+
+func (h *HistoriaHandler) DeleteMedicamento(w http.ResponseWriter, r *http.Request) {
+	pacienteID, id, err := parseItemPath(r)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	if err := h.service.DeleteMedicamento(r.Context(), pacienteID, id, userID); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "Medicamento eliminado"})
+}
+
+func medicamentoInput(req dto.MedicamentoRequest) historia.MedicamentoInput {
+	return historia.MedicamentoInput{
+		Nombre:      req.Nombre,
+		Dosis:       req.Dosis,
+		Frecuencia:  req.Frecuencia,
+		FechaInicio: req.FechaInicio,
+		FechaFin:    req.FechaFin,
+		Indicacion:  req.Indicacion,
+	}
+}
+
+// Condiciones
+
+func (h *HistoriaHandler) GetCondiciones(w http.ResponseWriter, r *http.Request) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+
+	condiciones, err := h.service.GetCondiciones(r.Context(), pacienteID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, condiciones)
+}
+
+func (h *HistoriaHandler) CreateCondicion(w http.ResponseWriter, r *http.Request) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+
+	var req dto.CondicionRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	condicion, err := h.service.CreateCondicion(r.Context(), pacienteID, condicionInput(req), userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, condicion)
+}
+
+func (h *HistoriaHandler) UpdateCondicion(w http.ResponseWriter, r *http.Request) {
+	pacienteID, id, err := parseItemPath(r)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	var req dto.CondicionRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	condicion, err := h.service.UpdateCondicion(r.Context(), pacienteID, id, condicionInput(req), userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, condicion)
+}
+
+func (h *HistoriaHandler) DeleteCondicion(w http.ResponseWriter, r *http.Request) {
+	pacienteID, id, err := parseItemPath(r)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	if err := h.service.DeleteCondicion(r.Context(), pacienteID, id, userID); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "Antecedente eliminado"})
+}
+
+func condicionInput(req dto.CondicionRequest) historia.CondicionInput {
+	return historia.CondicionInput{
+		Tipo:          domain.TipoCondicion(req.Tipo),
+		Codigo:        req.Codigo,
+		Descripcion:   req.Descripcion,
+		Parentesco:    req.Parentesco,
+		Observaciones: req.Observaciones,
+	}
+}
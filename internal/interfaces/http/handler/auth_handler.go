@@ -1,10 +1,18 @@
 package handler
 
 import (
+	"encoding/json"
+	"net"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/tunek/centro-caribel/internal/application/auth"
+	"github.com/tunek/centro-caribel/internal/domain"
 	"github.com/tunek/centro-caribel/internal/interfaces/http/dto"
+	"github.com/tunek/centro-caribel/internal/interfaces/http/middleware"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
 	"github.com/tunek/centro-caribel/pkg/response"
 	"github.com/tunek/centro-caribel/pkg/validator"
 )
@@ -24,17 +32,13 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, refreshToken, err := h.service.Login(r.Context(), req.Email, req.Password)
+	res, err := h.service.Login(r.Context(), req.Email, req.Password, dispositivo(r))
 	if err != nil {
 		response.Error(w, err)
 		return
 	}
 
-	response.JSON(w, http.StatusOK, dto.LoginResponse{
-		Token:        token,
-		RefreshToken: refreshToken,
-		ExpiresIn:    28800, // 8 horas en segundos
-	})
+	response.JSON(w, http.StatusOK, loginResponse(res))
 }
 
 func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
@@ -44,15 +48,355 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, refreshToken, err := h.service.RefreshToken(r.Context(), req.RefreshToken)
+	res, err := h.service.RefreshToken(r.Context(), req.RefreshToken, dispositivo(r))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, loginResponse(res))
+}
+
+// JWKS publica las claves públicas de verificación de los access tokens
+// (RFC 7517). No usa el sobre de respuesta de la API porque los clientes JWKS
+// esperan el documento tal cual.
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(struct {
+		Keys []auth.JWK `json:"keys"`
+	}{h.service.ClavesPublicas()})
+}
+
+// Logout cierra la sesión del refresh token enviado.
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req dto.RefreshRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	if err := h.service.Logout(r.Context(), req.RefreshToken); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "Sesión cerrada"})
+}
+
+// LogoutAll cierra todas las sesiones del usuario autenticado.
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	if err := h.service.LogoutAll(r.Context(), userID); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "Se cerraron todas las sesiones"})
+}
+
+// CambiarPassword cambia la contraseña del usuario autenticado y devuelve
+// tokens nuevos: las demás sesiones se cierran.
+func (h *AuthHandler) CambiarPassword(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	var req dto.CambiarPasswordRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	res, err := h.service.CambiarPassword(r.Context(), userID, req.PasswordActual, req.PasswordNueva, dispositivo(r))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, loginResponse(res))
+}
+
+// SolicitarResetPassword emite un token de restablecimiento para el usuario
+// {id} y lo envía por el notificador configurado.
+func (h *AuthHandler) SolicitarResetPassword(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID inválido"))
+		return
+	}
+	adminID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	vence, err := h.service.SolicitarResetPassword(r.Context(), id, adminID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{
+		"message":    "Se envió el enlace de restablecimiento",
+		"expires_at": vence.Format(time.RFC3339),
+	})
+}
+
+// RestablecerPassword fija una contraseña nueva con un token de restablecimiento.
+func (h *AuthHandler) RestablecerPassword(w http.ResponseWriter, r *http.Request) {
+	var req dto.RestablecerPasswordRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	if err := h.service.RestablecerPassword(r.Context(), req.Token, req.Password); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "Contraseña restablecida; inicie sesión con la nueva contraseña"})
+}
+
+// Verificar2FA completa un login con el código de la app de autenticación
+// o un código de recuperación.
+func (h *AuthHandler) Verificar2FA(w http.ResponseWriter, r *http.Request) {
+	var req dto.Verificar2FARequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	res, err := h.service.Verificar2FA(r.Context(), req.PreAuthToken, req.Codigo, dispositivo(r))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, loginResponse(res))
+}
+
+func (h *AuthHandler) GetEstado2FA(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	estado, err := h.service.GetEstado2FA(r.Context(), userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, estado)
+}
+
+// Inscribir2FA devuelve el secreto y el URI otpauth:// para la app.
+func (h *AuthHandler) Inscribir2FA(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	inscripcion, err := h.service.Inscribir2FA(r.Context(), userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, inscripcion)
+}
+
+// Activar2FA confirma la inscripción y devuelve los códigos de recuperación
+// y una sesión nueva.
+func (h *AuthHandler) Activar2FA(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	var req dto.Codigo2FARequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	codigos, res, err := h.service.Activar2FA(r.Context(), userID, req.Codigo, dispositivo(r))
 	if err != nil {
 		response.Error(w, err)
 		return
 	}
 
-	response.JSON(w, http.StatusOK, dto.LoginResponse{
-		Token:        token,
-		RefreshToken: refreshToken,
-		ExpiresIn:    28800,
+	response.JSON(w, http.StatusOK, dto.Activar2FAResponse{CodigosRecuperacion: codigos, Sesion: loginResponse(res)})
+}
+
+// RegenerarCodigos2FA reemplaza los códigos de recuperación.
+func (h *AuthHandler) RegenerarCodigos2FA(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	var req dto.Codigo2FARequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	codigos, err := h.service.RegenerarCodigos2FA(r.Context(), userID, req.Codigo, dispositivo(r))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string][]string{"codigos_recuperacion": codigos})
+}
+
+// Desactivar2FA quita el segundo factor y devuelve tokens nuevos: las demás
+// sesiones se cierran.
+func (h *AuthHandler) Desactivar2FA(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	var req dto.Codigo2FARequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	res, err := h.service.Desactivar2FA(r.Context(), userID, req.Codigo, dispositivo(r))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, loginResponse(res))
+}
+
+// Reset2FA borra el segundo factor del usuario {id} y cierra sus sesiones.
+func (h *AuthHandler) Reset2FA(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID inválido"))
+		return
+	}
+
+	if err := h.service.Reset2FA(r.Context(), id); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "Autenticación en dos pasos restablecida"})
+}
+
+func loginResponse(res *auth.ResultadoLogin) dto.LoginResponse {
+	if res.Requiere2FA {
+		return dto.LoginResponse{Requiere2FA: true, PreAuthToken: res.PreAuthToken}
+	}
+	return dto.LoginResponse{
+		Token:               res.Token,
+		RefreshToken:        res.RefreshToken,
+		ExpiresIn:           28800, // 8 horas en segundos
+		DebeCambiarPassword: res.DebeCambiarPassword,
+		DebeInscribir2FA:    res.DebeInscribir2FA,
+	}
+}
+
+// dispositivo toma el User-Agent y la IP de la conexión. No se confía en
+// X-Forwarded-For porque el cliente puede falsificarlo.
+func dispositivo(r *http.Request) auth.Dispositivo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return auth.Dispositivo{UserAgent: r.UserAgent(), IP: ip}
+}
+
+// GetIntentos lista el registro de intentos de login. Filtros opcionales:
+// email, usuario_id, ip, exitoso (true/false), desde y hasta (YYYY-MM-DD,
+// hasta inclusive).
+func (h *AuthHandler) GetIntentos(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page, _ := strconv.Atoi(q.Get("page"))
+	perPage, _ := strconv.Atoi(q.Get("per_page"))
+
+	var filtro domain.FiltroIntentosLogin
+	if email := q.Get("email"); email != "" {
+		filtro.Email = &email
+	}
+	if ip := q.Get("ip"); ip != "" {
+		filtro.IP = &ip
+	}
+	if idStr := q.Get("usuario_id"); idStr != "" {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			response.Error(w, apperrors.NewBadRequest("usuario_id inválido"))
+			return
+		}
+		filtro.UsuarioID = &id
+	}
+	if exitosoStr := q.Get("exitoso"); exitosoStr != "" {
+		exitoso, err := strconv.ParseBool(exitosoStr)
+		if err != nil {
+			response.Error(w, apperrors.NewBadRequest("exitoso debe ser true o false"))
+			return
+		}
+		filtro.Exitoso = &exitoso
+	}
+	if desdeStr := q.Get("desde"); desdeStr != "" {
+		desde, err := time.Parse("2006-01-02", desdeStr)
+		if err != nil {
+			response.Error(w, apperrors.NewBadRequest("Formato de fecha inválido"))
+			return
+		}
+		filtro.Desde = &desde
+	}
+	if hastaStr := q.Get("hasta"); hastaStr != "" {
+		hasta, err := time.Parse("2006-01-02", hastaStr)
+		if err != nil {
+			response.Error(w, apperrors.NewBadRequest("Formato de fecha inválido"))
+			return
+		}
+		hasta = hasta.AddDate(0, 0, 1)
+		filtro.Hasta = &hasta
+	}
+
+	intentos, total, err := h.service.GetIntentos(r.Context(), filtro, page, perPage)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = 20
+	}
+	totalPages := int(total) / perPage
+	if int(total)%perPage > 0 {
+		totalPages++
+	}
+
+	response.JSONWithMeta(w, http.StatusOK, intentos, &response.Meta{
+		Page:      page,
+		PerPage:   perPage,
+		Total:     total,
+		TotalPage: totalPages,
 	})
 }
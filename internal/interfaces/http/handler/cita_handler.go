@@ -117,17 +117,41 @@ func (h *CitaHandler) UpdateEstado(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
 	if req.Estado == "REAGENDADA" {
-		if err := h.service.Reagendar(r.Context(), id, req.Fecha, req.Hora, req.Turno); err != nil {
+		if err := h.service.Reagendar(r.Context(), id, req.Fecha, req.Hora, req.Turno, userID); err != nil {
 			response.Error(w, err)
 			return
 		}
-	} else {
-		if err := h.service.UpdateEstado(r.Context(), id, req.Estado); err != nil {
-			response.Error(w, err)
-			return
+		response.JSON(w, http.StatusOK, map[string]string{"message": "Estado actualizado"})
+		return
+	}
+
+	var nota *cita.NotaAtencion
+	if req.Nota != nil {
+		nota = &cita.NotaAtencion{
+			Tipo:        req.Nota.Tipo,
+			Contenido:   req.Nota.Contenido,
+			PlantillaID: req.Nota.PlantillaID,
+			Datos:       req.Nota.Datos,
+			Firmar:      !req.Nota.Borrador,
 		}
 	}
 
+	creada, err := h.service.UpdateEstado(r.Context(), id, req.Estado, nota, userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	if creada != nil {
+		response.JSON(w, http.StatusOK, map[string]interface{}{"message": "Estado actualizado", "nota": creada})
+		return
+	}
 	response.JSON(w, http.StatusOK, map[string]string{"message": "Estado actualizado"})
 }
@@ -39,7 +39,7 @@ func (h *ConsentimientoHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cons, err := h.service.Create(r.Context(), pacienteID, req.FirmaDigital, req.Contenido, req.AutorizaFotos, userID)
+	cons, err := h.service.Create(r.Context(), pacienteID, req.FirmaDigital, req.PlantillaID, req.Tratamiento, req.AutorizaFotos, req.TutorID, userID)
 	if err != nil {
 		response.Error(w, err)
 		return
@@ -63,3 +63,193 @@ func (h *ConsentimientoHandler) GetByPaciente(w http.ResponseWriter, r *http.Req
 
 	response.JSON(w, http.StatusOK, list)
 }
+
+func (h *ConsentimientoHandler) Previsualizar(w http.ResponseWriter, r *http.Request) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+
+	var req dto.PrevisualizarConsentimientoRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	plantilla, contenido, err := h.service.Previsualizar(r.Context(), req.PlantillaID, pacienteID, req.TutorID, req.Tratamiento)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]interface{}{
+		"plantilla_id":         plantilla.ID,
+		"plantilla_version_id": plantilla.VersionID,
+		"plantilla_version":    plantilla.VersionActual,
+		"contenido":            contenido,
+	})
+}
+
+func (h *ConsentimientoHandler) Verificar(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de consentimiento inválido"))
+		return
+	}
+
+	v, err := h.service.Verificar(r.Context(), id)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, v)
+}
+
+func (h *ConsentimientoHandler) Revocar(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de consentimiento inválido"))
+		return
+	}
+
+	var req dto.RevocarConsentimientoRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	cons, err := h.service.Revocar(r.Context(), id, req.Alcance, req.Motivo, req.Fecha, userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, cons)
+}
+
+// GetEstado devuelve la vigencia de los consentimientos del paciente por
+// tratamiento. ?tratamiento= limita la respuesta a uno.
+func (h *ConsentimientoHandler) GetEstado(w http.ResponseWriter, r *http.Request) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+
+	estados, err := h.service.GetEstado(r.Context(), pacienteID, r.URL.Query().Get("tratamiento"))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, estados)
+}
+
+// Plantillas de consentimiento
+
+func (h *ConsentimientoHandler) GetPlantillas(w http.ResponseWriter, r *http.Request) {
+	soloActivas := r.URL.Query().Get("todas") != "true"
+
+	plantillas, err := h.service.GetPlantillas(r.Context(), soloActivas)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, plantillas)
+}
+
+func (h *ConsentimientoHandler) GetPlantilla(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID inválido"))
+		return
+	}
+
+	p, err := h.service.GetPlantilla(r.Context(), id)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, p)
+}
+
+func (h *ConsentimientoHandler) GetVersionesPlantilla(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID inválido"))
+		return
+	}
+
+	versiones, err := h.service.GetVersionesPlantilla(r.Context(), id)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, versiones)
+}
+
+func (h *ConsentimientoHandler) CreatePlantilla(w http.ResponseWriter, r *http.Request) {
+	var req dto.PlantillaConsentimientoRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	p, err := h.service.CreatePlantilla(r.Context(), req.Nombre, req.TipoTratamiento, req.Texto, req.VigenciaDias, req.Requerido, userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, p)
+}
+
+func (h *ConsentimientoHandler) UpdatePlantilla(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID inválido"))
+		return
+	}
+
+	var req dto.PlantillaConsentimientoRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	activa := true
+	if req.Activa != nil {
+		activa = *req.Activa
+	}
+
+	p, err := h.service.UpdatePlantilla(r.Context(), id, req.Nombre, req.TipoTratamiento, req.Texto, req.VigenciaDias, req.Requerido, activa, userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, p)
+}
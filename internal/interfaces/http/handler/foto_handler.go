@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/application/foto"
+	"github.com/tunek/centro-caribel/internal/domain"
+	"github.com/tunek/centro-caribel/internal/interfaces/http/middleware"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+	"github.com/tunek/centro-caribel/pkg/response"
+)
+
+// maxFotoSize limita el tamaño de cada foto subida.
+const maxFotoSize = 10 << 20
+
+type FotoHandler struct {
+	service *foto.Service
+}
+
+func NewFotoHandler(service *foto.Service) *FotoHandler {
+	return &FotoHandler{service: service}
+}
+
+func (h *FotoHandler) GetByPaciente(w http.ResponseWriter, r *http.Request) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+
+	paqueteID, err := parsePaqueteFiltro(r)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	fotos, err := h.service.GetByPaciente(r.Context(), pacienteID, r.URL.Query().Get("zona"), paqueteID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, fotos)
+}
+
+func (h *FotoHandler) Subir(w http.ResponseWriter, r *http.Request) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFotoSize+1<<20)
+	if err := r.ParseMultipartForm(maxFotoSize); err != nil {
+		response.Error(w, apperrors.NewBadRequest("La foto excede el tamaño máximo o el formulario es inválido"))
+		return
+	}
+
+	file, header, err := r.FormFile("archivo")
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("El campo 'archivo' es requerido"))
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxFotoSize {
+		response.Error(w, apperrors.NewBadRequest("La foto excede el tamaño máximo de 10 MB"))
+		return
+	}
+
+	datos := foto.DatosFoto{
+		Zona:        r.FormValue("zona"),
+		Momento:     domain.MomentoFoto(r.FormValue("momento")),
+		Descripcion: r.FormValue("descripcion"),
+		TomadaEn:    r.FormValue("tomada_en"),
+	}
+	if datos.CitaID, err = parseFormUUID(r, "cita_id"); err != nil {
+		response.Error(w, err)
+		return
+	}
+	if datos.PaqueteID, err = parseFormUUID(r, "paquete_id"); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	f, err := h.service.Subir(r.Context(), pacienteID, file, header.Size, header.Filename, datos, userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, f)
+}
+
+func (h *FotoHandler) Descargar(w http.ResponseWriter, r *http.Request) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+	fotoID, err := uuid.Parse(r.PathValue("fotoId"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de foto inválido"))
+		return
+	}
+
+	f, contenido, err := h.service.Abrir(r.Context(), pacienteID, fotoID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	defer contenido.Close()
+
+	w.Header().Set("Content-Type", f.ContentType)
+	w.Header().Set("Cache-Control", "private, no-store")
+	http.ServeContent(w, r, f.NombreArchivo, f.CreatedAt, contenido)
+}
+
+func (h *FotoHandler) Comparaciones(w http.ResponseWriter, r *http.Request) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+
+	paqueteID, err := parsePaqueteFiltro(r)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	comparaciones, err := h.service.Comparaciones(r.Context(), pacienteID, paqueteID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, comparaciones)
+}
+
+// parseFormUUID lee un UUID opcional de un campo de formulario.
+func parseFormUUID(r *http.Request, campo string) (*uuid.UUID, error) {
+	v := r.FormValue(campo)
+	if v == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return nil, apperrors.NewBadRequest(campo + " inválido")
+	}
+	return &id, nil
+}
@@ -1,10 +1,13 @@
 package handler
 
 import (
+	"context"
 	"net/http"
+	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/tunek/centro-caribel/internal/application/historia"
+	"github.com/tunek/centro-caribel/internal/domain"
 	"github.com/tunek/centro-caribel/internal/interfaces/http/dto"
 	"github.com/tunek/centro-caribel/internal/interfaces/http/middleware"
 	apperrors "github.com/tunek/centro-caribel/pkg/errors"
@@ -49,9 +52,15 @@ func (h *HistoriaHandler) UpdateAntecedentes(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
 	hist, err := h.service.UpdateAntecedentes(r.Context(), pacienteID,
 		req.AntecedentesPersonales, req.AntecedentesFamiliares,
-		req.Alergias, req.MedicamentosActuales)
+		req.Alergias, req.MedicamentosActuales, userID)
 	if err != nil {
 		response.Error(w, err)
 		return
@@ -60,6 +69,93 @@ func (h *HistoriaHandler) UpdateAntecedentes(w http.ResponseWriter, r *http.Requ
 	response.JSON(w, http.StatusOK, hist)
 }
 
+func (h *HistoriaHandler) Cerrar(w http.ResponseWriter, r *http.Request) {
+	h.cambiarEstado(w, r, h.service.Cerrar)
+}
+
+func (h *HistoriaHandler) Reabrir(w http.ResponseWriter, r *http.Request) {
+	h.cambiarEstado(w, r, h.service.Reabrir)
+}
+
+func (h *HistoriaHandler) Archivar(w http.ResponseWriter, r *http.Request) {
+	h.cambiarEstado(w, r, h.service.Archivar)
+}
+
+func (h *HistoriaHandler) cambiarEstado(w http.ResponseWriter, r *http.Request,
+	cambiar func(ctx context.Context, pacienteID uuid.UUID, motivo string, userID uuid.UUID) (*domain.HistoriaClinica, error)) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+
+	var req dto.CambiarEstadoHistoriaRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	hist, err := cambiar(r.Context(), pacienteID, req.Motivo, userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, hist)
+}
+
+func (h *HistoriaHandler) GetVersiones(w http.ResponseWriter, r *http.Request) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+
+	versiones, err := h.service.GetVersiones(r.Context(), pacienteID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, versiones)
+}
+
+func (h *HistoriaHandler) DiffVersiones(w http.ResponseWriter, r *http.Request) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+
+	var desde, hasta int
+	if v := r.URL.Query().Get("desde"); v != "" {
+		if desde, err = strconv.Atoi(v); err != nil || desde < 1 {
+			response.Error(w, apperrors.NewBadRequest("Parámetro desde inválido"))
+			return
+		}
+	}
+	if v := r.URL.Query().Get("hasta"); v != "" {
+		if hasta, err = strconv.Atoi(v); err != nil || hasta < 1 {
+			response.Error(w, apperrors.NewBadRequest("Parámetro hasta inválido"))
+			return
+		}
+	}
+
+	diff, err := h.service.Diff(r.Context(), pacienteID, desde, hasta)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, diff)
+}
+
 func (h *HistoriaHandler) GetNotas(w http.ResponseWriter, r *http.Request) {
 	pacienteID, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
@@ -67,7 +163,13 @@ func (h *HistoriaHandler) GetNotas(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	notas, err := h.service.GetNotas(r.Context(), pacienteID)
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	notas, err := h.service.GetNotas(r.Context(), pacienteID, userID)
 	if err != nil {
 		response.Error(w, err)
 		return
@@ -95,7 +197,8 @@ func (h *HistoriaHandler) CreateNota(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	nota, err := h.service.CreateNota(r.Context(), pacienteID, req.Tipo, req.Contenido, userID)
+	nota, err := h.service.CreateNota(r.Context(), pacienteID, req.Tipo, req.Contenido,
+		req.CitaID, req.PaqueteID, req.PlantillaID, req.Datos, !req.Borrador, userID)
 	if err != nil {
 		response.Error(w, err)
 		return
@@ -103,3 +206,126 @@ func (h *HistoriaHandler) CreateNota(w http.ResponseWriter, r *http.Request) {
 
 	response.JSON(w, http.StatusCreated, nota)
 }
+
+// parseNotaPath obtiene el ID del paciente y el de la nota.
+func parseNotaPath(r *http.Request) (uuid.UUID, uuid.UUID, error) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, apperrors.NewBadRequest("ID de paciente inválido")
+	}
+	notaID, err := uuid.Parse(r.PathValue("notaId"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, apperrors.NewBadRequest("ID de nota inválido")
+	}
+	return pacienteID, notaID, nil
+}
+
+func (h *HistoriaHandler) UpdateNota(w http.ResponseWriter, r *http.Request) {
+	pacienteID, notaID, err := parseNotaPath(r)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	var req dto.UpdateNotaRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	nota, err := h.service.UpdateNota(r.Context(), pacienteID, notaID, req.Tipo, req.Contenido, req.Datos, userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, nota)
+}
+
+func (h *HistoriaHandler) FirmarNota(w http.ResponseWriter, r *http.Request) {
+	pacienteID, notaID, err := parseNotaPath(r)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	nota, err := h.service.FirmarNota(r.Context(), pacienteID, notaID, userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, nota)
+}
+
+// DescartarNota elimina un borrador; ver historia.Service.DescartarNota.
+func (h *HistoriaHandler) DescartarNota(w http.ResponseWriter, r *http.Request) {
+	pacienteID, notaID, err := parseNotaPath(r)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	// El cuerpo es opcional: solo hace falta el motivo para descartar el
+	// borrador de otro usuario.
+	var req dto.DescartarNotaRequest
+	if r.ContentLength != 0 {
+		if err := validator.DecodeAndValidate(r, &req); err != nil {
+			response.Error(w, err)
+			return
+		}
+	}
+
+	if err := h.service.DescartarNota(r.Context(), pacienteID, notaID, userID, middleware.GetRolNombre(r.Context()), req.Motivo); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "Borrador descartado"})
+}
+
+func (h *HistoriaHandler) CreateAdenda(w http.ResponseWriter, r *http.Request) {
+	pacienteID, notaID, err := parseNotaPath(r)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	var req dto.CreateAdendaRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	adenda, err := h.service.CreateAdenda(r.Context(), pacienteID, notaID, req.Contenido, userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, adenda)
+}
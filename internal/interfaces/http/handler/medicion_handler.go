@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/application/historia"
+	"github.com/tunek/centro-caribel/internal/domain"
+	"github.com/tunek/centro-caribel/internal/interfaces/http/dto"
+	"github.com/tunek/centro-caribel/internal/interfaces/http/middleware"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+	"github.com/tunek/centro-caribel/pkg/response"
+	"github.com/tunek/centro-caribel/pkg/validator"
+)
+
+// parsePaqueteFiltro lee el filtro opcional ?paquete_id=.
+func parsePaqueteFiltro(r *http.Request) (*uuid.UUID, error) {
+	v := r.URL.Query().Get("paquete_id")
+	if v == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return nil, apperrors.NewBadRequest("paquete_id inválido")
+	}
+	return &id, nil
+}
+
+func (h *HistoriaHandler) GetMediciones(w http.ResponseWriter, r *http.Request) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+
+	paqueteID, err := parsePaqueteFiltro(r)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	mediciones, err := h.service.GetMediciones(r.Context(), pacienteID, paqueteID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, mediciones)
+}
+
+func (h *HistoriaHandler) CreateMedicion(w http.ResponseWriter, r *http.Request) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+
+	var req dto.CreateMedicionRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	m, err := h.service.CreateMedicion(r.Context(), pacienteID, historia.MedicionInput{
+		Fecha:              req.Fecha,
+		CitaID:             req.CitaID,
+		PaqueteID:          req.PaqueteID,
+		PesoKg:             req.PesoKg,
+		TallaCm:            req.TallaCm,
+		CinturaCm:          req.CinturaCm,
+		CaderaCm:           req.CaderaCm,
+		AbdomenCm:          req.AbdomenCm,
+		PresionSistolica:   req.PresionSistolica,
+		PresionDiastolica:  req.PresionDiastolica,
+		FrecuenciaCardiaca: req.FrecuenciaCardiaca,
+		EscalaDolor:        req.EscalaDolor,
+		Observaciones:      req.Observaciones,
+	}, userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, m)
+}
+
+func (h *HistoriaHandler) GetSerieMedicion(w http.ResponseWriter, r *http.Request) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+
+	paqueteID, err := parsePaqueteFiltro(r)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	serie, err := h.service.GetSerie(r.Context(), pacienteID, domain.MetricaMedicion(r.PathValue("metrica")), paqueteID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, serie)
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"encoding/json"
 	"net/http"
 	"strconv"
 
@@ -10,9 +11,13 @@ import (
 	"github.com/tunek/centro-caribel/internal/interfaces/http/middleware"
 	apperrors "github.com/tunek/centro-caribel/pkg/errors"
 	"github.com/tunek/centro-caribel/pkg/response"
+	"github.com/tunek/centro-caribel/pkg/spreadsheet"
 	"github.com/tunek/centro-caribel/pkg/validator"
 )
 
+// maxImportSize limita el tamaño de las planillas de importación.
+const maxImportSize = 10 << 20
+
 type PacienteHandler struct {
 	service *paciente.Service
 }
@@ -48,7 +53,8 @@ func (h *PacienteHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
 
 	query := r.URL.Query().Get("q")
-	pacientes, total, err := h.service.GetAll(r.Context(), page, perPage, query)
+	incluirArchivados := r.URL.Query().Get("archivados") == "true"
+	pacientes, total, err := h.service.GetAll(r.Context(), page, perPage, query, incluirArchivados)
 	if err != nil {
 		response.Error(w, err)
 		return
@@ -88,3 +94,143 @@ func (h *PacienteHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 
 	response.JSON(w, http.StatusOK, pac)
 }
+
+func (h *PacienteHandler) Archivar(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID inválido"))
+		return
+	}
+
+	var req dto.ArchivarPacienteRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	pac, err := h.service.Archivar(r.Context(), id, req.Motivo, userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, pac)
+}
+
+func (h *PacienteHandler) Restaurar(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID inválido"))
+		return
+	}
+
+	pac, err := h.service.Restaurar(r.Context(), id)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, pac)
+}
+
+func (h *PacienteHandler) GetTutores(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID inválido"))
+		return
+	}
+
+	tutores, err := h.service.GetTutores(r.Context(), id)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, tutores)
+}
+
+func (h *PacienteHandler) CreateTutor(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID inválido"))
+		return
+	}
+
+	var req dto.CreateTutorRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	t, err := h.service.CreateTutor(r.Context(), id, req.NombreCompleto, req.CI, req.Parentesco, req.Celular, userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, t)
+}
+
+// Importar recibe un multipart/form-data con el campo "archivo" (.csv o .xlsx),
+// "mapeo" opcional en JSON ({"nombre_completo": "Nombre", ...}) y "dry_run".
+func (h *PacienteHandler) Importar(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		response.Error(w, apperrors.NewBadRequest("El archivo excede el tamaño máximo o el formulario es inválido"))
+		return
+	}
+
+	file, header, err := r.FormFile("archivo")
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("El campo 'archivo' es requerido"))
+		return
+	}
+	defer file.Close()
+
+	var mapeo map[string]string
+	if m := r.FormValue("mapeo"); m != "" {
+		if err := json.Unmarshal([]byte(m), &mapeo); err != nil {
+			response.Error(w, apperrors.NewBadRequest("El mapeo de columnas no es un JSON válido"))
+			return
+		}
+	}
+	dryRun := r.FormValue("dry_run") == "true"
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	registros, err := spreadsheet.Read(header.Filename, file)
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest(err.Error()))
+		return
+	}
+
+	filas, err := paciente.MapearFilas(registros, mapeo)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	res, err := h.service.Importar(r.Context(), filas, dryRun, userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/application/plantilla"
+	"github.com/tunek/centro-caribel/internal/interfaces/http/dto"
+	"github.com/tunek/centro-caribel/internal/interfaces/http/middleware"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+	"github.com/tunek/centro-caribel/pkg/response"
+	"github.com/tunek/centro-caribel/pkg/validator"
+)
+
+type PlantillaHandler struct {
+	service *plantilla.Service
+}
+
+func NewPlantillaHandler(service *plantilla.Service) *PlantillaHandler {
+	return &PlantillaHandler{service: service}
+}
+
+func (h *PlantillaHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	soloActivas := r.URL.Query().Get("todas") != "true"
+
+	plantillas, err := h.service.GetAll(r.Context(), soloActivas)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, plantillas)
+}
+
+func (h *PlantillaHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID inválido"))
+		return
+	}
+
+	p, err := h.service.GetByID(r.Context(), id)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, p)
+}
+
+func (h *PlantillaHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req dto.PlantillaNotaRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	p, err := h.service.Create(r.Context(), req.Nombre, req.Descripcion, req.TipoNota, req.Campos, userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, p)
+}
+
+func (h *PlantillaHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID inválido"))
+		return
+	}
+
+	var req dto.PlantillaNotaRequest
+	if err := validator.DecodeAndValidate(r, &req); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	activa := true
+	if req.Activa != nil {
+		activa = *req.Activa
+	}
+
+	p, err := h.service.Update(r.Context(), id, req.Nombre, req.Descripcion, req.TipoNota, req.Campos, activa)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, p)
+}
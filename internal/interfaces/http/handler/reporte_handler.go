@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/application/reporte"
+	"github.com/tunek/centro-caribel/internal/interfaces/http/middleware"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+	"github.com/tunek/centro-caribel/pkg/response"
+)
+
+type ReporteHandler struct {
+	service *reporte.Service
+}
+
+func NewReporteHandler(service *reporte.Service) *ReporteHandler {
+	return &ReporteHandler{service: service}
+}
+
+func (h *ReporteHandler) HistoriaPDF(w http.ResponseWriter, r *http.Request) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+
+	userID, err := uuid.Parse(middleware.GetUserID(r.Context()))
+	if err != nil {
+		response.Error(w, apperrors.NewUnauthorized("Usuario no identificado"))
+		return
+	}
+
+	doc, nombre, err := h.service.HistoriaPDF(r.Context(), pacienteID, userID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	writePDF(w, nombre, doc)
+}
+
+func (h *ReporteHandler) ConsentimientoPDF(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de consentimiento inválido"))
+		return
+	}
+
+	doc, nombre, err := h.service.ConsentimientoPDF(r.Context(), id)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	writePDF(w, nombre, doc)
+}
+
+func writePDF(w http.ResponseWriter, nombre string, doc []byte) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": nombre}))
+	w.Header().Set("Content-Length", strconv.Itoa(len(doc)))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(doc)
+}
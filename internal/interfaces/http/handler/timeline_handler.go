@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/tunek/centro-caribel/internal/application/timeline"
+	"github.com/tunek/centro-caribel/internal/domain"
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+	"github.com/tunek/centro-caribel/pkg/response"
+)
+
+type TimelineHandler struct {
+	service *timeline.Service
+}
+
+func NewTimelineHandler(service *timeline.Service) *TimelineHandler {
+	return &TimelineHandler{service: service}
+}
+
+func (h *TimelineHandler) GetByPaciente(w http.ResponseWriter, r *http.Request) {
+	pacienteID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID de paciente inválido"))
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+
+	// tipos=CITA,NOTA filtra por fuente; sin filtro se devuelven todas
+	var tipos []domain.TipoEventoTimeline
+	if tiposStr := r.URL.Query().Get("tipos"); tiposStr != "" {
+		for _, t := range strings.Split(tiposStr, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tipos = append(tipos, domain.TipoEventoTimeline(strings.ToUpper(t)))
+			}
+		}
+	}
+
+	eventos, total, err := h.service.GetByPacienteID(r.Context(), pacienteID, tipos, page, perPage)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+	totalPages := int(total) / perPage
+	if int(total)%perPage > 0 {
+		totalPages++
+	}
+
+	response.JSONWithMeta(w, http.StatusOK, eventos, &response.Meta{
+		Page:      page,
+		PerPage:   perPage,
+		Total:     total,
+		TotalPage: totalPages,
+	})
+}
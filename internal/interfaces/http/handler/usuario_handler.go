@@ -117,3 +117,20 @@ func (h *UsuarioHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	response.JSON(w, http.StatusOK, map[string]string{"message": "Usuario eliminado"})
 }
+
+// Desbloquear quita el bloqueo por intentos de login fallidos.
+func (h *UsuarioHandler) Desbloquear(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, apperrors.NewBadRequest("ID inválido"))
+		return
+	}
+
+	user, err := h.service.Desbloquear(r.Context(), id)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, user)
+}
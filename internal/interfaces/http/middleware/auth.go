@@ -17,7 +17,27 @@ const (
 	RolNombreKey contextKey = "rol_nombre"
 )
 
-func AuthMiddleware(jwtSvc auth.JWTService) func(http.Handler) http.Handler {
+// VerificadorSesion confirma que un access token válido no fue revocado; lo
+// implementa auth.Sesiones.
+type VerificadorSesion interface {
+	ValidarSesion(ctx context.Context, userID string, version int) error
+}
+
+// AuthMiddleware exige un access token válido y vigente. Rechaza los tokens
+// de usuarios con acciones pendientes: cambiar la contraseña o activar la
+// autenticación en dos pasos.
+func AuthMiddleware(jwtSvc auth.JWTService, sesiones VerificadorSesion) func(http.Handler) http.Handler {
+	return autenticar(jwtSvc, sesiones, false)
+}
+
+// AuthPendienteMiddleware es AuthMiddleware pero acepta también los tokens
+// con acciones pendientes; es para las rutas que ese usuario necesita para
+// completarlas y para cerrar sesiones.
+func AuthPendienteMiddleware(jwtSvc auth.JWTService, sesiones VerificadorSesion) func(http.Handler) http.Handler {
+	return autenticar(jwtSvc, sesiones, true)
+}
+
+func autenticar(jwtSvc auth.JWTService, sesiones VerificadorSesion, permitirPendientes bool) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			header := r.Header.Get("Authorization")
@@ -38,6 +58,22 @@ func AuthMiddleware(jwtSvc auth.JWTService) func(http.Handler) http.Handler {
 				return
 			}
 
+			// El token puede ser válido y estar revocado: usuario desactivado,
+			// rol cambiado o cierre de todas las sesiones.
+			if err := sesiones.ValidarSesion(r.Context(), claims.UserID, claims.TokenVersion); err != nil {
+				response.Error(w, apperrors.NewUnauthorized("La sesión ya no es válida; inicie sesión nuevamente"))
+				return
+			}
+
+			if claims.Restringido() && !permitirPendientes {
+				if claims.CambioPassword {
+					response.Error(w, apperrors.NewForbidden("Debe cambiar su contraseña antes de continuar"))
+				} else {
+					response.Error(w, apperrors.NewForbidden("Debe activar la autenticación en dos pasos antes de continuar"))
+				}
+				return
+			}
+
 			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
 			ctx = context.WithValue(ctx, RolNombreKey, claims.RolNombre)
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -17,9 +17,14 @@ type Handlers struct {
 	Historia       *handler.HistoriaHandler
 	Rol            *handler.RolHandler
 	Paquete        *handler.PaqueteHandler
+	Timeline       *handler.TimelineHandler
+	Plantilla      *handler.PlantillaHandler
+	Foto           *handler.FotoHandler
+	Adjunto        *handler.AdjuntoHandler
+	Reporte        *handler.ReporteHandler
 }
 
-func New(h Handlers, jwtSvc auth.JWTService) http.Handler {
+func New(h Handlers, jwtSvc auth.JWTService, sesiones middleware.VerificadorSesion) http.Handler {
 	mux := http.NewServeMux()
 
 	// Health check
@@ -31,13 +36,28 @@ func New(h Handlers, jwtSvc auth.JWTService) http.Handler {
 	// Auth (público)
 	mux.HandleFunc("POST /auth/login", h.Auth.Login)
 	mux.HandleFunc("POST /auth/refresh", h.Auth.Refresh)
+	mux.HandleFunc("POST /auth/logout", h.Auth.Logout)
+	mux.HandleFunc("POST /auth/restablecer-password", h.Auth.RestablecerPassword)
+	mux.HandleFunc("POST /auth/2fa/verificar", h.Auth.Verificar2FA)
+	mux.HandleFunc("GET /.well-known/jwks.json", h.Auth.JWKS)
 
 	// Rutas protegidas
-	authMw := middleware.AuthMiddleware(jwtSvc)
+	authMw := middleware.AuthMiddleware(jwtSvc, sesiones)
+	authPendienteMw := middleware.AuthPendienteMiddleware(jwtSvc, sesiones)
 	adminOnly := middleware.RequireRoles("Administradora")
 	staffRoles := middleware.RequireRoles("Administradora", "Licenciada")
 	allRoles := middleware.RequireRoles("Administradora", "Licenciada", "Interno", "Medico")
 
+	// Sesiones (autenticado)
+	mux.Handle("POST /auth/logout-all", authPendienteMw(allRoles(http.HandlerFunc(h.Auth.LogoutAll))))
+	mux.Handle("POST /auth/cambiar-password", authPendienteMw(allRoles(http.HandlerFunc(h.Auth.CambiarPassword))))
+	mux.Handle("GET /auth/2fa", authPendienteMw(allRoles(http.HandlerFunc(h.Auth.GetEstado2FA))))
+	mux.Handle("POST /auth/2fa/inscribir", authPendienteMw(allRoles(http.HandlerFunc(h.Auth.Inscribir2FA))))
+	mux.Handle("POST /auth/2fa/activar", authPendienteMw(allRoles(http.HandlerFunc(h.Auth.Activar2FA))))
+	mux.Handle("POST /auth/2fa/codigos-recuperacion", authMw(allRoles(http.HandlerFunc(h.Auth.RegenerarCodigos2FA))))
+	mux.Handle("POST /auth/2fa/desactivar", authMw(allRoles(http.HandlerFunc(h.Auth.Desactivar2FA))))
+	mux.Handle("GET /auth/intentos", authMw(adminOnly(http.HandlerFunc(h.Auth.GetIntentos))))
+
 	// Roles (autenticado)
 	mux.Handle("GET /roles", authMw(allRoles(http.HandlerFunc(h.Rol.GetAll))))
 
@@ -47,22 +67,89 @@ func New(h Handlers, jwtSvc auth.JWTService) http.Handler {
 	mux.Handle("GET /usuarios/{id}", authMw(adminOnly(http.HandlerFunc(h.Usuario.GetByID))))
 	mux.Handle("PUT /usuarios/{id}", authMw(adminOnly(http.HandlerFunc(h.Usuario.Update))))
 	mux.Handle("DELETE /usuarios/{id}", authMw(adminOnly(http.HandlerFunc(h.Usuario.Delete))))
+	mux.Handle("POST /usuarios/{id}/desbloquear", authMw(adminOnly(http.HandlerFunc(h.Usuario.Desbloquear))))
+	mux.Handle("POST /usuarios/{id}/reset-password", authMw(adminOnly(http.HandlerFunc(h.Auth.SolicitarResetPassword))))
+	mux.Handle("POST /usuarios/{id}/2fa/reset", authMw(adminOnly(http.HandlerFunc(h.Auth.Reset2FA))))
 
 	// Pacientes
 	mux.Handle("GET /pacientes", authMw(allRoles(http.HandlerFunc(h.Paciente.GetAll))))
 	mux.Handle("POST /pacientes", authMw(staffRoles(http.HandlerFunc(h.Paciente.Create))))
+	mux.Handle("POST /pacientes/importar", authMw(adminOnly(http.HandlerFunc(h.Paciente.Importar))))
 	mux.Handle("GET /pacientes/{id}", authMw(allRoles(http.HandlerFunc(h.Paciente.GetByID))))
+	mux.Handle("POST /pacientes/{id}/archivar", authMw(adminOnly(http.HandlerFunc(h.Paciente.Archivar))))
+	mux.Handle("POST /pacientes/{id}/restaurar", authMw(adminOnly(http.HandlerFunc(h.Paciente.Restaurar))))
+	mux.Handle("GET /pacientes/{id}/tutores", authMw(allRoles(http.HandlerFunc(h.Paciente.GetTutores))))
+	mux.Handle("POST /pacientes/{id}/tutores", authMw(staffRoles(http.HandlerFunc(h.Paciente.CreateTutor))))
 
 	// Consentimientos
 	mux.Handle("GET /pacientes/{id}/consentimientos", authMw(allRoles(http.HandlerFunc(h.Consentimiento.GetByPaciente))))
 	mux.Handle("POST /pacientes/{id}/consentimientos", authMw(staffRoles(http.HandlerFunc(h.Consentimiento.Create))))
+	mux.Handle("POST /pacientes/{id}/consentimientos/previsualizar", authMw(staffRoles(http.HandlerFunc(h.Consentimiento.Previsualizar))))
+	mux.Handle("GET /pacientes/{id}/consentimientos/estado", authMw(allRoles(http.HandlerFunc(h.Consentimiento.GetEstado))))
+	mux.Handle("GET /consentimientos/{id}/verificar", authMw(allRoles(http.HandlerFunc(h.Consentimiento.Verificar))))
+	mux.Handle("GET /consentimientos/{id}/pdf", authMw(allRoles(http.HandlerFunc(h.Reporte.ConsentimientoPDF))))
+	mux.Handle("POST /consentimientos/{id}/revocar", authMw(staffRoles(http.HandlerFunc(h.Consentimiento.Revocar))))
+
+	// Plantillas de consentimiento
+	mux.Handle("GET /plantillas-consentimiento", authMw(allRoles(http.HandlerFunc(h.Consentimiento.GetPlantillas))))
+	mux.Handle("GET /plantillas-consentimiento/{id}", authMw(allRoles(http.HandlerFunc(h.Consentimiento.GetPlantilla))))
+	mux.Handle("GET /plantillas-consentimiento/{id}/versiones", authMw(allRoles(http.HandlerFunc(h.Consentimiento.GetVersionesPlantilla))))
+	mux.Handle("POST /plantillas-consentimiento", authMw(adminOnly(http.HandlerFunc(h.Consentimiento.CreatePlantilla))))
+	mux.Handle("PUT /plantillas-consentimiento/{id}", authMw(adminOnly(http.HandlerFunc(h.Consentimiento.UpdatePlantilla))))
 
 	// Historia clínica
 	mux.Handle("GET /pacientes/{id}/historia", authMw(allRoles(http.HandlerFunc(h.Historia.GetByPaciente))))
 	mux.Handle("PUT /pacientes/{id}/historia/antecedentes", authMw(staffRoles(http.HandlerFunc(h.Historia.UpdateAntecedentes))))
+	mux.Handle("POST /pacientes/{id}/historia/cerrar", authMw(adminOnly(http.HandlerFunc(h.Historia.Cerrar))))
+	mux.Handle("POST /pacientes/{id}/historia/reabrir", authMw(adminOnly(http.HandlerFunc(h.Historia.Reabrir))))
+	mux.Handle("POST /pacientes/{id}/historia/archivar", authMw(adminOnly(http.HandlerFunc(h.Historia.Archivar))))
+	mux.Handle("GET /pacientes/{id}/historia/versiones", authMw(allRoles(http.HandlerFunc(h.Historia.GetVersiones))))
+	mux.Handle("GET /pacientes/{id}/historia/versiones/diff", authMw(allRoles(http.HandlerFunc(h.Historia.DiffVersiones))))
 	mux.Handle("GET /pacientes/{id}/historia/notas", authMw(allRoles(http.HandlerFunc(h.Historia.GetNotas))))
 	clinicalRoles := middleware.RequireRoles("Administradora", "Licenciada", "Medico")
 	mux.Handle("POST /pacientes/{id}/historia/notas", authMw(clinicalRoles(http.HandlerFunc(h.Historia.CreateNota))))
+	mux.Handle("PUT /pacientes/{id}/historia/notas/{notaId}", authMw(clinicalRoles(http.HandlerFunc(h.Historia.UpdateNota))))
+	mux.Handle("DELETE /pacientes/{id}/historia/notas/{notaId}", authMw(clinicalRoles(http.HandlerFunc(h.Historia.DescartarNota))))
+	mux.Handle("POST /pacientes/{id}/historia/notas/{notaId}/firmar", authMw(clinicalRoles(http.HandlerFunc(h.Historia.FirmarNota))))
+	mux.Handle("POST /pacientes/{id}/historia/notas/{notaId}/adendas", authMw(clinicalRoles(http.HandlerFunc(h.Historia.CreateAdenda))))
+	mux.Handle("GET /pacientes/{id}/historia/export.pdf", authMw(clinicalRoles(http.HandlerFunc(h.Reporte.HistoriaPDF))))
+
+	// Fotos clínicas
+	mux.Handle("GET /pacientes/{id}/fotos", authMw(allRoles(http.HandlerFunc(h.Foto.GetByPaciente))))
+	mux.Handle("POST /pacientes/{id}/fotos", authMw(clinicalRoles(http.HandlerFunc(h.Foto.Subir))))
+	mux.Handle("GET /pacientes/{id}/fotos/comparaciones", authMw(allRoles(http.HandlerFunc(h.Foto.Comparaciones))))
+	mux.Handle("GET /pacientes/{id}/fotos/{fotoId}/archivo", authMw(allRoles(http.HandlerFunc(h.Foto.Descargar))))
+
+	// Adjuntos (la visibilidad se filtra según el rol)
+	mux.Handle("GET /pacientes/{id}/adjuntos", authMw(allRoles(http.HandlerFunc(h.Adjunto.GetByPaciente))))
+	mux.Handle("POST /pacientes/{id}/adjuntos", authMw(clinicalRoles(http.HandlerFunc(h.Adjunto.Subir))))
+	mux.Handle("GET /pacientes/{id}/adjuntos/{adjuntoId}/archivo", authMw(allRoles(http.HandlerFunc(h.Adjunto.Descargar))))
+	mux.Handle("DELETE /pacientes/{id}/adjuntos/{adjuntoId}", authMw(staffRoles(http.HandlerFunc(h.Adjunto.Delete))))
+
+	// Mediciones
+	mux.Handle("GET /pacientes/{id}/historia/mediciones", authMw(allRoles(http.HandlerFunc(h.Historia.GetMediciones))))
+	mux.Handle("POST /pacientes/{id}/historia/mediciones", authMw(clinicalRoles(http.HandlerFunc(h.Historia.CreateMedicion))))
+	mux.Handle("GET /pacientes/{id}/historia/mediciones/series/{metrica}", authMw(allRoles(http.HandlerFunc(h.Historia.GetSerieMedicion))))
+
+	// Antecedentes estructurados
+	mux.Handle("GET /pacientes/{id}/historia/alergias", authMw(allRoles(http.HandlerFunc(h.Historia.GetAlergias))))
+	mux.Handle("POST /pacientes/{id}/historia/alergias", authMw(staffRoles(http.HandlerFunc(h.Historia.CreateAlergia))))
+	mux.Handle("PUT /pacientes/{id}/historia/alergias/{itemId}", authMw(staffRoles(http.HandlerFunc(h.Historia.UpdateAlergia))))
+	mux.Handle("DELETE /pacientes/{id}/historia/alergias/{itemId}", authMw(staffRoles(http.HandlerFunc(h.Historia.DeleteAlergia))))
+	mux.Handle("GET /pacientes/{id}/historia/medicamentos", authMw(allRoles(http.HandlerFunc(h.Historia.GetMedicamentos))))
+	mux.Handle("POST /pacientes/{id}/historia/medicamentos", authMw(staffRoles(http.HandlerFunc(h.Historia.CreateMedicamento))))
+	mux.Handle("PUT /pacientes/{id}/historia/medicamentos/{itemId}", authMw(staffRoles(http.HandlerFunc(h.Historia.UpdateMedicamento))))
+	mux.Handle("DELETE /pacientes/{id}/historia/medicamentos/{itemId}", authMw(staffRoles(http.HandlerFunc(h.Historia.DeleteMedicamento))))
+	mux.Handle("GET /pacientes/{id}/historia/condiciones", authMw(allRoles(http.HandlerFunc(h.Historia.GetCondiciones))))
+	mux.Handle("POST /pacientes/{id}/historia/condiciones", authMw(staffRoles(http.HandlerFunc(h.Historia.CreateCondicion))))
+	mux.Handle("PUT /pacientes/{id}/historia/condiciones/{itemId}", authMw(staffRoles(http.HandlerFunc(h.Historia.UpdateCondicion))))
+	mux.Handle("DELETE /pacientes/{id}/historia/condiciones/{itemId}", authMw(staffRoles(http.HandlerFunc(h.Historia.DeleteCondicion))))
+
+	// Plantillas de notas
+	mux.Handle("GET /plantillas-nota", authMw(allRoles(http.HandlerFunc(h.Plantilla.GetAll))))
+	mux.Handle("GET /plantillas-nota/{id}", authMw(allRoles(http.HandlerFunc(h.Plantilla.GetByID))))
+	mux.Handle("POST /plantillas-nota", authMw(adminOnly(http.HandlerFunc(h.Plantilla.Create))))
+	mux.Handle("PUT /plantillas-nota/{id}", authMw(adminOnly(http.HandlerFunc(h.Plantilla.Update))))
 
 	// Citas
 	mux.Handle("GET /citas", authMw(allRoles(http.HandlerFunc(h.Cita.GetAll))))
@@ -73,6 +160,9 @@ func New(h Handlers, jwtSvc auth.JWTService) http.Handler {
 	mux.Handle("POST /paquetes", authMw(staffRoles(http.HandlerFunc(h.Paquete.Create))))
 	mux.Handle("GET /pacientes/{id}/paquetes", authMw(allRoles(http.HandlerFunc(h.Paquete.GetByPaciente))))
 
+	// Línea de tiempo del paciente
+	mux.Handle("GET /pacientes/{id}/timeline", authMw(allRoles(http.HandlerFunc(h.Timeline.GetByPaciente))))
+
 	// Aplicar middlewares globales
 	var handler http.Handler = mux
 	handler = middleware.CORS(handler)
@@ -0,0 +1,8 @@
+-- Archivado lógico de pacientes (no se eliminan por las FK de citas y consentimientos)
+ALTER TABLE pacientes
+    ADD COLUMN estado VARCHAR(20) NOT NULL DEFAULT 'ACTIVO' CHECK (estado IN ('ACTIVO', 'ARCHIVADO')),
+    ADD COLUMN motivo_archivo TEXT,
+    ADD COLUMN fecha_archivo TIMESTAMPTZ,
+    ADD COLUMN archivado_por UUID REFERENCES usuarios(id);
+
+CREATE INDEX idx_pacientes_estado ON pacientes(estado);
@@ -0,0 +1,16 @@
+-- Búsqueda difusa de pacientes por nombre, CI, código y celular
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- unaccent() no es IMMUTABLE, por lo que no puede usarse directamente en un índice
+CREATE OR REPLACE FUNCTION f_unaccent(text)
+RETURNS text AS $$
+    SELECT public.unaccent('public.unaccent', $1)
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT;
+
+DROP INDEX IF EXISTS idx_pacientes_nombre_lower;
+
+CREATE INDEX idx_pacientes_nombre_trgm ON pacientes USING GIN (f_unaccent(lower(nombre_completo)) gin_trgm_ops);
+CREATE INDEX idx_pacientes_ci_trgm ON pacientes USING GIN (ci gin_trgm_ops);
+CREATE INDEX idx_pacientes_codigo_trgm ON pacientes USING GIN (codigo gin_trgm_ops);
+CREATE INDEX idx_pacientes_celular_trgm ON pacientes USING GIN (celular gin_trgm_ops);
@@ -0,0 +1,12 @@
+-- Historial de cambios de estado de citas (alimenta la línea de tiempo del paciente)
+CREATE TABLE citas_estados_historial (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    cita_id UUID NOT NULL REFERENCES citas(id),
+    estado_anterior estado_cita,
+    estado_nuevo estado_cita NOT NULL,
+    cambiado_por UUID NOT NULL REFERENCES usuarios(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_citas_estados_historial_cita ON citas_estados_historial(cita_id);
+CREATE INDEX idx_consentimientos_fecha_firma ON consentimientos(paciente_id, fecha_firma DESC);
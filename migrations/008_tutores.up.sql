@@ -0,0 +1,20 @@
+-- Tutores / apoderados de pacientes (obligatorio en consentimientos de menores de edad)
+CREATE TABLE tutores (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    paciente_id UUID NOT NULL REFERENCES pacientes(id),
+    nombre_completo VARCHAR(150) NOT NULL,
+    ci VARCHAR(20) NOT NULL,
+    parentesco VARCHAR(50) NOT NULL,
+    celular VARCHAR(20),
+    created_by UUID NOT NULL REFERENCES usuarios(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_tutores_paciente ON tutores(paciente_id);
+
+CREATE TRIGGER tr_tutores_updated_at BEFORE UPDATE ON tutores
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
+
+-- Tutor que firma el consentimiento en nombre del paciente
+ALTER TABLE consentimientos ADD COLUMN tutor_id UUID REFERENCES tutores(id);
@@ -0,0 +1,54 @@
+-- Antecedentes estructurados de la historia clínica. Los campos de texto de
+-- historias_clinicas se mantienen como resumen generado a partir de estas tablas.
+CREATE TABLE historia_alergias (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    historia_id UUID NOT NULL REFERENCES historias_clinicas(id),
+    sustancia VARCHAR(150) NOT NULL,
+    reaccion TEXT NOT NULL DEFAULT '',
+    severidad VARCHAR(20) NOT NULL CHECK (severidad IN ('LEVE', 'MODERADA', 'SEVERA')),
+    created_by UUID NOT NULL REFERENCES usuarios(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_historia_alergias_historia ON historia_alergias(historia_id);
+
+CREATE TABLE historia_medicamentos (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    historia_id UUID NOT NULL REFERENCES historias_clinicas(id),
+    nombre VARCHAR(150) NOT NULL,
+    dosis VARCHAR(100) NOT NULL,
+    frecuencia VARCHAR(100) NOT NULL,
+    fecha_inicio DATE NOT NULL,
+    fecha_fin DATE CHECK (fecha_fin IS NULL OR fecha_fin >= fecha_inicio),
+    indicacion TEXT NOT NULL DEFAULT '',
+    created_by UUID NOT NULL REFERENCES usuarios(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_historia_medicamentos_historia ON historia_medicamentos(historia_id);
+
+CREATE TABLE historia_condiciones (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    historia_id UUID NOT NULL REFERENCES historias_clinicas(id),
+    tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('PERSONAL', 'FAMILIAR')),
+    codigo VARCHAR(20) NOT NULL DEFAULT '',
+    descripcion TEXT NOT NULL,
+    parentesco VARCHAR(50) NOT NULL DEFAULT '',
+    observaciones TEXT NOT NULL DEFAULT '',
+    created_by UUID NOT NULL REFERENCES usuarios(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_historia_condiciones_historia ON historia_condiciones(historia_id);
+
+CREATE TRIGGER tr_historia_alergias_updated_at BEFORE UPDATE ON historia_alergias
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
+
+CREATE TRIGGER tr_historia_medicamentos_updated_at BEFORE UPDATE ON historia_medicamentos
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
+
+CREATE TRIGGER tr_historia_condiciones_updated_at BEFORE UPDATE ON historia_condiciones
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
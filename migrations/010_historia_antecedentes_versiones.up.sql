@@ -0,0 +1,21 @@
+-- Versiones de los antecedentes de la historia clínica. Cada actualización
+-- guarda una copia completa de los valores junto con su autor.
+CREATE TABLE historia_antecedentes_versiones (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    historia_id UUID NOT NULL REFERENCES historias_clinicas(id),
+    version INT NOT NULL,
+    antecedentes_personales TEXT NOT NULL DEFAULT '',
+    antecedentes_familiares TEXT NOT NULL DEFAULT '',
+    alergias TEXT NOT NULL DEFAULT '',
+    medicamentos_actuales TEXT NOT NULL DEFAULT '',
+    autor_id UUID REFERENCES usuarios(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (historia_id, version)
+);
+
+-- La versión 1 es el estado inicial; para historias existentes se toma el valor
+-- actual sin autor conocido.
+INSERT INTO historia_antecedentes_versiones (historia_id, version,
+    antecedentes_personales, antecedentes_familiares, alergias, medicamentos_actuales, created_at)
+SELECT id, 1, antecedentes_personales, antecedentes_familiares, alergias, medicamentos_actuales, updated_at
+FROM historias_clinicas;
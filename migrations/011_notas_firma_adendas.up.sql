@@ -0,0 +1,37 @@
+-- Flujo borrador -> firmada para notas de evolución. Una nota firmada es
+-- inmutable; las correcciones se registran como adendas de la nota original.
+ALTER TABLE notas_evolucion
+    ADD COLUMN estado VARCHAR(20) NOT NULL DEFAULT 'FIRMADA' CHECK (estado IN ('BORRADOR', 'FIRMADA')),
+    ADD COLUMN firmado_por UUID REFERENCES usuarios(id),
+    ADD COLUMN firmado_at TIMESTAMPTZ,
+    ADD COLUMN nota_original_id UUID REFERENCES notas_evolucion(id),
+    ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- Las notas existentes se consideran firmadas por su autor al momento de crearlas.
+UPDATE notas_evolucion SET firmado_por = created_by, firmado_at = created_at, updated_at = created_at;
+
+ALTER TABLE notas_evolucion
+    ALTER COLUMN estado SET DEFAULT 'BORRADOR',
+    ADD CONSTRAINT chk_notas_firma CHECK (estado = 'BORRADOR' OR (firmado_por IS NOT NULL AND firmado_at IS NOT NULL));
+
+CREATE INDEX idx_notas_original ON notas_evolucion(nota_original_id);
+
+CREATE TRIGGER tr_notas_evolucion_updated_at BEFORE UPDATE ON notas_evolucion
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
+
+-- Respaldo a nivel de base de datos: una nota firmada no se modifica ni se elimina.
+CREATE OR REPLACE FUNCTION proteger_nota_firmada()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF OLD.estado = 'FIRMADA' THEN
+        RAISE EXCEPTION 'La nota de evolución % está firmada y no puede modificarse', OLD.id;
+    END IF;
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tr_notas_evolucion_inmutable BEFORE UPDATE OR DELETE ON notas_evolucion
+    FOR EACH ROW EXECUTE FUNCTION proteger_nota_firmada();
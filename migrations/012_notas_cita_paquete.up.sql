@@ -0,0 +1,7 @@
+-- Vincula las notas de evolución con la cita y el paquete que documentan
+ALTER TABLE notas_evolucion
+    ADD COLUMN cita_id UUID REFERENCES citas(id),
+    ADD COLUMN paquete_id UUID REFERENCES paquetes_tratamiento(id);
+
+CREATE INDEX idx_notas_cita ON notas_evolucion(cita_id);
+CREATE INDEX idx_notas_paquete ON notas_evolucion(paquete_id);
@@ -0,0 +1,46 @@
+-- Plantillas de notas de evolución con campos tipados. Las notas basadas en
+-- una plantilla guardan los datos estructurados y el texto generado en contenido.
+CREATE TABLE plantillas_nota (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    nombre VARCHAR(100) NOT NULL UNIQUE,
+    descripcion TEXT NOT NULL DEFAULT '',
+    tipo_nota VARCHAR(20) NOT NULL DEFAULT 'EVOLUCION' CHECK (tipo_nota IN ('TRATAMIENTO', 'EVOLUCION', 'NOTA')),
+    campos JSONB NOT NULL,
+    activa BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID REFERENCES usuarios(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER tr_plantillas_nota_updated_at BEFORE UPDATE ON plantillas_nota
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
+
+ALTER TABLE notas_evolucion
+    ADD COLUMN plantilla_id UUID REFERENCES plantillas_nota(id),
+    ADD COLUMN datos JSONB;
+
+INSERT INTO plantillas_nota (nombre, descripcion, tipo_nota, campos) VALUES
+('SOAP', 'Nota clínica Subjetivo / Objetivo / Análisis / Plan', 'EVOLUCION', '[
+    {"clave": "subjetivo", "etiqueta": "Subjetivo", "tipo": "TEXTO", "requerido": true},
+    {"clave": "objetivo", "etiqueta": "Objetivo", "tipo": "TEXTO", "requerido": true},
+    {"clave": "analisis", "etiqueta": "Análisis", "tipo": "TEXTO", "requerido": true},
+    {"clave": "plan", "etiqueta": "Plan", "tipo": "TEXTO", "requerido": true}
+]'),
+('Evaluación corporal', 'Medidas corporales y observaciones de la sesión', 'TRATAMIENTO', '[
+    {"clave": "peso", "etiqueta": "Peso", "tipo": "NUMERO", "unidad": "kg", "min": 1, "max": 400},
+    {"clave": "cintura", "etiqueta": "Cintura", "tipo": "NUMERO", "unidad": "cm", "min": 1, "max": 300},
+    {"clave": "abdomen", "etiqueta": "Abdomen", "tipo": "NUMERO", "unidad": "cm", "min": 1, "max": 300},
+    {"clave": "cadera", "etiqueta": "Cadera", "tipo": "NUMERO", "unidad": "cm", "min": 1, "max": 300},
+    {"clave": "zona_tratada", "etiqueta": "Zona tratada", "tipo": "OPCION", "requerido": true,
+     "opciones": ["Abdomen", "Flancos", "Glúteos", "Piernas", "Brazos", "Espalda"]},
+    {"clave": "tolerancia", "etiqueta": "Buena tolerancia al tratamiento", "tipo": "BOOLEANO"},
+    {"clave": "observaciones", "etiqueta": "Observaciones", "tipo": "TEXTO"}
+]'),
+('Evaluación facial', 'Valoración de piel y tratamiento facial', 'TRATAMIENTO', '[
+    {"clave": "biotipo", "etiqueta": "Biotipo cutáneo", "tipo": "OPCION", "requerido": true,
+     "opciones": ["Normal", "Seca", "Grasa", "Mixta", "Sensible"]},
+    {"clave": "fototipo", "etiqueta": "Fototipo (Fitzpatrick)", "tipo": "ENTERO", "min": 1, "max": 6},
+    {"clave": "lesiones", "etiqueta": "Lesiones observadas", "tipo": "TEXTO"},
+    {"clave": "procedimiento", "etiqueta": "Procedimiento realizado", "tipo": "TEXTO", "requerido": true},
+    {"clave": "proxima_sesion", "etiqueta": "Próxima sesión", "tipo": "FECHA"}
+]');
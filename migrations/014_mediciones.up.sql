@@ -0,0 +1,24 @@
+-- Mediciones corporales y signos vitales por sesión
+CREATE TABLE mediciones (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    historia_id UUID NOT NULL REFERENCES historias_clinicas(id),
+    cita_id UUID REFERENCES citas(id),
+    paquete_id UUID REFERENCES paquetes_tratamiento(id),
+    fecha TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    peso_kg NUMERIC(5,2) CHECK (peso_kg > 0),
+    talla_cm NUMERIC(5,1) CHECK (talla_cm > 0),
+    imc NUMERIC(5,2),
+    cintura_cm NUMERIC(5,1) CHECK (cintura_cm > 0),
+    cadera_cm NUMERIC(5,1) CHECK (cadera_cm > 0),
+    abdomen_cm NUMERIC(5,1) CHECK (abdomen_cm > 0),
+    presion_sistolica SMALLINT CHECK (presion_sistolica > 0),
+    presion_diastolica SMALLINT CHECK (presion_diastolica > 0),
+    frecuencia_cardiaca SMALLINT CHECK (frecuencia_cardiaca > 0),
+    escala_dolor SMALLINT CHECK (escala_dolor BETWEEN 0 AND 10),
+    observaciones TEXT NOT NULL DEFAULT '',
+    created_by UUID NOT NULL REFERENCES usuarios(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_mediciones_historia_fecha ON mediciones(historia_id, fecha);
+CREATE INDEX idx_mediciones_paquete ON mediciones(paquete_id);
@@ -0,0 +1,22 @@
+-- Fotografías clínicas. El archivo vive en el almacenamiento configurado
+-- (storage_key); aquí se guarda su metadato y el consentimiento que lo autorizó.
+CREATE TABLE fotos (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    paciente_id UUID NOT NULL REFERENCES pacientes(id),
+    consentimiento_id UUID NOT NULL REFERENCES consentimientos(id),
+    cita_id UUID REFERENCES citas(id),
+    paquete_id UUID REFERENCES paquetes_tratamiento(id),
+    zona VARCHAR(100) NOT NULL,
+    momento VARCHAR(20) NOT NULL CHECK (momento IN ('ANTES', 'DESPUES', 'SEGUIMIENTO')),
+    descripcion TEXT NOT NULL DEFAULT '',
+    storage_key VARCHAR(255) NOT NULL UNIQUE,
+    nombre_archivo VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    tamano_bytes BIGINT NOT NULL,
+    tomada_en TIMESTAMPTZ NOT NULL,
+    tomada_por UUID NOT NULL REFERENCES usuarios(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_fotos_paciente_zona ON fotos(paciente_id, zona, tomada_en);
+CREATE INDEX idx_fotos_paquete ON fotos(paquete_id);
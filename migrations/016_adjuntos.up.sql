@@ -0,0 +1,22 @@
+-- Adjuntos: documentos externos (laboratorios, informes previos) del paciente
+
+CREATE TABLE adjuntos (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    paciente_id UUID NOT NULL REFERENCES pacientes(id),
+    nota_id UUID REFERENCES notas_evolucion(id),
+    descripcion TEXT NOT NULL DEFAULT '',
+    visibilidad VARCHAR(10) NOT NULL DEFAULT 'GENERAL'
+        CHECK (visibilidad IN ('GENERAL', 'CLINICO', 'ADMIN')),
+    nombre_archivo VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    tamano_bytes BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    storage_key TEXT NOT NULL,
+    subido_por UUID NOT NULL REFERENCES usuarios(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (paciente_id, sha256)
+);
+
+CREATE INDEX idx_adjuntos_paciente ON adjuntos(paciente_id);
+CREATE INDEX idx_adjuntos_nota ON adjuntos(nota_id);
+CREATE INDEX idx_adjuntos_sha256 ON adjuntos(sha256);
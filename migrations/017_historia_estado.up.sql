@@ -0,0 +1,9 @@
+-- Ciclo de vida de la historia clínica: ACTIVA, CERRADA, ARCHIVADA
+
+UPDATE historias_clinicas SET estado = 'ACTIVA' WHERE estado NOT IN ('ACTIVA', 'CERRADA', 'ARCHIVADA');
+
+ALTER TABLE historias_clinicas
+    ADD CONSTRAINT chk_historias_estado CHECK (estado IN ('ACTIVA', 'CERRADA', 'ARCHIVADA')),
+    ADD COLUMN motivo_estado TEXT,
+    ADD COLUMN estado_cambiado_por UUID REFERENCES usuarios(id),
+    ADD COLUMN estado_cambiado_at TIMESTAMPTZ;
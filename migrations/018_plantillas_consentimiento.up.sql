@@ -0,0 +1,51 @@
+-- Plantillas de consentimiento versionadas. Cada cambio de texto crea una
+-- versión nueva e inmutable; los consentimientos guardan la versión usada,
+-- el texto generado y su hash SHA-256.
+CREATE TABLE plantillas_consentimiento (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    nombre VARCHAR(100) NOT NULL UNIQUE,
+    tipo_tratamiento VARCHAR(100) NOT NULL DEFAULT '',
+    activa BOOLEAN NOT NULL DEFAULT TRUE,
+    version_actual INT NOT NULL DEFAULT 1,
+    created_by UUID REFERENCES usuarios(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER tr_plantillas_consentimiento_updated_at BEFORE UPDATE ON plantillas_consentimiento
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
+
+CREATE TABLE plantillas_consentimiento_versiones (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    plantilla_id UUID NOT NULL REFERENCES plantillas_consentimiento(id),
+    version INT NOT NULL,
+    texto TEXT NOT NULL,
+    created_by UUID REFERENCES usuarios(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (plantilla_id, version)
+);
+
+ALTER TABLE consentimientos
+    ADD COLUMN plantilla_version_id UUID REFERENCES plantillas_consentimiento_versiones(id),
+    ADD COLUMN contenido_sha256 CHAR(64);
+
+-- Los consentimientos anteriores no tienen plantilla; se registra el hash de su texto.
+UPDATE consentimientos SET contenido_sha256 = encode(sha256(convert_to(contenido, 'UTF8')), 'hex');
+
+ALTER TABLE consentimientos ALTER COLUMN contenido_sha256 SET NOT NULL;
+
+WITH p AS (
+    INSERT INTO plantillas_consentimiento (nombre, tipo_tratamiento)
+    VALUES ('Consentimiento general', '')
+    RETURNING id
+)
+INSERT INTO plantillas_consentimiento_versiones (plantilla_id, version, texto)
+SELECT id, 1,
+'Yo, {{paciente_nombre}}, con CI {{paciente_ci}}, declaro haber sido informado/a sobre el tratamiento {{tratamiento}} que recibiré en Centro Caribel, sus beneficios, riesgos y cuidados posteriores, y haber podido resolver mis dudas.
+
+Autorizo al personal del centro a realizar dicho tratamiento y me comprometo a seguir las indicaciones recibidas.
+
+{{tutor}}
+
+Fecha: {{fecha}}'
+FROM p;
@@ -0,0 +1,17 @@
+-- Firma validada y sello HMAC de los consentimientos. Un consentimiento firmado
+-- no se modifica ni se elimina: el trigger rechaza UPDATE y DELETE.
+
+ALTER TABLE consentimientos
+    ADD COLUMN firma_tipo VARCHAR(20),
+    ADD COLUMN sello CHAR(64);
+
+CREATE OR REPLACE FUNCTION proteger_consentimiento()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'Los consentimientos no se pueden modificar ni eliminar (id %)', OLD.id
+        USING ERRCODE = 'integrity_constraint_violation';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tr_consentimientos_inmutables BEFORE UPDATE OR DELETE ON consentimientos
+    FOR EACH ROW EXECUTE FUNCTION proteger_consentimiento();
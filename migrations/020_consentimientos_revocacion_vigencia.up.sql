@@ -0,0 +1,34 @@
+-- Revocación y vencimiento de consentimientos. Los consentimientos son
+-- inmutables, así que las revocaciones se registran en su propia tabla.
+
+ALTER TABLE plantillas_consentimiento
+    ADD COLUMN vigencia_dias INT CHECK (vigencia_dias > 0),
+    ADD COLUMN requerido BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE consentimientos
+    ADD COLUMN tratamiento VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN fecha_vencimiento TIMESTAMPTZ;
+
+-- El tratamiento no forma parte del sello, así que completarlo no invalida
+-- los consentimientos existentes.
+ALTER TABLE consentimientos DISABLE TRIGGER tr_consentimientos_inmutables;
+UPDATE consentimientos c SET tratamiento = p.tipo_tratamiento
+FROM plantillas_consentimiento_versiones v
+JOIN plantillas_consentimiento p ON p.id = v.plantilla_id
+WHERE v.id = c.plantilla_version_id;
+ALTER TABLE consentimientos ENABLE TRIGGER tr_consentimientos_inmutables;
+
+CREATE INDEX idx_consentimientos_paciente_tratamiento ON consentimientos(paciente_id, lower(tratamiento));
+
+CREATE TABLE consentimiento_revocaciones (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    consentimiento_id UUID NOT NULL REFERENCES consentimientos(id),
+    alcance VARCHAR(10) NOT NULL CHECK (alcance IN ('TOTAL', 'FOTOS')),
+    fecha_revocacion TIMESTAMPTZ NOT NULL,
+    motivo TEXT NOT NULL,
+    registrado_por UUID NOT NULL REFERENCES usuarios(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (consentimiento_id, alcance)
+);
+
+CREATE INDEX idx_consentimiento_revocaciones_consentimiento ON consentimiento_revocaciones(consentimiento_id);
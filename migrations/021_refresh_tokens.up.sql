@@ -0,0 +1,22 @@
+-- Refresh tokens persistentes. Solo se guarda el SHA-256 del token. Cada
+-- refresh marca el token como usado y emite otro de la misma familia (la
+-- sesión iniciada en un login); presentar un token ya usado revoca la familia.
+
+CREATE TABLE refresh_tokens (
+    id UUID PRIMARY KEY,
+    usuario_id UUID NOT NULL REFERENCES usuarios(id),
+    familia_id UUID NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    reemplazado_por UUID REFERENCES refresh_tokens(id),
+    revoked_at TIMESTAMPTZ,
+    motivo_revocacion VARCHAR(20) CHECK (motivo_revocacion IN ('LOGOUT', 'LOGOUT_TODOS', 'REUTILIZADO')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_refresh_tokens_usuario ON refresh_tokens(usuario_id) WHERE revoked_at IS NULL;
+CREATE INDEX idx_refresh_tokens_familia ON refresh_tokens(familia_id);
+CREATE INDEX idx_refresh_tokens_expires ON refresh_tokens(expires_at);
@@ -0,0 +1,3 @@
+-- Versión de los access tokens de cada usuario. Los tokens llevan la versión
+-- vigente al emitirse en el claim "ver"; incrementarla invalida los emitidos.
+ALTER TABLE usuarios ADD COLUMN token_version INT NOT NULL DEFAULT 0;
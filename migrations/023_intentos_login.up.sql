@@ -0,0 +1,23 @@
+-- Protección contra fuerza bruta en el login. Cada usuario lleva la cuenta de
+-- fallos consecutivos; al llegar al máximo la cuenta queda bloqueada hasta
+-- bloqueado_hasta. intentos_login registra todos los intentos, exitosos o no.
+
+ALTER TABLE usuarios
+    ADD COLUMN intentos_fallidos INT NOT NULL DEFAULT 0,
+    ADD COLUMN ultimo_fallo_at TIMESTAMPTZ,
+    ADD COLUMN bloqueado_hasta TIMESTAMPTZ;
+
+CREATE TABLE intentos_login (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    email VARCHAR(255) NOT NULL,
+    usuario_id UUID REFERENCES usuarios(id),
+    exitoso BOOLEAN NOT NULL,
+    motivo VARCHAR(20) CHECK (motivo IN ('CREDENCIALES', 'DESACTIVADO', 'BLOQUEADO', 'DEMORA', 'LIMITE_IP')),
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_intentos_login_email ON intentos_login(email, created_at DESC);
+CREATE INDEX idx_intentos_login_ip ON intentos_login(ip, created_at DESC) WHERE motivo = 'CREDENCIALES';
+CREATE INDEX idx_intentos_login_created ON intentos_login(created_at DESC);
@@ -0,0 +1,24 @@
+-- Gestión de contraseñas. debe_cambiar_password obliga a elegir una contraseña
+-- propia en el primer login (usuarios creados por la Administradora). Los
+-- tokens de restablecimiento sirven una sola vez y solo se guarda su SHA-256.
+
+ALTER TABLE usuarios
+    ADD COLUMN debe_cambiar_password BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN password_changed_at TIMESTAMPTZ;
+
+CREATE TABLE password_reset_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    usuario_id UUID NOT NULL REFERENCES usuarios(id),
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    creado_por UUID NOT NULL REFERENCES usuarios(id),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_password_reset_tokens_usuario ON password_reset_tokens(usuario_id) WHERE used_at IS NULL;
+
+-- Cambiar la contraseña cierra las demás sesiones.
+ALTER TABLE refresh_tokens DROP CONSTRAINT refresh_tokens_motivo_revocacion_check;
+ALTER TABLE refresh_tokens ADD CONSTRAINT refresh_tokens_motivo_revocacion_check
+    CHECK (motivo_revocacion IN ('LOGOUT', 'LOGOUT_TODOS', 'REUTILIZADO', 'CAMBIO_PASSWORD'));
@@ -0,0 +1,31 @@
+-- Autenticación en dos pasos con TOTP. El secreto se guarda cifrado con
+-- AES-GCM; mientras habilitado es false la inscripción está pendiente de
+-- confirmar con un código. ultimo_paso evita que un mismo código sirva dos
+-- veces. De los códigos de recuperación solo se guarda su SHA-256.
+
+CREATE TABLE usuarios_totp (
+    usuario_id UUID PRIMARY KEY REFERENCES usuarios(id),
+    secreto_cifrado TEXT NOT NULL,
+    habilitado BOOLEAN NOT NULL DEFAULT false,
+    ultimo_paso BIGINT NOT NULL DEFAULT 0,
+    habilitado_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE totp_codigos_recuperacion (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    usuario_id UUID NOT NULL REFERENCES usuarios(id),
+    codigo_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (usuario_id, codigo_hash)
+);
+
+ALTER TABLE intentos_login DROP CONSTRAINT intentos_login_motivo_check;
+ALTER TABLE intentos_login ADD CONSTRAINT intentos_login_motivo_check
+    CHECK (motivo IN ('CREDENCIALES', 'CODIGO_2FA', 'DESACTIVADO', 'BLOQUEADO', 'DEMORA', 'LIMITE_IP'));
+
+-- Restablecer el segundo factor cierra las sesiones del usuario.
+ALTER TABLE refresh_tokens DROP CONSTRAINT refresh_tokens_motivo_revocacion_check;
+ALTER TABLE refresh_tokens ADD CONSTRAINT refresh_tokens_motivo_revocacion_check
+    CHECK (motivo_revocacion IN ('LOGOUT', 'LOGOUT_TODOS', 'REUTILIZADO', 'CAMBIO_PASSWORD', 'RESET_2FA'));
@@ -0,0 +1,5 @@
+-- Sello v2: incluye el tratamiento y el vencimiento, de los que depende la
+-- vigencia. Los consentimientos existentes conservan su sello v1.
+
+ALTER TABLE consentimientos
+    ADD COLUMN sello_version SMALLINT NOT NULL DEFAULT 1 CHECK (sello_version IN (1, 2));
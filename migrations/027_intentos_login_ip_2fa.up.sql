@@ -0,0 +1,5 @@
+-- Los códigos 2FA incorrectos también cuentan para el límite por IP.
+
+DROP INDEX idx_intentos_login_ip;
+CREATE INDEX idx_intentos_login_ip ON intentos_login(ip, created_at DESC)
+    WHERE motivo IN ('CREDENCIALES', 'CODIGO_2FA');
@@ -0,0 +1,22 @@
+-- Los borradores descartados se eliminan de notas_evolucion, pero su contenido
+-- queda registrado junto con quién los descartó y cuándo. Si no fue su autor
+-- (la Administradora), el motivo es obligatorio.
+
+CREATE TABLE notas_descartadas (
+    id UUID PRIMARY KEY,
+    historia_id UUID NOT NULL REFERENCES historias_clinicas(id),
+    tipo VARCHAR(20) NOT NULL,
+    contenido TEXT NOT NULL,
+    datos JSONB,
+    plantilla_id UUID,
+    cita_id UUID,
+    paquete_id UUID,
+    created_by UUID NOT NULL REFERENCES usuarios(id),
+    created_at TIMESTAMPTZ NOT NULL,
+    descartada_por UUID NOT NULL REFERENCES usuarios(id),
+    descartada_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    motivo TEXT,
+    CONSTRAINT chk_notas_descartadas_motivo CHECK (descartada_por = created_by OR COALESCE(TRIM(motivo), '') <> '')
+);
+
+CREATE INDEX idx_notas_descartadas_historia ON notas_descartadas(historia_id);
@@ -0,0 +1,10 @@
+-- La contraseña actual incorrecta al cambiarla cuenta como intento fallido,
+-- con la misma demora, bloqueo y límite por IP que el login.
+
+ALTER TABLE intentos_login DROP CONSTRAINT intentos_login_motivo_check;
+ALTER TABLE intentos_login ADD CONSTRAINT intentos_login_motivo_check
+    CHECK (motivo IN ('CREDENCIALES', 'CODIGO_2FA', 'PASSWORD_ACTUAL', 'DESACTIVADO', 'BLOQUEADO', 'DEMORA', 'LIMITE_IP'));
+
+DROP INDEX idx_intentos_login_ip;
+CREATE INDEX idx_intentos_login_ip ON intentos_login(ip, created_at DESC)
+    WHERE motivo IN ('CREDENCIALES', 'CODIGO_2FA', 'PASSWORD_ACTUAL');
@@ -0,0 +1,5 @@
+-- Desactivar el segundo factor cierra las demás sesiones del usuario.
+
+ALTER TABLE refresh_tokens DROP CONSTRAINT refresh_tokens_motivo_revocacion_check;
+ALTER TABLE refresh_tokens ADD CONSTRAINT refresh_tokens_motivo_revocacion_check
+    CHECK (motivo_revocacion IN ('LOGOUT', 'LOGOUT_TODOS', 'REUTILIZADO', 'CAMBIO_PASSWORD', 'RESET_2FA', 'DESACTIVAR_2FA'));
@@ -9,6 +9,8 @@ type AppError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 	Detail  string `json:"detail,omitempty"`
+	// RetryAfter, en segundos, se envía en la cabecera Retry-After si es mayor a cero.
+	RetryAfter int `json:"-"`
 }
 
 func (e *AppError) Error() string {
@@ -38,3 +40,7 @@ func NewConflict(detail string) *AppError {
 func NewInternal(detail string) *AppError {
 	return &AppError{Code: http.StatusInternalServerError, Message: "Error interno", Detail: detail}
 }
+
+func NewTooManyRequests(detail string, retryAfter int) *AppError {
+	return &AppError{Code: http.StatusTooManyRequests, Message: "Demasiados intentos", Detail: detail, RetryAfter: retryAfter}
+}
@@ -0,0 +1,72 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	apperrors "github.com/tunek/centro-caribel/pkg/errors"
+)
+
+// MaxLength es el largo máximo en bytes: bcrypt ignora lo que sigue.
+const MaxLength = 72
+
+// Policy define los requisitos de una contraseña.
+type Policy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// Validate devuelve un error BadRequest que enumera todos los requisitos que
+// la contraseña no cumple.
+func (p Policy) Validate(password string) error {
+	if len(password) > MaxLength {
+		return apperrors.NewBadRequest(fmt.Sprintf("La contraseña no puede superar los %d caracteres", MaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+
+	var faltan []string
+	if n := len([]rune(password)); n < p.MinLength {
+		faltan = append(faltan, fmt.Sprintf("al menos %d caracteres", p.MinLength))
+	}
+	if p.RequireUpper && !upper {
+		faltan = append(faltan, "una mayúscula")
+	}
+	if p.RequireLower && !lower {
+		faltan = append(faltan, "una minúscula")
+	}
+	if p.RequireDigit && !digit {
+		faltan = append(faltan, "un número")
+	}
+	if p.RequireSymbol && !symbol {
+		faltan = append(faltan, "un símbolo")
+	}
+	if len(faltan) == 0 {
+		return nil
+	}
+	return apperrors.NewBadRequest("La contraseña debe tener " + enumerar(faltan))
+}
+
+// enumerar une los elementos como en "a, b y c".
+func enumerar(items []string) string {
+	if len(items) == 1 {
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " y " + items[len(items)-1]
+}
@@ -0,0 +1,60 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+)
+
+// imagen es un XObject RGB de 8 bits comprimido con Flate.
+type imagen struct {
+	ancho, alto int
+	datos       []byte
+}
+
+// Image dibuja img alineada a la izquierda con el ancho indicado en puntos,
+// respetando la proporción. Si la altura resultante supera altoMax, la imagen
+// se reduce. Las zonas transparentes se componen sobre fondo blanco.
+func (d *Document) Image(img image.Image, ancho, altoMax float64) {
+	b := img.Bounds()
+	if b.Dx() == 0 || b.Dy() == 0 {
+		return
+	}
+
+	rgb := make([]byte, 0, b.Dx()*b.Dy()*3)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			// RGBA devuelve valores premultiplicados de 16 bits: sobre blanco,
+			// cada canal es c + (1-alfa).
+			r, g, bl, a := img.At(x, y).RGBA()
+			fondo := 0xffff - a
+			rgb = append(rgb, byte((r+fondo)>>8), byte((g+fondo)>>8), byte((bl+fondo)>>8))
+		}
+	}
+	var comprimido bytes.Buffer
+	zw := zlib.NewWriter(&comprimido)
+	zw.Write(rgb)
+	zw.Close()
+
+	d.imagenes = append(d.imagenes, &imagen{ancho: b.Dx(), alto: b.Dy(), datos: comprimido.Bytes()})
+	w, h := d.caja(float64(b.Dx()), float64(b.Dy()), ancho, altoMax)
+	fmt.Fprintf(&d.actual().contenido, "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n",
+		w, h, margen, d.y, len(d.imagenes))
+}
+
+// caja calcula el tamaño en puntos de un gráfico de proporciones
+// anchoNatural×altoNatural, reserva su espacio y deja d.y en su borde inferior.
+func (d *Document) caja(anchoNatural, altoNatural, ancho, altoMax float64) (float64, float64) {
+	if ancho <= 0 || ancho > d.anchoUtil() {
+		ancho = d.anchoUtil()
+	}
+	alto := ancho * altoNatural / anchoNatural
+	if altoMax > 0 && alto > altoMax {
+		ancho *= altoMax / alto
+		alto = altoMax
+	}
+	d.reservar(alto)
+	d.y -= alto
+	return ancho, alto
+}
@@ -0,0 +1,267 @@
+// Package pdf genera documentos PDF de texto paginado con las fuentes estándar
+// Helvetica, sin dependencias externas. El texto se codifica en WinAnsi, que
+// cubre los caracteres del español. Admite imágenes rasterizadas y un
+// subconjunto de SVG, suficiente para firmas manuscritas.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Dimensiones de una página A4 en puntos.
+const (
+	AnchoPagina = 595.28
+	AltoPagina  = 841.89
+
+	margen       = 50.0
+	margenPie    = 40.0
+	interlineado = 1.35
+)
+
+type fuente int
+
+const (
+	regular fuente = iota
+	negrita
+)
+
+var nombresFuente = [...]string{regular: "Helvetica", negrita: "Helvetica-Bold"}
+
+type pagina struct {
+	contenido bytes.Buffer
+}
+
+// Document acumula páginas; el pie se dibuja al escribir, cuando ya se conoce
+// el total de páginas.
+type Document struct {
+	titulo   string
+	creado   time.Time
+	paginas  []*pagina
+	imagenes []*imagen
+	y        float64
+
+	// Pie devuelve el texto del pie de cada página. Puede ser nil.
+	Pie func(pagina, total int) string
+}
+
+func New(titulo string) *Document {
+	d := &Document{titulo: titulo, creado: time.Now()}
+	d.nuevaPagina()
+	return d
+}
+
+func (d *Document) actual() *pagina {
+	return d.paginas[len(d.paginas)-1]
+}
+
+func (d *Document) nuevaPagina() {
+	d.paginas = append(d.paginas, &pagina{})
+	d.y = AltoPagina - margen
+}
+
+// reservar salta de página si no quedan alto puntos disponibles.
+func (d *Document) reservar(alto float64) {
+	if d.y-alto < margen+margenPie {
+		d.nuevaPagina()
+	}
+}
+
+func (d *Document) anchoUtil() float64 {
+	return AnchoPagina - 2*margen
+}
+
+// Title escribe el título principal del documento.
+func (d *Document) Title(texto string) {
+	d.bloque(texto, negrita, 16, 0)
+	d.Space(6)
+}
+
+// Heading abre una sección con una línea separadora. Si quedan pocas líneas en
+// la página, la sección empieza en la siguiente.
+func (d *Document) Heading(texto string) {
+	d.Space(10)
+	d.reservar(60)
+	d.bloque(texto, negrita, 13, 0)
+	d.Rule()
+}
+
+// Subheading escribe un subtítulo dentro de una sección.
+func (d *Document) Subheading(texto string) {
+	d.Space(4)
+	d.reservar(40)
+	d.bloque(texto, negrita, 11, 0)
+}
+
+// Text escribe un párrafo ajustado al ancho de la página. Los saltos de línea
+// del texto se respetan.
+func (d *Document) Text(texto string) {
+	d.bloque(texto, regular, 10, 0)
+}
+
+// Indented escribe un párrafo con sangría izquierda.
+func (d *Document) Indented(texto string) {
+	d.bloque(texto, regular, 10, 15)
+}
+
+// Small escribe un párrafo en tamaño reducido, para notas y metadatos.
+func (d *Document) Small(texto string) {
+	d.bloque(texto, regular, 8, 0)
+}
+
+// Field escribe "etiqueta: valor" con la etiqueta en negrita. El valor continúa
+// en las líneas siguientes si no cabe.
+func (d *Document) Field(etiqueta, valor string) {
+	const tam = 10
+	etiqueta += ": "
+	anchoEtiqueta := anchoTexto(etiqueta, negrita, tam)
+	lineas := ajustar(valor, regular, tam, d.anchoUtil(), anchoEtiqueta)
+	alto := tam * interlineado
+
+	for i, linea := range lineas {
+		d.reservar(alto)
+		d.y -= alto
+		x := margen
+		if i == 0 {
+			d.texto(margen, d.y, etiqueta, negrita, tam)
+			x += anchoEtiqueta
+		}
+		d.texto(x, d.y, linea, regular, tam)
+	}
+}
+
+// Space avanza verticalmente alto puntos.
+func (d *Document) Space(alto float64) {
+	d.y -= alto
+	if d.y < margen+margenPie {
+		d.nuevaPagina()
+	}
+}
+
+// Rule dibuja una línea horizontal a lo ancho de la página.
+func (d *Document) Rule() {
+	d.Space(4)
+	fmt.Fprintf(&d.actual().contenido, "0.6 G 0.5 w %.2f %.2f m %.2f %.2f l S 0 G\n",
+		margen, d.y, AnchoPagina-margen, d.y)
+	d.Space(8)
+}
+
+func (d *Document) bloque(texto string, f fuente, tam, sangria float64) {
+	alto := tam * interlineado
+	for _, linea := range ajustar(texto, f, tam, d.anchoUtil()-sangria, 0) {
+		d.reservar(alto)
+		d.y -= alto
+		d.texto(margen+sangria, d.y, linea, f, tam)
+	}
+}
+
+func (d *Document) texto(x, y float64, s string, f fuente, tam float64) {
+	if s == "" {
+		return
+	}
+	fmt.Fprintf(&d.actual().contenido, "BT /F%d %.1f Tf %.2f %.2f Td (%s) Tj ET\n",
+		f+1, tam, x, y, escapar(s))
+}
+
+// WriteTo serializa el documento en formato PDF 1.4.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	total := len(d.paginas)
+	if d.Pie != nil {
+		for i, p := range d.paginas {
+			pie := d.Pie(i+1, total)
+			fmt.Fprintf(&p.contenido, "0.6 G 0.5 w %.2f %.2f m %.2f %.2f l S 0 G\n",
+				margen, margenPie+10, AnchoPagina-margen, margenPie+10)
+			fmt.Fprintf(&p.contenido, "0.4 g BT /F1 8 Tf %.2f %.2f Td (%s) Tj ET 0 g\n",
+				margen, margenPie-2, escapar(pie))
+		}
+	}
+
+	e := &escritor{}
+	e.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objetos fijos: 1 catálogo, 2 árbol de páginas, 3 info, 4-5 fuentes.
+	// Cada página ocupa dos objetos: la página y su contenido. Las imágenes
+	// van al final y todas las páginas las declaran como recursos.
+	const primeraPagina = 6
+	kids := make([]string, total)
+	for i := range d.paginas {
+		kids[i] = fmt.Sprintf("%d 0 R", primeraPagina+2*i)
+	}
+	primeraImagen := primeraPagina + 2*total
+	var xobjects strings.Builder
+	if len(d.imagenes) > 0 {
+		xobjects.WriteString(" /XObject <<")
+		for i := range d.imagenes {
+			fmt.Fprintf(&xobjects, " /Im%d %d 0 R", i+1, primeraImagen+i)
+		}
+		xobjects.WriteString(" >>")
+	}
+
+	e.objeto("<< /Type /Catalog /Pages 2 0 R >>")
+	e.objeto(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), total))
+	e.objeto(fmt.Sprintf("<< /Title (%s) /Producer (centro-caribel) /CreationDate (D:%s) >>",
+		escapar(d.titulo), d.creado.UTC().Format("20060102150405Z")))
+	for _, nombre := range nombresFuente {
+		e.objeto(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", nombre))
+	}
+
+	for i, p := range d.paginas {
+		e.objeto(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 4 0 R /F2 5 0 R >>%s >> /Contents %d 0 R >>",
+			AnchoPagina, AltoPagina, xobjects.String(), primeraPagina+2*i+1))
+
+		var comprimido bytes.Buffer
+		zw := zlib.NewWriter(&comprimido)
+		zw.Write(p.contenido.Bytes())
+		zw.Close()
+		e.stream("/Filter /FlateDecode", comprimido.Bytes())
+	}
+
+	for _, img := range d.imagenes {
+		e.stream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d "+
+			"/ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode", img.ancho, img.alto), img.datos)
+	}
+
+	e.cerrar()
+	n, err := w.Write(e.buf.Bytes())
+	return int64(n), err
+}
+
+// Bytes devuelve el documento serializado.
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	d.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+// escritor numera los objetos y registra sus posiciones para la tabla xref.
+type escritor struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (e *escritor) objeto(cuerpo string) {
+	e.offsets = append(e.offsets, e.buf.Len())
+	fmt.Fprintf(&e.buf, "%d 0 obj\n%s\nendobj\n", len(e.offsets), cuerpo)
+}
+
+func (e *escritor) stream(dict string, datos []byte) {
+	e.offsets = append(e.offsets, e.buf.Len())
+	fmt.Fprintf(&e.buf, "%d 0 obj\n<< %s /Length %d >>\nstream\n", len(e.offsets), dict, len(datos))
+	e.buf.Write(datos)
+	e.buf.WriteString("\nendstream\nendobj\n")
+}
+
+func (e *escritor) cerrar() {
+	xref := e.buf.Len()
+	fmt.Fprintf(&e.buf, "xref\n0 %d\n0000000000 65535 f \n", len(e.offsets)+1)
+	for _, off := range e.offsets {
+		fmt.Fprintf(&e.buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&e.buf, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(e.offsets)+1, xref)
+}
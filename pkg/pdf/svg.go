@@ -0,0 +1,676 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SVG dibuja un SVG como gráfico vectorial, con el mismo criterio de tamaño
+// que Image. Se admiten los elementos de dibujo path, line, polyline,
+// polygon, rect, circle y ellipse, agrupados con g y transformados con
+// transform; los colores de relleno y trazo se toman de los atributos o del
+// atributo style. El resto (texto, gradientes, referencias) se ignora.
+func (d *Document) SVG(datos []byte, ancho, altoMax float64) error {
+	var buf bytes.Buffer
+	vb, err := traducirSVG(datos, &buf)
+	if err != nil {
+		return err
+	}
+
+	w, h := d.caja(vb.ancho, vb.alto, ancho, altoMax)
+	s := w / vb.ancho
+	c := &d.actual().contenido
+	// El origen de SVG está arriba a la izquierda y el eje y crece hacia abajo.
+	fmt.Fprintf(c, "q %.2f %.2f %.2f %.2f re W n %s 0 0 %s %s %s cm\n",
+		margen, d.y, w, h, num(s), num(-s), num(margen-vb.x*s), num(d.y+h+vb.y*s))
+	c.Write(buf.Bytes())
+	c.WriteString("Q\n")
+	return nil
+}
+
+// contenedoresOcultos no se dibujan directamente; su contenido se ignora.
+var contenedoresOcultos = map[string]bool{
+	"defs": true, "symbol": true, "clipPath": true, "mask": true, "marker": true, "pattern": true,
+	"title": true, "desc": true, "metadata": true, "style": true, "text": true,
+}
+
+type vistaSVG struct {
+	x, y, ancho, alto float64
+}
+
+// estiloSVG son las propiedades de pintura heredables.
+type estiloSVG struct {
+	relleno, trazo string // color PDF ("r g b") o "" si es none
+	grosor         float64
+}
+
+// traducirSVG escribe en out los operadores PDF equivalentes, en coordenadas
+// del viewBox, y devuelve el viewBox.
+func traducirSVG(datos []byte, out *bytes.Buffer) (vistaSVG, error) {
+	var vb vistaSVG
+	dec := xml.NewDecoder(bytes.NewReader(datos))
+	dec.Strict = true
+
+	pila := []estiloSVG{{relleno: "0 0 0", grosor: 1}}
+	raiz := true
+	dibujado := false
+	ocultos := 0 // profundidad dentro de un contenedor oculto
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return vb, fmt.Errorf("svg inválido: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			nombre := t.Name.Local
+			if ocultos > 0 || (!raiz && contenedoresOcultos[nombre]) {
+				ocultos++
+				continue
+			}
+			attrs := atributosSVG(t.Attr)
+			if raiz {
+				if nombre != "svg" {
+					return vb, errors.New("el elemento raíz no es svg")
+				}
+				if vb, err = vistaDe(attrs); err != nil {
+					return vb, err
+				}
+				raiz = false
+			}
+
+			estilo := pila[len(pila)-1].heredar(attrs)
+			pila = append(pila, estilo)
+			out.WriteString("q\n")
+			if tr := attrs["transform"]; tr != "" {
+				m, err := transformacionSVG(tr)
+				if err != nil {
+					return vb, err
+				}
+				fmt.Fprintf(out, "%s %s %s %s %s %s cm\n", num(m[0]), num(m[1]), num(m[2]), num(m[3]), num(m[4]), num(m[5]))
+			}
+			if estilo.grosor > 0 {
+				fmt.Fprintf(out, "%s w 1 J 1 j\n", num(estilo.grosor))
+			}
+
+			trazado, err := figuraSVG(nombre, attrs)
+			if err != nil {
+				return vb, err
+			}
+			if trazado != "" {
+				out.WriteString(trazado)
+				out.WriteString(estilo.operadorPintura())
+				dibujado = true
+			}
+		case xml.EndElement:
+			if ocultos > 0 {
+				ocultos--
+				continue
+			}
+			if len(pila) > 1 {
+				pila = pila[:len(pila)-1]
+				out.WriteString("Q\n")
+			}
+		}
+	}
+	if raiz {
+		return vb, errors.New("el elemento raíz no es svg")
+	}
+	if !dibujado {
+		return vb, errors.New("el svg no contiene trazos representables")
+	}
+	return vb, nil
+}
+
+func atributosSVG(attrs []xml.Attr) map[string]string {
+	m := make(map[string]string, len(attrs))
+	for _, a := range attrs {
+		m[a.Name.Local] = strings.TrimSpace(a.Value)
+	}
+	// Las declaraciones de style tienen prioridad sobre los atributos.
+	for _, decl := range strings.Split(m["style"], ";") {
+		if k, v, ok := strings.Cut(decl, ":"); ok {
+			m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return m
+}
+
+func vistaDe(attrs map[string]string) (vistaSVG, error) {
+	if v := attrs["viewBox"]; v != "" {
+		n, err := numerosSVG(v)
+		if err != nil || len(n) != 4 || n[2] <= 0 || n[3] <= 0 {
+			return vistaSVG{}, errors.New("viewBox inválido")
+		}
+		return vistaSVG{n[0], n[1], n[2], n[3]}, nil
+	}
+	w, errW := longitudSVG(attrs["width"])
+	h, errH := longitudSVG(attrs["height"])
+	if errW != nil || errH != nil || w <= 0 || h <= 0 {
+		return vistaSVG{}, errors.New("el svg debe indicar viewBox o width y height")
+	}
+	return vistaSVG{0, 0, w, h}, nil
+}
+
+func longitudSVG(v string) (float64, error) {
+	v = strings.TrimSuffix(strings.TrimSpace(v), "px")
+	return strconv.ParseFloat(v, 64)
+}
+
+func (e estiloSVG) heredar(attrs map[string]string) estiloSVG {
+	if v, ok := attrs["fill"]; ok {
+		e.relleno = colorSVG(v, e.relleno)
+	}
+	if v, ok := attrs["stroke"]; ok {
+		e.trazo = colorSVG(v, e.trazo)
+	}
+	if v, ok := attrs["stroke-width"]; ok {
+		if g, err := longitudSVG(v); err == nil && g >= 0 {
+			e.grosor = g
+		}
+	}
+	return e
+}
+
+func (e estiloSVG) operadorPintura() string {
+	var color string
+	if e.relleno != "" {
+		color += e.relleno + " rg "
+	}
+	if e.trazo != "" {
+		color += e.trazo + " RG "
+	}
+	switch {
+	case e.relleno != "" && e.trazo != "":
+		return color + "B\n"
+	case e.relleno != "":
+		return color + "f\n"
+	case e.trazo != "":
+		return color + "S\n"
+	}
+	return "n\n"
+}
+
+var coloresSVG = map[string]string{
+	"black": "0 0 0", "white": "1 1 1", "red": "1 0 0", "green": "0 0.5 0",
+	"blue": "0 0 1", "navy": "0 0 0.5", "gray": "0.5 0.5 0.5", "grey": "0.5 0.5 0.5",
+}
+
+// colorSVG convierte un color SVG a componentes RGB de PDF. Los colores no
+// reconocidos se dibujan en negro para no perder el trazo.
+func colorSVG(v, heredado string) string {
+	v = strings.ToLower(strings.TrimSpace(v))
+	switch {
+	case v == "none" || v == "transparent":
+		return ""
+	case v == "inherit":
+		return heredado
+	case coloresSVG[v] != "":
+		return coloresSVG[v]
+	case strings.HasPrefix(v, "#") && (len(v) == 4 || len(v) == 7):
+		hex := v[1:]
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		if n, err := strconv.ParseUint(hex, 16, 32); err == nil {
+			return fmt.Sprintf("%s %s %s", num(float64(n>>16&0xff)/255), num(float64(n>>8&0xff)/255), num(float64(n&0xff)/255))
+		}
+	}
+	return "0 0 0"
+}
+
+// figuraSVG devuelve el trazado PDF del elemento, o "" si no dibuja nada.
+func figuraSVG(nombre string, a map[string]string) (string, error) {
+	f := func(k string) float64 {
+		v, _ := longitudSVG(a[k])
+		return v
+	}
+	switch nombre {
+	case "path":
+		return trazadoSVG(a["d"])
+	case "line":
+		return fmt.Sprintf("%s %s m %s %s l\n", num(f("x1")), num(f("y1")), num(f("x2")), num(f("y2"))), nil
+	case "polyline", "polygon":
+		n, err := numerosSVG(a["points"])
+		if err != nil || len(n) < 4 {
+			return "", nil
+		}
+		var b strings.Builder
+		for i := 0; i+1 < len(n); i += 2 {
+			op := "l"
+			if i == 0 {
+				op = "m"
+			}
+			fmt.Fprintf(&b, "%s %s %s\n", num(n[i]), num(n[i+1]), op)
+		}
+		if nombre == "polygon" {
+			b.WriteString("h\n")
+		}
+		return b.String(), nil
+	case "rect":
+		if f("width") <= 0 || f("height") <= 0 {
+			return "", nil
+		}
+		return fmt.Sprintf("%s %s %s %s re\n", num(f("x")), num(f("y")), num(f("width")), num(f("height"))), nil
+	case "circle":
+		return elipse(f("cx"), f("cy"), f("r"), f("r")), nil
+	case "ellipse":
+		return elipse(f("cx"), f("cy"), f("rx"), f("ry")), nil
+	}
+	return "", nil
+}
+
+// kappa es la distancia de los puntos de control que aproxima un cuarto de
+// círculo con una curva de Bézier cúbica.
+const kappa = 0.5522847498
+
+func elipse(cx, cy, rx, ry float64) string {
+	if rx <= 0 || ry <= 0 {
+		return ""
+	}
+	ox, oy := rx*kappa, ry*kappa
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s m\n", num(cx+rx), num(cy))
+	fmt.Fprintf(&b, "%s %s %s %s %s %s c\n", num(cx+rx), num(cy+oy), num(cx+ox), num(cy+ry), num(cx), num(cy+ry))
+	fmt.Fprintf(&b, "%s %s %s %s %s %s c\n", num(cx-ox), num(cy+ry), num(cx-rx), num(cy+oy), num(cx-rx), num(cy))
+	fmt.Fprintf(&b, "%s %s %s %s %s %s c\n", num(cx-rx), num(cy-oy), num(cx-ox), num(cy-ry), num(cx), num(cy-ry))
+	fmt.Fprintf(&b, "%s %s %s %s %s %s c\n", num(cx+ox), num(cy-ry), num(cx+rx), num(cy-oy), num(cx+rx), num(cy))
+	b.WriteString("h\n")
+	return b.String()
+}
+
+// transformacionSVG compone la lista de transformaciones de un atributo
+// transform en una matriz [a b c d e f].
+func transformacionSVG(v string) ([6]float64, error) {
+	m := [6]float64{1, 0, 0, 1, 0, 0}
+	resto := strings.TrimSpace(v)
+	for resto != "" {
+		abre := strings.IndexByte(resto, '(')
+		cierra := strings.IndexByte(resto, ')')
+		if abre < 0 || cierra < abre {
+			return m, fmt.Errorf("transform inválido: %q", v)
+		}
+		nombre := strings.TrimSpace(resto[:abre])
+		args, err := numerosSVG(resto[abre+1 : cierra])
+		if err != nil {
+			return m, err
+		}
+		resto = strings.TrimLeft(resto[cierra+1:], " ,\t\n")
+
+		var t [6]float64
+		switch {
+		case nombre == "matrix" && len(args) == 6:
+			copy(t[:], args)
+		case nombre == "translate" && (len(args) == 1 || len(args) == 2):
+			t = [6]float64{1, 0, 0, 1, args[0], 0}
+			if len(args) == 2 {
+				t[5] = args[1]
+			}
+		case nombre == "scale" && (len(args) == 1 || len(args) == 2):
+			t = [6]float64{args[0], 0, 0, args[0], 0, 0}
+			if len(args) == 2 {
+				t[3] = args[1]
+			}
+		case nombre == "rotate" && (len(args) == 1 || len(args) == 3):
+			rad := args[0] * math.Pi / 180
+			sin, cos := math.Sin(rad), math.Cos(rad)
+			t = [6]float64{cos, sin, -sin, cos, 0, 0}
+			if len(args) == 3 {
+				cx, cy := args[1], args[2]
+				t[4] = cx - cos*cx + sin*cy
+				t[5] = cy - sin*cx - cos*cy
+			}
+		case nombre == "skewX" && len(args) == 1:
+			t = [6]float64{1, 0, math.Tan(args[0] * math.Pi / 180), 1, 0, 0}
+		case nombre == "skewY" && len(args) == 1:
+			t = [6]float64{1, math.Tan(args[0] * math.Pi / 180), 0, 1, 0, 0}
+		default:
+			return m, fmt.Errorf("transform no admitido: %s", nombre)
+		}
+		m = multiplicar(m, t)
+	}
+	return m, nil
+}
+
+// multiplicar devuelve m·t: t se aplica primero, como en SVG.
+func multiplicar(m, t [6]float64) [6]float64 {
+	return [6]float64{
+		m[0]*t[0] + m[2]*t[1],
+		m[1]*t[0] + m[3]*t[1],
+		m[0]*t[2] + m[2]*t[3],
+		m[1]*t[2] + m[3]*t[3],
+		m[0]*t[4] + m[2]*t[5] + m[4],
+		m[1]*t[4] + m[3]*t[5] + m[5],
+	}
+}
+
+// numerosSVG separa una lista de números SVG. Admite separadores por comas,
+// espacios, signos y puntos consecutivos ("1.5.5" son 1.5 y .5).
+func numerosSVG(s string) ([]float64, error) {
+	var nums []float64
+	l := &lectorSVG{s: s}
+	for {
+		l.saltar()
+		if l.fin() {
+			return nums, nil
+		}
+		n, err := l.numero()
+		if err != nil {
+			return nil, err
+		}
+		nums = append(nums, n)
+	}
+}
+
+type lectorSVG struct {
+	s   string
+	pos int
+}
+
+func (l *lectorSVG) fin() bool { return l.pos >= len(l.s) }
+
+func (l *lectorSVG) saltar() {
+	for !l.fin() && strings.IndexByte(" \t\r\n,", l.s[l.pos]) >= 0 {
+		l.pos++
+	}
+}
+
+func (l *lectorSVG) numero() (float64, error) {
+	inicio := l.pos
+	if !l.fin() && (l.s[l.pos] == '+' || l.s[l.pos] == '-') {
+		l.pos++
+	}
+	punto, digitos := false, false
+	for !l.fin() {
+		c := l.s[l.pos]
+		switch {
+		case c >= '0' && c <= '9':
+			digitos = true
+		case c == '.' && !punto:
+			punto = true
+		case (c == 'e' || c == 'E') && digitos:
+			l.pos++
+			if !l.fin() && (l.s[l.pos] == '+' || l.s[l.pos] == '-') {
+				l.pos++
+			}
+			for !l.fin() && l.s[l.pos] >= '0' && l.s[l.pos] <= '9' {
+				l.pos++
+			}
+			return parseNumero(l.s[inicio:l.pos])
+		default:
+			goto fin
+		}
+		l.pos++
+	}
+fin:
+	if !digitos {
+		return 0, fmt.Errorf("número inválido en %q", l.s)
+	}
+	return parseNumero(l.s[inicio:l.pos])
+}
+
+// parseNumero rechaza los valores no finitos, que no pueden dibujarse.
+func parseNumero(s string) (float64, error) {
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsInf(n, 0) || math.IsNaN(n) {
+		return 0, fmt.Errorf("número fuera de rango: %s", s)
+	}
+	return n, nil
+}
+
+// bandera lee un flag de arco, que puede ir pegado al número siguiente.
+func (l *lectorSVG) bandera() (bool, error) {
+	l.saltar()
+	if l.fin() || (l.s[l.pos] != '0' && l.s[l.pos] != '1') {
+		return false, fmt.Errorf("flag de arco inválido en %q", l.s)
+	}
+	l.pos++
+	return l.s[l.pos-1] == '1', nil
+}
+
+// trazadoSVG traduce el atributo d de un path. Las coordenadas relativas y
+// las curvas abreviadas (S, T, Q, H, V) se convierten a absolutas; los arcos
+// se aproximan con curvas de Bézier.
+func trazadoSVG(d string) (string, error) {
+	var b strings.Builder
+	l := &lectorSVG{s: d}
+	var cx, cy, inicioX, inicioY float64 // punto actual e inicio del subtrazado
+	var ctrlX, ctrlY float64             // último punto de control, para S y T
+	var cmd, previo byte
+
+	leer := func(n int) ([]float64, error) {
+		v := make([]float64, n)
+		for i := range v {
+			l.saltar()
+			x, err := l.numero()
+			if err != nil {
+				return nil, err
+			}
+			v[i] = x
+		}
+		return v, nil
+	}
+	cubica := func(x1, y1, x2, y2, x, y float64) {
+		fmt.Fprintf(&b, "%s %s %s %s %s %s c\n", num(x1), num(y1), num(x2), num(y2), num(x), num(y))
+		ctrlX, ctrlY, cx, cy = x2, y2, x, y
+	}
+
+	for {
+		l.saltar()
+		if l.fin() {
+			break
+		}
+		c := l.s[l.pos]
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+			cmd = c
+			l.pos++
+		} else if cmd == 0 {
+			return "", fmt.Errorf("path inválido: %q", d)
+		} else if cmd == 'M' {
+			cmd = 'L' // las coordenadas que siguen a M son líneas
+		} else if cmd == 'm' {
+			cmd = 'l'
+		}
+
+		rel := cmd >= 'a'
+		ox, oy := 0.0, 0.0
+		if rel {
+			ox, oy = cx, cy
+		}
+		tipo := cmd &^ 0x20 // mayúscula
+
+		switch tipo {
+		case 'M', 'L':
+			v, err := leer(2)
+			if err != nil {
+				return "", err
+			}
+			cx, cy = v[0]+ox, v[1]+oy
+			op := "l"
+			if tipo == 'M' {
+				op = "m"
+				inicioX, inicioY = cx, cy
+			}
+			fmt.Fprintf(&b, "%s %s %s\n", num(cx), num(cy), op)
+		case 'H':
+			v, err := leer(1)
+			if err != nil {
+				return "", err
+			}
+			cx = v[0] + ox
+			fmt.Fprintf(&b, "%s %s l\n", num(cx), num(cy))
+		case 'V':
+			v, err := leer(1)
+			if err != nil {
+				return "", err
+			}
+			cy = v[0] + oy
+			fmt.Fprintf(&b, "%s %s l\n", num(cx), num(cy))
+		case 'C':
+			v, err := leer(6)
+			if err != nil {
+				return "", err
+			}
+			cubica(v[0]+ox, v[1]+oy, v[2]+ox, v[3]+oy, v[4]+ox, v[5]+oy)
+		case 'S':
+			v, err := leer(4)
+			if err != nil {
+				return "", err
+			}
+			x1, y1 := cx, cy
+			if p := previo &^ 0x20; p == 'C' || p == 'S' {
+				x1, y1 = 2*cx-ctrlX, 2*cy-ctrlY
+			}
+			cubica(x1, y1, v[0]+ox, v[1]+oy, v[2]+ox, v[3]+oy)
+		case 'Q', 'T':
+			var qx, qy, x, y float64
+			if tipo == 'Q' {
+				v, err := leer(4)
+				if err != nil {
+					return "", err
+				}
+				qx, qy, x, y = v[0]+ox, v[1]+oy, v[2]+ox, v[3]+oy
+			} else {
+				v, err := leer(2)
+				if err != nil {
+					return "", err
+				}
+				qx, qy, x, y = cx, cy, v[0]+ox, v[1]+oy
+				if p := previo &^ 0x20; p == 'Q' || p == 'T' {
+					qx, qy = 2*cx-ctrlX, 2*cy-ctrlY
+				}
+			}
+			// Una cuadrática es una cúbica con controles a 2/3 del control único.
+			cubica(cx+2.0/3*(qx-cx), cy+2.0/3*(qy-cy), x+2.0/3*(qx-x), y+2.0/3*(qy-y), x, y)
+			ctrlX, ctrlY = qx, qy
+		case 'A':
+			v, err := leer(3)
+			if err != nil {
+				return "", err
+			}
+			grande, err := l.bandera()
+			if err != nil {
+				return "", err
+			}
+			horario, err := l.bandera()
+			if err != nil {
+				return "", err
+			}
+			fin, err := leer(2)
+			if err != nil {
+				return "", err
+			}
+			for _, seg := range arcoABezier(cx, cy, v[0], v[1], v[2], grande, horario, fin[0]+ox, fin[1]+oy) {
+				cubica(seg[0], seg[1], seg[2], seg[3], seg[4], seg[5])
+			}
+			cx, cy = fin[0]+ox, fin[1]+oy
+		case 'Z':
+			b.WriteString("h\n")
+			cx, cy = inicioX, inicioY
+		default:
+			return "", fmt.Errorf("comando de path no admitido: %c", cmd)
+		}
+		previo = cmd
+	}
+	return b.String(), nil
+}
+
+func finito(v float64) bool {
+	return !math.IsInf(v, 0) && !math.IsNaN(v)
+}
+
+// arcoABezier convierte un arco elíptico SVG (parametrización por extremos)
+// en curvas cúbicas de a lo sumo 90 grados, según el apéndice B.2.4 de SVG 1.1.
+func arcoABezier(x1, y1, rx, ry, rotacion float64, grande, horario bool, x2, y2 float64) [][6]float64 {
+	if x1 == x2 && y1 == y2 {
+		return nil
+	}
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 {
+		return [][6]float64{{x1, y1, x2, y2, x2, y2}}
+	}
+
+	phi := rotacion * math.Pi / 180
+	sinPhi, cosPhi := math.Sin(phi), math.Cos(phi)
+	dx, dy := (x1-x2)/2, (y1-y2)/2
+	x1p := cosPhi*dx + sinPhi*dy
+	y1p := -sinPhi*dx + cosPhi*dy
+
+	// Si los radios no alcanzan para unir los extremos, se agrandan.
+	if lambda := x1p*x1p/(rx*rx) + y1p*y1p/(ry*ry); lambda > 1 {
+		s := math.Sqrt(lambda)
+		rx, ry = rx*s, ry*s
+	}
+
+	numerador := rx*rx*ry*ry - rx*rx*y1p*y1p - ry*ry*x1p*x1p
+	denominador := rx*rx*y1p*y1p + ry*ry*x1p*x1p
+	coef := math.Sqrt(math.Max(0, numerador/denominador))
+	if grande == horario {
+		coef = -coef
+	}
+	cxp, cyp := coef*rx*y1p/ry, -coef*ry*x1p/rx
+	centroX := cosPhi*cxp - sinPhi*cyp + (x1+x2)/2
+	centroY := sinPhi*cxp + cosPhi*cyp + (y1+y2)/2
+
+	angulo := func(ux, uy, vx, vy float64) float64 {
+		return math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+	}
+	theta := angulo(1, 0, (x1p-cxp)/rx, (y1p-cyp)/ry)
+	delta := angulo((x1p-cxp)/rx, (y1p-cyp)/ry, (-x1p-cxp)/rx, (-y1p-cyp)/ry)
+	if !horario && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if horario && delta < 0 {
+		delta += 2 * math.Pi
+	}
+	// Con radios enormes los cálculos desbordan; el arco es indistinguible de
+	// una recta.
+	if !finito(delta) || !finito(theta) || !finito(centroX) || !finito(centroY) || delta == 0 {
+		return [][6]float64{{x1, y1, x2, y2, x2, y2}}
+	}
+
+	n := int(math.Ceil(math.Abs(delta) / (math.Pi / 2)))
+	paso := delta / float64(n)
+	t := 4.0 / 3 * math.Tan(paso/4)
+	punto := func(a float64) (float64, float64, float64, float64) {
+		cosA, sinA := math.Cos(a), math.Sin(a)
+		x := centroX + rx*cosA*cosPhi - ry*sinA*sinPhi
+		y := centroY + rx*cosA*sinPhi + ry*sinA*cosPhi
+		// Derivada respecto de a.
+		dxa := -rx*sinA*cosPhi - ry*cosA*sinPhi
+		dya := -rx*sinA*sinPhi + ry*cosA*cosPhi
+		return x, y, dxa, dya
+	}
+
+	segs := make([][6]float64, 0, n)
+	for i := 0; i < n; i++ {
+		a1 := theta + float64(i)*paso
+		a2 := a1 + paso
+		px, py, d1x, d1y := punto(a1)
+		qx, qy, d2x, d2y := punto(a2)
+		segs = append(segs, [6]float64{px + t*d1x, py + t*d1y, qx - t*d2x, qy - t*d2y, qx, qy})
+	}
+	return segs
+}
+
+// num formatea un número sin ceros ni punto decimal sobrantes.
+func num(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 3, 64)
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	if s == "-0" || s == "" {
+		return "0"
+	}
+	return s
+}
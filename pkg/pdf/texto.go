@@ -0,0 +1,157 @@
+package pdf
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Anchos de Helvetica y Helvetica-Bold (milésimas de punto por unidad de
+// tamaño) para los caracteres ASCII imprimibles, desde el espacio (32).
+var anchosASCII = [...][95]int{
+	regular: {
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	},
+	negrita: {
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	},
+}
+
+// baseLatina reduce letras acentuadas a su base para estimar el ancho.
+var baseLatina = map[rune]rune{
+	'á': 'a', 'à': 'a', 'â': 'a', 'ä': 'a', 'ã': 'a',
+	'é': 'e', 'è': 'e', 'ê': 'e', 'ë': 'e',
+	'ó': 'o', 'ò': 'o', 'ô': 'o', 'ö': 'o', 'õ': 'o',
+	'ú': 'u', 'ù': 'u', 'û': 'u', 'ü': 'u',
+	'ñ': 'n', 'ç': 'c',
+	'Á': 'A', 'À': 'A', 'Â': 'A', 'Ä': 'A', 'Ã': 'A',
+	'É': 'E', 'È': 'E', 'Ê': 'E', 'Ë': 'E',
+	'Í': 'I', 'Ì': 'I', 'Î': 'I', 'Ï': 'I',
+	'Ó': 'O', 'Ò': 'O', 'Ô': 'O', 'Ö': 'O', 'Õ': 'O',
+	'Ú': 'U', 'Ù': 'U', 'Û': 'U', 'Ü': 'U',
+	'Ñ': 'N', 'Ç': 'C',
+}
+
+func anchoRuna(r rune, f fuente) int {
+	if b, ok := baseLatina[r]; ok {
+		r = b
+	}
+	switch {
+	case r >= 32 && r <= 126:
+		return anchosASCII[f][r-32]
+	case r == 'í' || r == 'ì' || r == 'î' || r == 'ï':
+		return 278
+	case r == '¡':
+		return 333
+	case r == '¿':
+		return 611
+	case r == '°' || r == 'º' || r == 'ª':
+		return 370
+	case r == '–':
+		return 556
+	case r == '—':
+		return 1000
+	}
+	return 556
+}
+
+func anchoTexto(s string, f fuente, tam float64) float64 {
+	total := 0
+	for _, r := range s {
+		total += anchoRuna(r, f)
+	}
+	return float64(total) * tam / 1000
+}
+
+// ajustar divide el texto en líneas que caben en ancho; la primera línea
+// dispone de ancho-sangriaInicial. Las palabras más largas que una línea se
+// cortan por carácter.
+func ajustar(texto string, f fuente, tam, ancho, sangriaInicial float64) []string {
+	var lineas []string
+	disponible := ancho - sangriaInicial
+	espacio := anchoTexto(" ", f, tam)
+
+	for _, parrafo := range strings.Split(strings.ReplaceAll(texto, "\r\n", "\n"), "\n") {
+		var actual strings.Builder
+		usado := 0.0
+		emitir := func() {
+			lineas = append(lineas, actual.String())
+			actual.Reset()
+			usado = 0
+			disponible = ancho
+		}
+
+		for _, palabra := range strings.FieldsFunc(parrafo, unicode.IsSpace) {
+			w := anchoTexto(palabra, f, tam)
+			if actual.Len() > 0 && usado+espacio+w > disponible {
+				emitir()
+			}
+			for w > disponible {
+				// Palabra más larga que la línea: se corta donde deje de caber.
+				corte, acumulado := 0, 0.0
+				for i, r := range palabra {
+					cw := anchoTexto(string(r), f, tam)
+					if acumulado+cw > disponible-usado && i > 0 {
+						corte = i
+						break
+					}
+					acumulado += cw
+				}
+				if corte == 0 {
+					break
+				}
+				actual.WriteString(palabra[:corte])
+				emitir()
+				palabra = palabra[corte:]
+				w = anchoTexto(palabra, f, tam)
+			}
+			if actual.Len() > 0 {
+				actual.WriteByte(' ')
+				usado += espacio
+			}
+			actual.WriteString(palabra)
+			usado += w
+		}
+		emitir()
+	}
+	return lineas
+}
+
+// winAnsi traduce los caracteres de CP1252 fuera de Latin-1.
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92,
+	'“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// escapar codifica el texto en WinAnsi y escapa los delimitadores de cadena
+// de PDF. Los caracteres no representables se reemplazan por '?'.
+func escapar(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r == '\t':
+			b.WriteByte(' ')
+		case r >= 32 && r <= 126, r >= 0xA0 && r <= 0xFF:
+			b.WriteByte(byte(r))
+		default:
+			if c, ok := winAnsi[r]; ok {
+				b.WriteByte(c)
+			} else if r >= 32 {
+				b.WriteByte('?')
+			}
+		}
+	}
+	return b.String()
+}
@@ -3,6 +3,7 @@ package response
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	apperrors "github.com/tunek/centro-caribel/pkg/errors"
 )
@@ -41,6 +42,9 @@ func JSONWithMeta(w http.ResponseWriter, status int, data interface{}, meta *Met
 func Error(w http.ResponseWriter, err error) {
 	if appErr, ok := err.(*apperrors.AppError); ok {
 		w.Header().Set("Content-Type", "application/json")
+		if appErr.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(appErr.RetryAfter))
+		}
 		w.WriteHeader(appErr.Code)
 		json.NewEncoder(w).Encode(&Response{
 			Success: false,
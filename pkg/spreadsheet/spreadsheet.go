@@ -0,0 +1,331 @@
+// Package spreadsheet lee planillas CSV y XLSX como una matriz de celdas de texto.
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Read detecta el formato por la extensión del nombre de archivo.
+func Read(filename string, r io.Reader) ([][]string, error) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv", ".txt":
+		return ReadCSV(r)
+	case ".xlsx":
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return ReadXLSX(bytes.NewReader(data), int64(len(data)))
+	}
+	return nil, fmt.Errorf("formato no soportado: %s (use .csv o .xlsx)", filename)
+}
+
+// ReadCSV acepta separador coma o punto y coma (habitual en planillas exportadas
+// con configuración regional en español) y descarta el BOM de UTF-8.
+func ReadCSV(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("el archivo CSV no está codificado en UTF-8")
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	firstLine, _, _ := strings.Cut(string(data), "\n")
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	// csv.Reader salta las líneas en blanco; se reponen vacías para que el
+	// registro i siga correspondiendo a la línea i+1, como en ReadXLSX.
+	var records [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("CSV inválido: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if line > maxRows {
+			return nil, fmt.Errorf("la planilla excede el máximo de %d filas", maxRows)
+		}
+		for len(records) < line-1 {
+			records = append(records, nil)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+const (
+	// maxColumns es la cantidad de columnas de una hoja de Excel (A..XFD).
+	maxColumns = 16384
+	// maxXMLSize limita lo que se descomprime de cada parte del libro, para
+	// que un archivo pequeño no pueda expandirse sin límite.
+	maxXMLSize = 64 << 20
+	// maxRows y maxCells limitan las filas de la hoja y las celdas que se
+	// reservan en total, contando las vacías intermedias.
+	maxRows  = 100000
+	maxCells = 1 << 20
+)
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Items []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxText struct {
+	T string `xml:"t"`
+	R []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.R) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	b.WriteString(t.T)
+	for _, r := range t.R {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Ref   string `xml:"r,attr"`
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// ReadXLSX devuelve las celdas de la primera hoja del libro. El registro i
+// corresponde a la fila i+1 de la hoja, aunque esté vacía, y se ignoran las
+// celdas a la derecha de la última columna del encabezado (la primera fila con
+// datos). Las fechas se devuelven como el número de serie de Excel; ver
+// ExcelSerialToDate.
+func ReadXLSX(r io.ReaderAt, size int64) ([][]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("XLSX inválido: %w", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var wb xlsxWorkbook
+	if err := decodeZipXML(files, "xl/workbook.xml", &wb); err != nil {
+		return nil, err
+	}
+	if len(wb.Sheets) == 0 {
+		return nil, fmt.Errorf("el libro no contiene hojas")
+	}
+
+	var rels xlsxRelationships
+	if err := decodeZipXML(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	sheetPath := ""
+	for _, rel := range rels.Items {
+		if rel.ID == wb.Sheets[0].RID {
+			sheetPath = rel.Target
+			break
+		}
+	}
+	if sheetPath == "" {
+		return nil, fmt.Errorf("no se encontró la hoja %q", wb.Sheets[0].Name)
+	}
+	if strings.HasPrefix(sheetPath, "/") {
+		sheetPath = strings.TrimPrefix(sheetPath, "/")
+	} else {
+		sheetPath = path.Join("xl", sheetPath)
+	}
+
+	var shared xlsxSharedStrings
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeZipXML(files, "xl/sharedStrings.xml", &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	var sheet xlsxSheet
+	if err := decodeZipXML(files, sheetPath, &sheet); err != nil {
+		return nil, err
+	}
+
+	var records [][]string
+	width := 0 // columnas del encabezado; 0 hasta encontrarlo
+	cells := 0
+	for _, row := range sheet.Rows {
+		num := len(records) + 1
+		if row.Ref != "" {
+			n, err := strconv.Atoi(row.Ref)
+			if err != nil || n < num {
+				return nil, fmt.Errorf("número de fila inválido: %.20s", row.Ref)
+			}
+			num = n
+		}
+		if num > maxRows {
+			return nil, fmt.Errorf("la planilla excede el máximo de %d filas", maxRows)
+		}
+		// Las filas omitidas en el XML se conservan vacías para que cada
+		// registro siga correspondiendo a su número de fila.
+		for len(records) < num-1 {
+			records = append(records, nil)
+		}
+
+		type celda struct {
+			col   int
+			value string
+		}
+		var valores []celda
+		last := -1
+		for i, c := range row.Cells {
+			col := i
+			if c.Ref != "" {
+				n, ok := columnIndex(c.Ref)
+				if !ok {
+					return nil, fmt.Errorf("referencia de celda inválida: %.20s", c.Ref)
+				}
+				col = n
+			}
+			// Las celdas a la derecha del encabezado no pertenecen a ninguna columna.
+			if width > 0 && col >= width {
+				continue
+			}
+
+			var value string
+			switch c.Type {
+			case "s":
+				idx, err := strconv.Atoi(c.Value)
+				if err != nil || idx < 0 || idx >= len(shared.Items) {
+					return nil, fmt.Errorf("referencia de texto compartido inválida en %s", c.Ref)
+				}
+				value = shared.Items[idx].String()
+			case "inlineStr":
+				value = c.Inline.String()
+			case "b":
+				if c.Value == "1" {
+					value = "TRUE"
+				} else {
+					value = "FALSE"
+				}
+			default:
+				value = c.Value
+			}
+			if value == "" {
+				continue
+			}
+			valores = append(valores, celda{col, value})
+			if col > last {
+				last = col
+			}
+		}
+
+		var record []string
+		if last >= 0 {
+			cells += last + 1
+			if cells > maxCells {
+				return nil, fmt.Errorf("la hoja excede el máximo de %d celdas", maxCells)
+			}
+			record = make([]string, last+1)
+			for _, v := range valores {
+				record[v.col] = v.value
+			}
+			if width == 0 {
+				width = last + 1
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func decodeZipXML(files map[string]*zip.File, name string, dst interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("XLSX inválido: falta %s", name)
+	}
+	if f.UncompressedSize64 > maxXMLSize {
+		return fmt.Errorf("XLSX inválido: %s excede el tamaño máximo", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	// El tamaño declarado en el zip puede ser falso; el límite se aplica
+	// también a lo que realmente se descomprime.
+	if err := xml.NewDecoder(io.LimitReader(rc, maxXMLSize)).Decode(dst); err != nil {
+		return fmt.Errorf("XLSX inválido: %s: %w", name, err)
+	}
+	return nil
+}
+
+// columnIndex convierte la referencia de celda "C12" en el índice de columna 2.
+// Rechaza las columnas posteriores a XFD, la última de Excel.
+func columnIndex(ref string) (int, bool) {
+	n := 0
+	i := 0
+	for ; i < len(ref); i++ {
+		ch := ref[i]
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		n = n*26 + int(ch-'A'+1)
+		if n > maxColumns {
+			return 0, false
+		}
+	}
+	if i == 0 {
+		return 0, false
+	}
+	return n - 1, true
+}
+
+// ExcelSerialToDate interpreta v como número de serie de fecha de Excel
+// (sistema 1900, el predeterminado) y devuelve la fecha correspondiente.
+func ExcelSerialToDate(v string) (time.Time, bool) {
+	serial, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil || serial < 1 || serial > 2958465 {
+		return time.Time{}, false
+	}
+	base := time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+	return base.AddDate(0, 0, int(serial)), true
+}
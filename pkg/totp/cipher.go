@@ -0,0 +1,55 @@
+package totp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// Cipher cifra los secretos TOTP con AES-256-GCM para guardarlos en la base.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher deriva la clave AES-256 como el SHA-256 de key.
+func NewCipher(key []byte) (*Cipher, error) {
+	sum := sha256.Sum256(key)
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Seal devuelve nonce||texto cifrado en base64.
+func (c *Cipher) Seal(secret string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	out := c.aead.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+func (c *Cipher) Open(sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < c.aead.NonceSize() {
+		return "", fmt.Errorf("secreto cifrado inválido")
+	}
+	nonce, ct := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, ct, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
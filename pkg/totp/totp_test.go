@@ -0,0 +1,126 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// secretoRFC es la clave SHA1 de los vectores del apéndice B de RFC 6238,
+// "12345678901234567890", en base32.
+var secretoRFC = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+// Los vectores del RFC son de 8 dígitos; con 6 se comparan los últimos 6.
+var vectoresRFC = []struct {
+	unix   int64
+	codigo string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCodeRFC6238(t *testing.T) {
+	for _, v := range vectoresRFC {
+		got, err := Code(secretoRFC, Step(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatalf("T=%d: %v", v.unix, err)
+		}
+		if got != v.codigo {
+			t.Errorf("T=%d: código %s, se esperaba %s", v.unix, got, v.codigo)
+		}
+	}
+}
+
+func TestCodeSecretoSinPaddingNiMayusculas(t *testing.T) {
+	secreto := "gezdgnbvgy3tqojqgezdgnbvgy3tqojq"
+	got, err := Code(secreto, Step(time.Unix(59, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "287082" {
+		t.Errorf("código %s, se esperaba 287082", got)
+	}
+}
+
+func TestCodeSecretoInvalido(t *testing.T) {
+	if _, err := Code("no-es-base32!", 1); err == nil {
+		t.Error("se esperaba error con un secreto inválido")
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	// 1111111111 está en el paso 37037037; el código del RFC es el de ese paso.
+	ahora := time.Unix(1111111111, 0)
+	paso := Step(ahora)
+	codigo := func(p int64) string {
+		c, err := Code(secretoRFC, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	casos := []struct {
+		nombre string
+		codigo string
+		skew   int
+		paso   int64
+		ok     bool
+	}{
+		{"paso actual", codigo(paso), 1, paso, true},
+		{"paso anterior dentro del skew", codigo(paso - 1), 1, paso - 1, true},
+		{"paso siguiente dentro del skew", codigo(paso + 1), 1, paso + 1, true},
+		{"dos pasos antes fuera del skew", codigo(paso - 2), 1, 0, false},
+		{"dos pasos después fuera del skew", codigo(paso + 2), 1, 0, false},
+		{"paso anterior sin skew", codigo(paso - 1), 0, 0, false},
+		{"paso actual sin skew", codigo(paso), 0, paso, true},
+		{"con espacios", codigo(paso)[:3] + " " + codigo(paso)[3:], 1, paso, true},
+		{"longitud incorrecta", codigo(paso)[:5], 1, 0, false},
+		{"vacío", "", 1, 0, false},
+	}
+	for _, c := range casos {
+		got, ok := Validate(secretoRFC, c.codigo, ahora, c.skew)
+		if ok != c.ok || got != c.paso {
+			t.Errorf("%s: Validate = (%d, %v), se esperaba (%d, %v)", c.nombre, got, ok, c.paso, c.ok)
+		}
+	}
+}
+
+func TestValidateBordeDePaso(t *testing.T) {
+	// El último segundo de un paso y el primero del siguiente aceptan el
+	// código del otro con skew 1, y no con skew 0.
+	ultimo := time.Unix(59, 0)
+	primero := time.Unix(60, 0)
+	if Step(ultimo)+1 != Step(primero) {
+		t.Fatalf("pasos %d y %d no son consecutivos", Step(ultimo), Step(primero))
+	}
+	codigoUltimo, _ := Code(secretoRFC, Step(ultimo))
+	codigoPrimero, _ := Code(secretoRFC, Step(primero))
+
+	if _, ok := Validate(secretoRFC, codigoUltimo, primero, 1); !ok {
+		t.Error("el código del paso anterior debería aceptarse con skew 1")
+	}
+	if _, ok := Validate(secretoRFC, codigoPrimero, ultimo, 1); !ok {
+		t.Error("el código del paso siguiente debería aceptarse con skew 1")
+	}
+	if _, ok := Validate(secretoRFC, codigoUltimo, primero, 0); ok {
+		t.Error("el código del paso anterior no debería aceptarse con skew 0")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secreto, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secreto) != 32 {
+		t.Errorf("secreto de %d caracteres, se esperaban 32", len(secreto))
+	}
+	if _, err := Code(secreto, 0); err != nil {
+		t.Errorf("el secreto generado no sirve para Code: %v", err)
+	}
+}